@@ -12,10 +12,19 @@ import (
 	"syscall"
 	"time"
 
+	"nomad-events/internal/alerts"
+	"nomad-events/internal/cache"
+	"nomad-events/internal/checks"
 	"nomad-events/internal/config"
+	"nomad-events/internal/enrich"
+	"nomad-events/internal/interactions"
 	"nomad-events/internal/nomad"
 	"nomad-events/internal/outputs"
 	"nomad-events/internal/routing"
+	"nomad-events/internal/server"
+	"nomad-events/internal/silence"
+	"nomad-events/internal/state"
+	"nomad-events/internal/template"
 )
 
 var (
@@ -32,13 +41,16 @@ type ServiceManager struct {
 	outputManager *outputs.Manager
 	configPath    string
 	eventStream   *nomad.EventStream
+	silences      *silence.Manager
+	templateDir   string
 }
 
 // NewServiceManager creates a new service manager with initial configuration
-func NewServiceManager(configPath string, eventStream *nomad.EventStream) (*ServiceManager, error) {
+func NewServiceManager(configPath string, eventStream *nomad.EventStream, silences *silence.Manager) (*ServiceManager, error) {
 	sm := &ServiceManager{
 		configPath:  configPath,
 		eventStream: eventStream,
+		silences:    silences,
 	}
 
 	// Load initial configuration
@@ -60,9 +72,18 @@ func (sm *ServiceManager) reloadConfig() error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Named templates are used by the router and outputs created below
+	library, err := template.NewLibrary(cfg.Templates, cfg.TemplateDir)
+	if err != nil {
+		slog.Error("Failed to load named templates", "error", err)
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
+	previousLibrary := template.SetLibrary(library)
+
 	// Create new router
-	newRouter, err := routing.NewRouter(cfg.Routes)
+	newRouter, err := routing.NewRouterWithTimeIntervals(cfg.Routes, cfg.TimeIntervals)
 	if err != nil {
+		template.SetLibrary(previousLibrary)
 		slog.Error("Failed to create new router", "error", err)
 		return fmt.Errorf("failed to create router: %w", err)
 	}
@@ -70,24 +91,67 @@ func (sm *ServiceManager) reloadConfig() error {
 	// Create new output manager
 	newOutputManager, err := outputs.NewManager(cfg.Outputs, sm.eventStream.Client())
 	if err != nil {
+		newRouter.Close()
+		template.SetLibrary(previousLibrary)
 		slog.Error("Failed to create new output manager", "error", err)
 		return fmt.Errorf("failed to create output manager: %w", err)
 	}
 
+	// Unchanged routes keep their dedupe windows and flap history, and rate
+	// limited outputs keep their bucket and queue
+	sm.mu.RLock()
+	oldRouter := sm.router
+	oldOutputs := sm.outputManager
+	sm.mu.RUnlock()
+	if oldRouter != nil {
+		newRouter.Inherit(oldRouter)
+	}
+	if oldOutputs != nil {
+		newOutputManager.Inherit(oldOutputs)
+	}
+
 	// Atomically replace components
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
+	oldOutputManager := sm.outputManager
 	sm.router = newRouter
 	sm.outputManager = newOutputManager
+	sm.templateDir = cfg.TemplateDir
+	sm.mu.Unlock()
+
+	// Flush pending batches and release connections held by the previous outputs
+	if oldOutputManager != nil {
+		if err := oldOutputManager.Close(); err != nil {
+			slog.Warn("Failed to close previous outputs", "error", err)
+		}
+	}
 
 	slog.Info("Configuration reload completed successfully",
 		"outputs", len(cfg.Outputs),
-		"routes", len(cfg.Routes))
+		"routes", len(cfg.Routes),
+		"templates", len(library.Names()))
 
 	return nil
 }
 
+// TemplateDir returns the template directory of the current configuration
+func (sm *ServiceManager) TemplateDir() string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	return sm.templateDir
+}
+
+// Close flushes and releases the current outputs and router state
+func (sm *ServiceManager) Close() error {
+	sm.mu.RLock()
+	outputManager := sm.outputManager
+	router := sm.router
+	sm.mu.RUnlock()
+
+	router.Close()
+	return outputManager.Close()
+}
+
 // Route processes an event through the current router (thread-safe)
 func (sm *ServiceManager) Route(event nomad.Event) ([]string, error) {
 	sm.mu.RLock()
@@ -97,6 +161,37 @@ func (sm *ServiceManager) Route(event nomad.Event) ([]string, error) {
 	return router.Route(event)
 }
 
+// Notifications returns events generated by the current router, such as flapping changes
+func (sm *ServiceManager) Notifications() []routing.Notification {
+	sm.mu.RLock()
+	router := sm.router
+	sm.mu.RUnlock()
+
+	return router.Notifications()
+}
+
+// SweepRouter returns notifications for time-based changes in the current router
+func (sm *ServiceManager) SweepRouter() []routing.Notification {
+	sm.mu.RLock()
+	router := sm.router
+	sm.mu.RUnlock()
+
+	return router.Sweep()
+}
+
+// Silenced returns the active silence muting an event for an output, or nil
+func (sm *ServiceManager) Silenced(outputName string, event nomad.Event) *silence.Silence {
+	return sm.silences.Match(event, outputName)
+}
+
+// Outputs returns the current output manager (thread-safe)
+func (sm *ServiceManager) Outputs() *outputs.Manager {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	return sm.outputManager
+}
+
 // Send sends an event to the specified output (thread-safe)
 func (sm *ServiceManager) Send(outputName string, event nomad.Event) error {
 	sm.mu.RLock()
@@ -107,6 +202,11 @@ func (sm *ServiceManager) Send(outputName string, event nomad.Event) error {
 }
 
 func main() {
+	// Subcommands talk to a running instance and have their own flags
+	if len(os.Args) > 1 && os.Args[1] == "silence" {
+		os.Exit(runSilenceCommand(os.Args[2:]))
+	}
+
 	var (
 		configPath     = flag.String("config", "config.yaml", "Path to configuration file")
 		validateConfig = flag.Bool("validate-config", false, "Validate configuration and exit")
@@ -124,6 +224,7 @@ func main() {
 
 USAGE:
     nomad-events [options]
+    nomad-events silence <add|list|expire> [options]
 
 DESCRIPTION:
     Connects to Nomad's event stream API and processes events through a configurable
@@ -150,6 +251,9 @@ EXAMPLES:
     # Reload configuration without restart (send SIGHUP)
     kill -HUP <pid>
 
+    # Silence node events for two hours (requires api.address)
+    nomad-events silence add -matcher Topic=Node -duration 2h -comment "maintenance"
+
 For more information, see: https://github.com/your-repo/nomad-events
 `)
 	}
@@ -176,15 +280,25 @@ For more information, see: https://github.com/your-repo/nomad-events
 		os.Exit(1)
 	}
 
+	// Named templates are needed by everything that renders templates,
+	// including derived event trackers created before the first reload
+	library, err := template.NewLibrary(cfg.Templates, cfg.TemplateDir)
+	if err != nil {
+		slog.Error("Failed to load named templates", "error", err)
+		os.Exit(1)
+	}
+	template.SetLibrary(library)
+
 	if *validateConfig {
 		fmt.Printf("✅ Configuration is valid\n")
 		fmt.Printf("   - Config file: %s\n", *configPath)
 		fmt.Printf("   - Nomad address: %s\n", cfg.Nomad.Address)
 		fmt.Printf("   - Outputs defined: %d\n", len(cfg.Outputs))
 		fmt.Printf("   - Routes defined: %d\n", len(cfg.Routes))
+		fmt.Printf("   - Named templates: %d\n", len(library.Names()))
 
 		// Test router creation
-		_, err := routing.NewRouter(cfg.Routes)
+		_, err := routing.NewRouterWithTimeIntervals(cfg.Routes, cfg.TimeIntervals)
 		if err != nil {
 			slog.Error("Failed to validate routing configuration", "error", err)
 			os.Exit(1)
@@ -199,6 +313,26 @@ For more information, see: https://github.com/your-repo/nomad-events
 		}
 		fmt.Printf("   - Output configuration: valid\n")
 
+		if _, err := newDerivers(cfg.DerivedEvents); err != nil {
+			slog.Error("Failed to validate derived events configuration", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("   - Derived events configuration: valid\n")
+
+		if _, err := checks.NewScheduler(cfg.Checks, nil); err != nil {
+			slog.Error("Failed to validate checks configuration", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("   - Checks defined: %d\n", len(cfg.Checks))
+		if cfg.Interactions != nil {
+			fmt.Printf("   - Slack actions defined: %d\n", len(cfg.Interactions.Actions))
+		}
+
+		if _, err := newEnricher(cfg.Enrichment, nil); err != nil {
+			slog.Error("Failed to validate enrichment configuration", "error", err)
+			os.Exit(1)
+		}
+
 		fmt.Printf("✅ All configuration checks passed!\n")
 		os.Exit(0)
 	}
@@ -214,13 +348,101 @@ For more information, see: https://github.com/your-repo/nomad-events
 		os.Exit(1)
 	}
 
+	// Template functions share one cache of Nomad API lookups across outputs and reloads
+	apiCache, err := newAPICache(cfg.Nomad.Cache)
+	if err != nil {
+		slog.Error("Failed to create Nomad API cache", "error", err)
+		os.Exit(1)
+	}
+	template.SetNomadOptions(eventStream.Client(), template.NomadOptions{
+		Cache:     apiCache,
+		Namespace: cfg.Nomad.Namespace,
+		UIURL:     cfg.Nomad.UIURL,
+	})
+
+	// Silences persist across reloads; their storage settings require a restart
+	silencesPath := silence.DefaultPath
+	retention := silence.DefaultRetention
+	if cfg.Silences != nil {
+		if cfg.Silences.Path != "" {
+			silencesPath = cfg.Silences.Path
+		}
+		if cfg.Silences.Retention != "" {
+			retention, _ = time.ParseDuration(cfg.Silences.Retention)
+		}
+	}
+	silenceManager, err := silence.NewManager(silencesPath, retention)
+	if err != nil {
+		slog.Error("Failed to load silences", "error", err, "path", silencesPath)
+		os.Exit(1)
+	}
+
+	// Derived event trackers keep state for the life of the process
+	derivers, err := newDerivers(cfg.DerivedEvents)
+	if err != nil {
+		slog.Error("Failed to create derived event trackers", "error", err)
+		os.Exit(1)
+	}
+
+	// Checks query the Nomad API on their own schedule; changes require a restart
+	scheduler, err := checks.NewScheduler(cfg.Checks, checks.NewAPISource(eventStream.Client()))
+	if err != nil {
+		slog.Error("Failed to create checks", "error", err)
+		os.Exit(1)
+	}
+
+	// Enrichment runs before routing; its settings require a restart
+	enricher, err := newEnricher(cfg.Enrichment, eventStream)
+	if err != nil {
+		slog.Error("Failed to create enrichment", "error", err)
+		os.Exit(1)
+	}
+
 	// Create service manager with reloadable components
-	serviceManager, err := NewServiceManager(*configPath, eventStream)
+	serviceManager, err := NewServiceManager(*configPath, eventStream, silenceManager)
 	if err != nil {
 		slog.Error("Failed to create service manager", "error", err)
 		os.Exit(1)
 	}
 
+	var apiServer *server.Server
+	var actionHandler *interactions.Handler
+	if cfg.API != nil {
+		apiServer = server.New(cfg.API.Address)
+		silence.NewAPI(silenceManager).Register(apiServer.Mux())
+
+		caches := map[string]*cache.Cache{"templates": apiCache}
+		if enricher != nil {
+			caches["enrichment"] = enricher.Cache()
+		}
+		cache.Register(apiServer.Mux(), caches)
+		outputs.Register(apiServer.Mux(), serviceManager.Outputs)
+
+		if err := apiServer.Start(); err != nil {
+			slog.Error("Failed to start API server", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Slack actions run Nomad operations; changes require a restart. They are
+	// served on their own address, so exposing them to Slack does not expose
+	// the unauthenticated management API.
+	var interactionsServer *server.Server
+	if cfg.Interactions != nil {
+		actionHandler, err = interactions.NewHandler(cfg.Interactions, cfg.Nomad.Namespace, interactions.NewAPINomad(eventStream.Client()), silenceManager)
+		if err != nil {
+			slog.Error("Failed to create Slack action handler", "error", err)
+			os.Exit(1)
+		}
+
+		interactionsServer = server.New(cfg.Interactions.Listen)
+		actionHandler.Register(interactionsServer.Mux())
+		if err := interactionsServer.Start(); err != nil {
+			slog.Error("Failed to start Slack interactions server", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -239,18 +461,38 @@ For more information, see: https://github.com/your-repo/nomad-events
 		}
 	}()
 
+	// Check events join the stream events so they are routed and tracked alike
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx, eventChan)
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		processEvents(ctx, eventChan, serviceManager)
+		processEvents(ctx, eventChan, serviceManager, derivers, enricher, apiCache)
 	}()
 
+	// Changes to template files are reloaded like SIGHUP
+	templateChanges := make(chan struct{}, 1)
+	go watchTemplateDir(ctx, serviceManager, templateChanges)
+
 	slog.Info("Service started successfully",
 		"event_buffer_size", cap(eventChan))
 
 	// Signal handling loop
 	for {
-		sig := <-sigChan
+		var sig os.Signal
+		select {
+		case <-templateChanges:
+			slog.Info("Template files changed - reloading configuration...")
+			if err := serviceManager.reloadConfig(); err != nil {
+				slog.Error("Configuration reload failed - continuing with current config", "error", err)
+			}
+			continue
+		case sig = <-sigChan:
+		}
 		slog.Info("Received signal", "signal", sig.String())
 
 		switch sig {
@@ -267,9 +509,28 @@ For more information, see: https://github.com/your-repo/nomad-events
 			slog.Info("Initiating graceful shutdown...")
 			cancel()
 
-			// Close event channel and wait for goroutines with timeout
-			close(eventChan)
+			if apiServer != nil {
+				shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := apiServer.Shutdown(shutdownCtx); err != nil {
+					slog.Warn("Failed to shut down API server", "error", err)
+				}
+				shutdownCancel()
+			}
+			if interactionsServer != nil {
+				shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := interactionsServer.Shutdown(shutdownCtx); err != nil {
+					slog.Warn("Failed to shut down Slack interactions server", "error", err)
+				}
+				shutdownCancel()
+			}
+			if actionHandler != nil {
+				if err := actionHandler.Close(); err != nil {
+					slog.Warn("Failed to close Slack action audit log", "error", err)
+				}
+			}
 
+			// Wait for goroutines with timeout. The event channel has several
+			// senders, so it is left open and processing stops on cancellation.
 			done := make(chan struct{})
 			go func() {
 				wg.Wait()
@@ -278,6 +539,10 @@ For more information, see: https://github.com/your-repo/nomad-events
 
 			select {
 			case <-done:
+				// Deliver any events still held by grouped outputs
+				if err := serviceManager.Close(); err != nil {
+					slog.Warn("Failed to close outputs", "error", err)
+				}
 				slog.Info("Graceful shutdown completed")
 			case <-time.After(30 * time.Second):
 				slog.Warn("Shutdown timeout exceeded, forcing exit")
@@ -288,13 +553,139 @@ For more information, see: https://github.com/your-repo/nomad-events
 	}
 }
 
-func processEvents(ctx context.Context, eventChan <-chan nomad.Event, serviceManager *ServiceManager) {
+// templateWatchInterval is how often the template directory is checked for changes
+const templateWatchInterval = 5 * time.Second
+
+// watchTemplateDir polls the configured template directory and signals when
+// its template files change. A reload that changes the directory itself has
+// already loaded it, so that is not signalled.
+func watchTemplateDir(ctx context.Context, sm *ServiceManager, changes chan<- struct{}) {
+	ticker := time.NewTicker(templateWatchInterval)
+	defer ticker.Stop()
+
+	dir := sm.TemplateDir()
+	fingerprint := template.DirFingerprint(dir)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := sm.TemplateDir()
+		next := template.DirFingerprint(current)
+		if current != dir {
+			dir, fingerprint = current, next
+			continue
+		}
+
+		if next != fingerprint {
+			fingerprint = next
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// newDerivers creates the derived event trackers enabled in the configuration
+func newDerivers(cfg *config.DerivedEventsConfig) ([]state.Deriver, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var derivers []state.Deriver
+	if cfg.Allocations != nil {
+		tracker, err := state.NewAllocationTracker(*cfg.Allocations)
+		if err != nil {
+			return nil, fmt.Errorf("allocations: %w", err)
+		}
+		derivers = append(derivers, tracker)
+	}
+	if cfg.Deployments != nil {
+		derivers = append(derivers, state.NewDeploymentAggregator(*cfg.Deployments))
+	}
+	if len(cfg.Alerts) > 0 {
+		tracker, err := alerts.NewTracker(cfg.Alerts)
+		if err != nil {
+			return nil, fmt.Errorf("alerts: %w", err)
+		}
+		derivers = append(derivers, tracker)
+	}
+
+	return derivers, nil
+}
+
+// newAPICache creates the cache of Nomad API lookups made by template functions
+func newAPICache(cfg *config.APICacheConfig) (*cache.Cache, error) {
+	var ttl, timeout time.Duration
+	if cfg != nil {
+		var err error
+		if cfg.TTL != "" {
+			if ttl, err = time.ParseDuration(cfg.TTL); err != nil {
+				return nil, fmt.Errorf("invalid ttl %q: %w", cfg.TTL, err)
+			}
+		}
+		if cfg.Timeout != "" {
+			if timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+				return nil, fmt.Errorf("invalid timeout %q: %w", cfg.Timeout, err)
+			}
+		}
+	}
+	return cache.New(ttl, timeout), nil
+}
+
+// newEnricher creates the enrichment stage, or nil when it is not configured.
+// The event stream is nil when only validating the configuration.
+func newEnricher(cfg *config.EnrichmentConfig, eventStream *nomad.EventStream) (*enrich.Enricher, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var fetcher enrich.Fetcher
+	if eventStream != nil {
+		fetcher = enrich.NewAPIFetcher(eventStream.Client())
+	}
+	return enrich.New(*cfg, fetcher)
+}
+
+// sweepInterval is how often derivers check for time-based conditions
+const sweepInterval = 15 * time.Second
+
+func processEvents(ctx context.Context, eventChan <-chan nomad.Event, serviceManager *ServiceManager, derivers []state.Deriver, enricher *enrich.Enricher, apiCache *cache.Cache) {
+	sweep := time.NewTicker(sweepInterval)
+	defer sweep.Stop()
+
+	// Enriched data is attached once, before routing, so every filter,
+	// deriver and output sees the same lookups
+	enrichEvent := func(event nomad.Event) nomad.Event {
+		if enricher == nil {
+			return event
+		}
+		return enricher.Enrich(ctx, event)
+	}
+
 	eventCount := 0
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Debug("Processing stopped", "events_processed", eventCount)
 			return
+		case <-sweep.C:
+			for _, notification := range serviceManager.SweepRouter() {
+				deliverEvent(notification.Event, notification.Outputs, serviceManager)
+			}
+			for _, deriver := range derivers {
+				for _, derived := range deriver.Sweep() {
+					dispatchEvent(enrichEvent(derived), serviceManager)
+				}
+			}
+			if enricher != nil {
+				enricher.Prune()
+			}
+			apiCache.Prune()
 		case event, ok := <-eventChan:
 			if !ok {
 				slog.Debug("Event channel closed", "events_processed", eventCount)
@@ -309,34 +700,69 @@ func processEvents(ctx context.Context, eventChan <-chan nomad.Event, serviceMan
 				"key", event.Key,
 				"index", event.Index)
 
-			matchedOutputs, err := serviceManager.Route(event)
-			if err != nil {
-				slog.Error("Failed to route event",
-					"error", err,
-					"topic", event.Topic,
-					"type", event.Type,
-					"key", event.Key)
-				continue
-			}
+			// Drop cached lookups of whatever the event changed before templates render it
+			apiCache.Observe(event)
 
-			slog.Debug("Event routed",
-				"topic", event.Topic,
-				"type", event.Type,
-				"outputs", matchedOutputs)
-
-			for _, outputName := range matchedOutputs {
-				if err := serviceManager.Send(outputName, event); err != nil {
-					slog.Error("Failed to send event to output",
-						"error", err,
-						"output", outputName,
-						"topic", event.Topic,
-						"type", event.Type)
+			event = enrichEvent(event)
+			dispatchEvent(event, serviceManager)
+
+			// Derived events are routed like any other, but never observed again
+			for _, deriver := range derivers {
+				for _, derived := range deriver.Observe(event) {
+					dispatchEvent(enrichEvent(derived), serviceManager)
 				}
 			}
 		}
 	}
 }
 
+// dispatchEvent routes an event and sends it to every matched, unsilenced output
+func dispatchEvent(event nomad.Event, serviceManager *ServiceManager) {
+	matchedOutputs, err := serviceManager.Route(event)
+	if err != nil {
+		slog.Error("Failed to route event",
+			"error", err,
+			"topic", event.Topic,
+			"type", event.Type,
+			"key", event.Key)
+		return
+	}
+
+	slog.Debug("Event routed",
+		"topic", event.Topic,
+		"type", event.Type,
+		"outputs", matchedOutputs)
+
+	deliverEvent(event, matchedOutputs, serviceManager)
+
+	// Routing can generate events of its own, e.g. when a key starts flapping
+	for _, notification := range serviceManager.Notifications() {
+		deliverEvent(notification.Event, notification.Outputs, serviceManager)
+	}
+}
+
+// deliverEvent sends an event to each output unless a silence matches
+func deliverEvent(event nomad.Event, outputNames []string, serviceManager *ServiceManager) {
+	for _, outputName := range outputNames {
+		if s := serviceManager.Silenced(outputName, event); s != nil {
+			slog.Debug("Event silenced",
+				"silence_id", s.ID,
+				"output", outputName,
+				"topic", event.Topic,
+				"type", event.Type)
+			continue
+		}
+
+		if err := serviceManager.Send(outputName, event); err != nil {
+			slog.Error("Failed to send event to output",
+				"error", err,
+				"output", outputName,
+				"topic", event.Topic,
+				"type", event.Type)
+		}
+	}
+}
+
 func setupLogging(level, format string) {
 	var logLevel slog.Level
 	switch level {
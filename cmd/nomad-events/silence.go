@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"nomad-events/internal/silence"
+)
+
+const defaultAPIAddress = "http://127.0.0.1:8686"
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runSilenceCommand implements `nomad-events silence <add|list|expire>` and
+// returns the process exit code
+func runSilenceCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: nomad-events silence <add|list|expire> [options]")
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "add":
+		err = silenceAdd(args[1:])
+	case "list":
+		err = silenceList(args[1:])
+	case "expire":
+		err = silenceExpire(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown silence command %q (expected add, list or expire)\n", args[0])
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func silenceAdd(args []string) error {
+	fs := flag.NewFlagSet("silence add", flag.ExitOnError)
+	var matchers, outputs stringList
+	fs.Var(&matchers, "matcher", "Event matcher such as Topic=Node or Payload.Job.ID=~web-.* (repeatable)")
+	fs.Var(&outputs, "output", "Only silence this output (repeatable; default all outputs)")
+	expression := fs.String("expression", "", "CEL expression over `event` that must be true")
+	duration := fs.String("duration", "1h", "How long the silence lasts")
+	endsAt := fs.String("ends-at", "", "RFC3339 end time (overrides -duration)")
+	author := fs.String("author", currentUser(), "Who created the silence")
+	comment := fs.String("comment", "", "Why the silence was created (required)")
+	apiAddress := fs.String("api", defaultAPIAddress, "Address of the nomad-events API")
+	fs.Parse(args)
+
+	req := silence.AddRequest{
+		Expression: *expression,
+		Outputs:    outputs,
+		CreatedBy:  *author,
+		Comment:    *comment,
+	}
+
+	for _, m := range matchers {
+		matcher, err := silence.ParseMatcher(m)
+		if err != nil {
+			return err
+		}
+		req.Matchers = append(req.Matchers, matcher)
+	}
+
+	if *endsAt != "" {
+		t, err := time.Parse(time.RFC3339, *endsAt)
+		if err != nil {
+			return fmt.Errorf("invalid -ends-at: %w", err)
+		}
+		req.EndsAt = &t
+	} else {
+		req.Duration = *duration
+	}
+
+	s, err := silence.NewClient(*apiAddress).Add(req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created silence %s (%s until %s)\n", s.ID, s.Status, s.EndsAt.Format(time.RFC3339))
+	return nil
+}
+
+func silenceList(args []string) error {
+	fs := flag.NewFlagSet("silence list", flag.ExitOnError)
+	apiAddress := fs.String("api", defaultAPIAddress, "Address of the nomad-events API")
+	all := fs.Bool("all", false, "Include expired silences")
+	fs.Parse(args)
+
+	silences, err := silence.NewClient(*apiAddress).List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tENDS AT\tCREATED BY\tMATCHERS\tCOMMENT")
+	for _, s := range silences {
+		if s.Status == silence.StatusExpired && !*all {
+			continue
+		}
+
+		criteria := make([]string, 0, len(s.Matchers)+2)
+		for _, m := range s.Matchers {
+			criteria = append(criteria, m.String())
+		}
+		if s.Expression != "" {
+			criteria = append(criteria, s.Expression)
+		}
+		if len(s.Outputs) > 0 {
+			criteria = append(criteria, "outputs="+strings.Join(s.Outputs, ","))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID, s.Status, s.EndsAt.Format(time.RFC3339), s.CreatedBy, strings.Join(criteria, " "), s.Comment)
+	}
+	return w.Flush()
+}
+
+func silenceExpire(args []string) error {
+	fs := flag.NewFlagSet("silence expire", flag.ExitOnError)
+	apiAddress := fs.String("api", defaultAPIAddress, "Address of the nomad-events API")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("silence ID is required")
+	}
+
+	client := silence.NewClient(*apiAddress)
+	for _, id := range fs.Args() {
+		if _, err := client.Expire(id); err != nil {
+			return fmt.Errorf("failed to expire %s: %w", id, err)
+		}
+		fmt.Printf("Expired silence %s\n", id)
+	}
+	return nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
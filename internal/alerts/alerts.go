@@ -0,0 +1,326 @@
+package alerts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Alert event types and statuses
+const (
+	TypeAlertFiring   = "AlertFiring"
+	TypeAlertResolved = "AlertResolved"
+
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Reasons an alert resolved, in the Reason of AlertResolved events
+const (
+	ReasonRecovered       = "recovered"
+	ReasonExpired         = "expired"
+	ReasonJobDeregistered = "job_deregistered"
+)
+
+// DefaultMaxAge is how long an alert stays open without recovering
+const DefaultMaxAge = 7 * 24 * time.Hour
+
+// Tracker opens an alert when an event matches a problem expression and
+// resolves it when a later event with the same key matches the recovery
+// expression. Each emits a single derived event carrying the alert status and
+// a fingerprint that stays the same from firing to resolved.
+type Tracker struct {
+	alerts         []alert
+	templateEngine *template.Engine
+	now            func() time.Time
+
+	mu   sync.Mutex
+	open map[string]*openAlert // fingerprint -> alert
+}
+
+type alert struct {
+	name        string
+	keyTemplate string
+	problem     cel.Program
+	recovery    cel.Program
+	maxAge      time.Duration
+}
+
+type openAlert struct {
+	alert       alert
+	key         string
+	job         string // namespace/job ID of the firing event, empty if it has none
+	startedAt   time.Time
+	firingEvent map[string]interface{}
+	namespace   string
+	index       uint64
+}
+
+// NewTracker compiles the configured alerts
+func NewTracker(cfgs []config.AlertConfig) (*Tracker, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("diff", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	t := &Tracker{
+		templateEngine: template.NewEngine(),
+		now:            time.Now,
+		open:           make(map[string]*openAlert),
+	}
+
+	for _, cfg := range cfgs {
+		problem, err := compile(env, cfg.Problem)
+		if err != nil {
+			return nil, fmt.Errorf("alert %q: invalid problem expression: %w", cfg.Name, err)
+		}
+		recovery, err := compile(env, cfg.Recovery)
+		if err != nil {
+			return nil, fmt.Errorf("alert %q: invalid recovery expression: %w", cfg.Name, err)
+		}
+		if err := t.templateEngine.Compile(cfg.Key); err != nil {
+			return nil, fmt.Errorf("alert %q: invalid key template: %w", cfg.Name, err)
+		}
+
+		maxAge := DefaultMaxAge
+		if cfg.MaxAge != "" {
+			maxAge, err = time.ParseDuration(cfg.MaxAge)
+			if err != nil || maxAge <= 0 {
+				return nil, fmt.Errorf("alert %q: invalid max_age %q", cfg.Name, cfg.MaxAge)
+			}
+		}
+
+		t.alerts = append(t.alerts, alert{
+			name:        cfg.Name,
+			keyTemplate: cfg.Key,
+			problem:     problem,
+			recovery:    recovery,
+			maxAge:      maxAge,
+		})
+	}
+
+	return t, nil
+}
+
+func compile(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return env.Program(ast)
+}
+
+// Observe returns AlertFiring events for newly opened alerts and AlertResolved
+// events for open alerts whose recovery expression matched. Deregistering a
+// job resolves the alerts opened by its events, since they cannot recover.
+func (t *Tracker) Observe(event nomad.Event) []nomad.Event {
+	eventMap := nomad.EventMap(event)
+	evalContext := map[string]interface{}{
+		"event": eventMap,
+		"diff":  event.Diff,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var derived []nomad.Event
+
+	// Alerts opened before the job was deregistered cannot recover
+	if event.Topic == "Job" && event.Type == "JobDeregistered" {
+		if job, ok := eventJob(event); ok {
+			for _, fingerprint := range t.openFingerprints() {
+				open := t.open[fingerprint]
+				if open.job != job {
+					continue
+				}
+				derived = append(derived, t.resolve(fingerprint, event, open, ReasonJobDeregistered, map[string]interface{}{
+					"Message": fmt.Sprintf("%s resolved for %s: job %s was deregistered", open.alert.name, open.key, job),
+				}))
+			}
+		}
+	}
+
+	for _, a := range t.alerts {
+		problem := matches(a.problem, evalContext)
+		recovery := !problem && matches(a.recovery, evalContext)
+		if !problem && !recovery {
+			continue
+		}
+
+		key, ok := t.renderKey(a, event)
+		if !ok {
+			continue
+		}
+		fingerprint := Fingerprint(a.name, key)
+		open, isOpen := t.open[fingerprint]
+
+		switch {
+		case problem && !isOpen:
+			job, _ := eventJob(event)
+			open = &openAlert{
+				alert:       a,
+				key:         key,
+				job:         job,
+				startedAt:   t.now(),
+				firingEvent: eventMap,
+				namespace:   event.Namespace,
+				index:       event.Index,
+			}
+			t.open[fingerprint] = open
+			derived = append(derived, t.alertEvent(TypeAlertFiring, StatusFiring, a, key, fingerprint, event, open, map[string]interface{}{
+				"Message": fmt.Sprintf("%s firing for %s", a.name, key),
+			}))
+
+		case recovery && isOpen:
+			now := t.now()
+			duration := now.Sub(open.startedAt).Truncate(time.Second)
+			derived = append(derived, t.resolve(fingerprint, event, open, ReasonRecovered, map[string]interface{}{
+				"Message":       fmt.Sprintf("%s resolved for %s after %s", a.name, key, duration),
+				"RecoveryEvent": eventMap,
+			}))
+		}
+	}
+
+	return derived
+}
+
+// Sweep resolves alerts that have been open for longer than their max age,
+// such as alerts whose recovery event was missed
+func (t *Tracker) Sweep() []nomad.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var derived []nomad.Event
+	for _, fingerprint := range t.openFingerprints() {
+		open := t.open[fingerprint]
+		if now.Sub(open.startedAt) <= open.alert.maxAge {
+			continue
+		}
+		event := nomad.Event{Namespace: open.namespace, Index: open.index}
+		derived = append(derived, t.resolve(fingerprint, event, open, ReasonExpired, map[string]interface{}{
+			"Message": fmt.Sprintf("%s expired for %s after %s without recovering", open.alert.name, open.key, open.alert.maxAge),
+		}))
+	}
+	return derived
+}
+
+// resolve closes an open alert and builds its AlertResolved event. Callers must hold t.mu.
+func (t *Tracker) resolve(fingerprint string, event nomad.Event, open *openAlert, reason string, fields map[string]interface{}) nomad.Event {
+	delete(t.open, fingerprint)
+
+	now := t.now()
+	fields["Reason"] = reason
+	fields["ResolvedAt"] = now.UTC().Format(time.RFC3339)
+	fields["Duration"] = now.Sub(open.startedAt).Truncate(time.Second).String()
+	return t.alertEvent(TypeAlertResolved, StatusResolved, open.alert, open.key, fingerprint, event, open, fields)
+}
+
+// openFingerprints returns the open alerts in a stable order. Callers must hold t.mu.
+func (t *Tracker) openFingerprints() []string {
+	fingerprints := make([]string, 0, len(t.open))
+	for fingerprint := range t.open {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
+}
+
+// Open returns the fingerprints of the alerts currently firing
+func (t *Tracker) Open() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.openFingerprints()
+}
+
+// alertEvent builds a derived alert event linked to the event that opened it
+func (t *Tracker) alertEvent(eventType, status string, a alert, key, fingerprint string, event nomad.Event, open *openAlert, fields map[string]interface{}) nomad.Event {
+	payload := map[string]interface{}{
+		"Alert":       a.name,
+		"AlertKey":    key,
+		"StartedAt":   open.startedAt.UTC().Format(time.RFC3339),
+		"FiringEvent": open.firingEvent,
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+
+	return nomad.Event{
+		Topic:       nomad.TopicDerived,
+		Type:        eventType,
+		Key:         key,
+		Namespace:   event.Namespace,
+		Index:       event.Index,
+		Payload:     payload,
+		Diff:        event.Diff,
+		Status:      status,
+		Fingerprint: fingerprint,
+	}
+}
+
+func (t *Tracker) renderKey(a alert, event nomad.Event) (string, bool) {
+	key, err := t.templateEngine.ProcessText(a.keyTemplate, event)
+	if err != nil {
+		slog.Warn("Failed to render alert key",
+			"alert", a.name,
+			"error", err,
+			"topic", event.Topic,
+			"type", event.Type)
+		return "", false
+	}
+
+	key = strings.TrimSpace(key)
+	return key, key != ""
+}
+
+// eventJob returns the namespace/job ID an event is about: the job of Job
+// events, or the JobID of the payload object named after the topic
+func eventJob(event nomad.Event) (string, bool) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	var jobID string
+	if job, ok := payload["Job"].(map[string]interface{}); ok && event.Topic == "Job" {
+		jobID, _ = job["ID"].(string)
+	} else if object, ok := payload[event.Topic].(map[string]interface{}); ok {
+		jobID, _ = object["JobID"].(string)
+	}
+	if jobID == "" {
+		return "", false
+	}
+
+	namespace := event.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace + "/" + jobID, true
+}
+
+func matches(program cel.Program, evalContext map[string]interface{}) bool {
+	result, _, err := program.Eval(evalContext)
+	return err == nil && result == types.True
+}
+
+// Fingerprint returns the stable identifier of an alert instance
+func Fingerprint(name, key string) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + key))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,189 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var nodeDown = config.AlertConfig{
+	Name:     "node_down",
+	Problem:  "event.Topic == 'Node' && event.Payload.Node.Status == 'down'",
+	Recovery: "event.Topic == 'Node' && event.Payload.Node.Status == 'ready'",
+	Key:      "{{ .Payload.Node.ID }}",
+}
+
+func nodeEvent(id, status string) nomad.Event {
+	return nomad.Event{
+		Topic:     "Node",
+		Type:      "NodeEvent",
+		Key:       id,
+		Namespace: "default",
+		Index:     10,
+		Payload: map[string]interface{}{
+			"Node": map[string]interface{}{"ID": id, "Status": status},
+		},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker, err := NewTracker([]config.AlertConfig{nodeDown})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	// Recovery without an open alert does nothing
+	assert.Empty(t, tracker.Observe(nodeEvent("node-1", "ready")))
+
+	derived := tracker.Observe(nodeEvent("node-1", "down"))
+	require.Len(t, derived, 1)
+	firing := derived[0]
+	assert.Equal(t, nomad.TopicDerived, firing.Topic)
+	assert.Equal(t, TypeAlertFiring, firing.Type)
+	assert.Equal(t, StatusFiring, firing.Status)
+	assert.Equal(t, "node-1", firing.Key)
+	assert.Equal(t, Fingerprint("node_down", "node-1"), firing.Fingerprint)
+
+	payload := firing.Payload.(map[string]interface{})
+	assert.Equal(t, "node_down firing for node-1", payload["Message"])
+	assert.Equal(t, "node_down", payload["Alert"])
+	assert.Equal(t, "node-1", payload["AlertKey"])
+
+	// Repeated problem events while open emit nothing
+	assert.Empty(t, tracker.Observe(nodeEvent("node-1", "down")))
+	assert.Equal(t, []string{firing.Fingerprint}, tracker.Open())
+
+	// Other keys are tracked separately
+	other := tracker.Observe(nodeEvent("node-2", "down"))
+	require.Len(t, other, 1)
+	assert.NotEqual(t, firing.Fingerprint, other[0].Fingerprint)
+
+	now = now.Add(5 * time.Minute)
+	derived = tracker.Observe(nodeEvent("node-1", "ready"))
+	require.Len(t, derived, 1)
+	resolved := derived[0]
+	assert.Equal(t, TypeAlertResolved, resolved.Type)
+	assert.Equal(t, StatusResolved, resolved.Status)
+	assert.Equal(t, firing.Fingerprint, resolved.Fingerprint)
+
+	payload = resolved.Payload.(map[string]interface{})
+	assert.Equal(t, "node_down resolved for node-1 after 5m0s", payload["Message"])
+	assert.Equal(t, ReasonRecovered, payload["Reason"])
+	assert.Equal(t, "5m0s", payload["Duration"])
+	assert.Equal(t, "2024-01-01T12:00:00Z", payload["StartedAt"])
+	assert.Equal(t, "2024-01-01T12:05:00Z", payload["ResolvedAt"])
+	firingEvent := payload["FiringEvent"].(map[string]interface{})
+	assert.Equal(t, "down", firingEvent["Payload"].(map[string]interface{})["Node"].(map[string]interface{})["Status"])
+	recoveryEvent := payload["RecoveryEvent"].(map[string]interface{})
+	assert.Equal(t, "ready", recoveryEvent["Payload"].(map[string]interface{})["Node"].(map[string]interface{})["Status"])
+
+	// The alert can fire again after resolving
+	derived = tracker.Observe(nodeEvent("node-1", "down"))
+	require.Len(t, derived, 1)
+	assert.Equal(t, TypeAlertFiring, derived[0].Type)
+}
+
+func TestTrackerSweepExpiresAlerts(t *testing.T) {
+	cfg := nodeDown
+	cfg.MaxAge = "1h"
+	tracker, err := NewTracker([]config.AlertConfig{cfg})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	firing := tracker.Observe(nodeEvent("node-1", "down"))
+	require.Len(t, firing, 1)
+
+	now = now.Add(time.Hour)
+	assert.Empty(t, tracker.Sweep())
+
+	now = now.Add(time.Minute)
+	derived := tracker.Sweep()
+	require.Len(t, derived, 1)
+	assert.Equal(t, TypeAlertResolved, derived[0].Type)
+	assert.Equal(t, firing[0].Fingerprint, derived[0].Fingerprint)
+	assert.Equal(t, "default", derived[0].Namespace)
+	payload := derived[0].Payload.(map[string]interface{})
+	assert.Equal(t, ReasonExpired, payload["Reason"])
+	assert.Equal(t, "node_down expired for node-1 after 1h0m0s without recovering", payload["Message"])
+	assert.Empty(t, tracker.Open())
+}
+
+func TestTrackerResolvesAlertsOfDeregisteredJobs(t *testing.T) {
+	tracker, err := NewTracker([]config.AlertConfig{{
+		Name:     "alloc_failed",
+		Problem:  "event.Topic == 'Allocation' && event.Payload.Allocation.ClientStatus == 'failed'",
+		Recovery: "event.Topic == 'Allocation' && event.Payload.Allocation.ClientStatus == 'running'",
+		Key:      "{{ .Payload.Allocation.JobID }}/{{ .Payload.Allocation.TaskGroup }}",
+	}})
+	require.NoError(t, err)
+
+	allocEvent := func(namespace, jobID string) nomad.Event {
+		return nomad.Event{
+			Topic:     "Allocation",
+			Namespace: namespace,
+			Payload: map[string]interface{}{
+				"Allocation": map[string]interface{}{"JobID": jobID, "TaskGroup": "web", "ClientStatus": "failed"},
+			},
+		}
+	}
+	require.Len(t, tracker.Observe(allocEvent("default", "api")), 1)
+	require.Len(t, tracker.Observe(allocEvent("default", "batch")), 1)
+
+	// The same job name in another namespace is a different job
+	deregistered := func(namespace string) nomad.Event {
+		return nomad.Event{
+			Topic:     "Job",
+			Type:      "JobDeregistered",
+			Namespace: namespace,
+			Payload:   map[string]interface{}{"Job": map[string]interface{}{"ID": "api"}},
+		}
+	}
+	assert.Empty(t, tracker.Observe(deregistered("staging")))
+
+	derived := tracker.Observe(deregistered("default"))
+	require.Len(t, derived, 1)
+	assert.Equal(t, TypeAlertResolved, derived[0].Type)
+	assert.Equal(t, Fingerprint("alloc_failed", "api/web"), derived[0].Fingerprint)
+	assert.Equal(t, ReasonJobDeregistered, derived[0].Payload.(map[string]interface{})["Reason"])
+	assert.Equal(t, []string{Fingerprint("alloc_failed", "batch/web")}, tracker.Open())
+}
+
+func TestTrackerIgnoresEmptyKeys(t *testing.T) {
+	cfg := nodeDown
+	cfg.Key = "{{ .Namespace }}"
+	tracker, err := NewTracker([]config.AlertConfig{cfg})
+	require.NoError(t, err)
+
+	event := nodeEvent("node-1", "down")
+	event.Namespace = ""
+	assert.Empty(t, tracker.Observe(event))
+}
+
+func TestFingerprintIsStable(t *testing.T) {
+	assert.Equal(t, Fingerprint("node_down", "node-1"), Fingerprint("node_down", "node-1"))
+	assert.NotEqual(t, Fingerprint("node_down", "node-1"), Fingerprint("job_dead", "node-1"))
+	assert.Len(t, Fingerprint("node_down", "node-1"), 16)
+}
+
+func TestNewTrackerInvalidExpression(t *testing.T) {
+	cfg := nodeDown
+	cfg.Recovery = "event.Topic =="
+
+	_, err := NewTracker([]config.AlertConfig{cfg})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `alert "node_down": invalid recovery expression`)
+
+	cfg = nodeDown
+	cfg.Key = "{{ .Payload.Node.ID"
+	_, err = NewTracker([]config.AlertConfig{cfg})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `alert "node_down": invalid key template`)
+}
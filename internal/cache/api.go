@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Register adds GET /api/v1/cache to a mux, returning the stats of each named cache
+func Register(mux *http.ServeMux, caches map[string]*Cache) {
+	mux.HandleFunc("GET /api/v1/cache", func(w http.ResponseWriter, r *http.Request) {
+		stats := make(map[string]Stats, len(caches))
+		for name, c := range caches {
+			stats[name] = c.Stats()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	})
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nomad-events/internal/nomad"
+)
+
+// Defaults for optional cache settings
+const (
+	DefaultTTL     = 30 * time.Second
+	DefaultTimeout = 5 * time.Second
+)
+
+// FetchFunc looks up a value, returning the Nomad index it was read at
+type FetchFunc func(ctx context.Context) (interface{}, uint64, error)
+
+// Cache holds the results of Nomad API lookups for a TTL. Concurrent lookups
+// of the same key share a single call, every call is bounded by a timeout, and
+// events from the stream invalidate entries older than the change they carry.
+type Cache struct {
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	tagged  map[string]map[string]struct{} // tag -> keys
+	calls   map[string]*call
+	stats   Stats
+}
+
+type entry struct {
+	value     interface{}
+	index     uint64
+	tags      []string
+	expiresAt time.Time
+}
+
+// call is a lookup in flight; waiters share its result
+type call struct {
+	done     chan struct{}
+	value    interface{}
+	err      error
+	tags     []string
+	minIndex uint64 // results older than this were invalidated while in flight
+}
+
+// Stats counts cache activity since the cache was created
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Coalesced     uint64 `json:"coalesced"`
+	Errors        uint64 `json:"errors"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+// New creates a cache. Zero durations use the defaults.
+func New(ttl, timeout time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Cache{
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+		tagged:  make(map[string]map[string]struct{}),
+		calls:   make(map[string]*call),
+	}
+}
+
+// Get returns the cached value for key, or fetches it. Tags name the Nomad
+// objects the value depends on (see JobTag and friends) for invalidation.
+// Errors are returned to every waiter but not cached.
+func (c *Cache) Get(ctx context.Context, key string, tags []string, fetch FetchFunc) (interface{}, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expiresAt) {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return e.value, nil
+	}
+
+	cl, inFlight := c.calls[key]
+	if inFlight {
+		c.stats.Coalesced++
+	} else {
+		c.stats.Misses++
+		cl = &call{done: make(chan struct{}), tags: tags}
+		c.calls[key] = cl
+		go c.fetch(key, cl, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch runs a lookup detached from any one caller, so a caller giving up
+// does not fail the others waiting on it
+func (c *Cache) fetch(key string, cl *call, fetch FetchFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	value, index, err := fetch(ctx)
+	cancel()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	cl.value, cl.err = value, err
+	switch {
+	case err != nil:
+		c.stats.Errors++
+	case index >= cl.minIndex:
+		c.remove(key)
+		c.entries[key] = &entry{value: value, index: index, tags: cl.tags, expiresAt: c.now().Add(c.ttl)}
+		for _, tag := range cl.tags {
+			if c.tagged[tag] == nil {
+				c.tagged[tag] = make(map[string]struct{})
+			}
+			c.tagged[tag][key] = struct{}{}
+		}
+	}
+	c.mu.Unlock()
+
+	close(cl.done)
+}
+
+// Invalidate drops entries depending on tag that were read before index.
+// Lookups in flight are still returned to their callers but not cached if
+// they were read before index.
+func (c *Cache) Invalidate(tag string, index uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.tagged[tag] {
+		if e := c.entries[key]; e != nil && e.index < index {
+			c.remove(key)
+			c.stats.Invalidations++
+		}
+	}
+
+	for _, cl := range c.calls {
+		for _, t := range cl.tags {
+			if t == tag && cl.minIndex < index {
+				cl.minIndex = index
+			}
+		}
+	}
+}
+
+// Observe invalidates the entries an event from the stream has changed
+func (c *Cache) Observe(event nomad.Event) {
+	for _, tag := range EventTags(event) {
+		c.Invalidate(tag, event.Index)
+	}
+}
+
+// Prune removes expired entries
+func (c *Cache) Prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			c.remove(key)
+		}
+	}
+}
+
+// Stats returns the cache counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// remove deletes an entry and its tag references. Callers must hold c.mu.
+func (c *Cache) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, tag := range e.tags {
+		delete(c.tagged[tag], key)
+		if len(c.tagged[tag]) == 0 {
+			delete(c.tagged, tag)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter returns a fetch func that counts its calls and returns the call number
+func counter(calls *int32, index uint64) FetchFunc {
+	return func(ctx context.Context) (interface{}, uint64, error) {
+		return int(atomic.AddInt32(calls, 1)), index, nil
+	}
+}
+
+func TestCacheGet(t *testing.T) {
+	c := New(time.Minute, time.Second)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	var calls int32
+	value, err := c.Get(context.Background(), "job/web", nil, counter(&calls, 5))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	value, err = c.Get(context.Background(), "job/web", nil, counter(&calls, 5))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	now = now.Add(time.Minute)
+	value, err = c.Get(context.Background(), "job/web", nil, counter(&calls, 5))
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+
+	now = now.Add(time.Minute)
+	c.Prune()
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestCacheCoalescesConcurrentLookups(t *testing.T) {
+	c := New(time.Minute, time.Second)
+
+	release := make(chan struct{})
+	var calls int32
+	fetch := func(ctx context.Context) (interface{}, uint64, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "job", 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.Get(context.Background(), "job/web", nil, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "job", value)
+		}()
+	}
+
+	// Wait until every caller is waiting on the single lookup
+	require.Eventually(t, func() bool {
+		stats := c.Stats()
+		return stats.Misses+stats.Coalesced == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, uint64(9), c.Stats().Coalesced)
+}
+
+func TestCacheErrorsAreNotCached(t *testing.T) {
+	c := New(time.Minute, time.Second)
+
+	_, err := c.Get(context.Background(), "job/web", nil, func(ctx context.Context) (interface{}, uint64, error) {
+		return nil, 0, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+
+	var calls int32
+	value, err := c.Get(context.Background(), "job/web", nil, counter(&calls, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, uint64(1), c.Stats().Errors)
+}
+
+func TestCacheTimeout(t *testing.T) {
+	c := New(time.Minute, 10*time.Millisecond)
+
+	_, err := c.Get(context.Background(), "job/web", nil, func(ctx context.Context) (interface{}, uint64, error) {
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCacheCallerCancellation(t *testing.T) {
+	c := New(time.Minute, time.Second)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Get(ctx, "job/web", nil, func(ctx context.Context) (interface{}, uint64, error) {
+		<-release
+		return "job", 1, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c := New(time.Minute, time.Second)
+	var calls int32
+	tags := []string{JobTag("default", "web")}
+
+	c.Get(context.Background(), "job/web", tags, counter(&calls, 10))
+	c.Get(context.Background(), "jobAllocs/web", tags, counter(&calls, 10))
+	c.Get(context.Background(), "job/api", []string{JobTag("default", "api")}, counter(&calls, 10))
+
+	// Changes the cached values already include are ignored
+	c.Invalidate(JobTag("default", "web"), 10)
+	assert.Equal(t, 3, c.Stats().Entries)
+
+	c.Invalidate(JobTag("default", "web"), 11)
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, uint64(2), stats.Invalidations)
+
+	value, _ := c.Get(context.Background(), "job/web", tags, counter(&calls, 11))
+	assert.Equal(t, 4, value)
+}
+
+func TestCacheInvalidateInFlight(t *testing.T) {
+	c := New(time.Minute, time.Second)
+	tags := []string{JobTag("default", "web")}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		value, err := c.Get(context.Background(), "job/web", tags, func(ctx context.Context) (interface{}, uint64, error) {
+			close(started)
+			<-release
+			return "stale", 10, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "stale", value)
+	}()
+
+	<-started
+	c.Invalidate(JobTag("default", "web"), 11)
+	close(release)
+	<-done
+
+	// The lookup read before the change is returned but not cached
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestEventTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    nomad.Event
+		expected []string
+	}{
+		{
+			name: "job",
+			event: nomad.Event{Topic: "Job", Payload: map[string]interface{}{
+				"Job": map[string]interface{}{"ID": "web"},
+			}},
+			expected: []string{"job/default/web"},
+		},
+		{
+			name: "allocation",
+			event: nomad.Event{Topic: "Allocation", Payload: map[string]interface{}{
+				"Allocation": map[string]interface{}{"ID": "alloc-1", "JobID": "web", "EvalID": "eval-1", "DeploymentID": ""},
+			}},
+			expected: []string{"alloc/alloc-1", "job/default/web", "eval/eval-1"},
+		},
+		{
+			name: "job in another namespace",
+			event: nomad.Event{Topic: "Evaluation", Payload: map[string]interface{}{
+				"Evaluation": map[string]interface{}{"ID": "eval-1", "JobID": "web", "Namespace": "batch"},
+			}},
+			expected: []string{"eval/eval-1", "job/batch/web"},
+		},
+		{
+			name: "deployment",
+			event: nomad.Event{Topic: "Deployment", Payload: map[string]interface{}{
+				"Deployment": map[string]interface{}{"ID": "deploy-1", "JobID": "web"},
+			}},
+			expected: []string{"deployment/deploy-1", "job/default/web"},
+		},
+		{
+			name: "service",
+			event: nomad.Event{Topic: "Service", Payload: map[string]interface{}{
+				"Service": map[string]interface{}{"ID": "_nomad-task-1", "ServiceName": "web"},
+			}},
+			expected: []string{"service/web"},
+		},
+		{
+			name:     "derived",
+			event:    nomad.Event{Topic: nomad.TopicDerived, Payload: map[string]interface{}{"Message": "x"}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, EventTags(tt.event))
+		})
+	}
+}
+
+func TestCacheObserve(t *testing.T) {
+	c := New(time.Minute, time.Second)
+	var calls int32
+	c.Get(context.Background(), "nodeInfo/node-1", []string{NodeTag("node-1")}, counter(&calls, 3))
+
+	c.Observe(nomad.Event{Topic: "Node", Index: 4, Payload: map[string]interface{}{
+		"Node": map[string]interface{}{"ID": "node-1"},
+	}})
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestCacheRegister(t *testing.T) {
+	c := New(time.Minute, time.Second)
+	var calls int32
+	c.Get(context.Background(), "job/web", nil, counter(&calls, 1))
+
+	mux := http.NewServeMux()
+	Register(mux, map[string]*Cache{"templates": c})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/cache")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var stats map[string]Stats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, uint64(1), stats["templates"].Misses)
+	assert.Equal(t, 1, stats["templates"].Entries)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import "nomad-events/internal/nomad"
+
+// JobTag identifies lookups that depend on a job. Job IDs are only unique
+// within a namespace; an empty namespace is the default one.
+func JobTag(namespace, id string) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return "job/" + namespace + "/" + id
+}
+
+// NodeTag identifies lookups that depend on a node
+func NodeTag(id string) string { return "node/" + id }
+
+// AllocationTag identifies lookups that depend on an allocation
+func AllocationTag(id string) string { return "alloc/" + id }
+
+// DeploymentTag identifies lookups that depend on a deployment
+func DeploymentTag(id string) string { return "deployment/" + id }
+
+// EvaluationTag identifies lookups that depend on an evaluation
+func EvaluationTag(id string) string { return "eval/" + id }
+
+// ServiceTag identifies lookups that depend on a service's registrations
+func ServiceTag(name string) string { return "service/" + name }
+
+// EventTags returns the tags of the objects changed by an event. Changes to
+// allocations, evaluations and deployments also change their job's lookups,
+// such as its allocations and summary.
+func EventTags(event nomad.Event) []string {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	object, _ := payload[event.Topic].(map[string]interface{})
+	if object == nil {
+		return nil
+	}
+	field := func(name string) string {
+		value, _ := object[name].(string)
+		return value
+	}
+
+	namespace := field("Namespace")
+	if namespace == "" {
+		namespace = event.Namespace
+	}
+
+	var tags []string
+	add := func(tag func(string) string, id string) {
+		if id != "" {
+			tags = append(tags, tag(id))
+		}
+	}
+	job := func(id string) string { return JobTag(namespace, id) }
+
+	switch event.Topic {
+	case "Job":
+		add(job, field("ID"))
+	case "Node":
+		add(NodeTag, field("ID"))
+	case "Allocation":
+		add(AllocationTag, field("ID"))
+		add(job, field("JobID"))
+		add(EvaluationTag, field("EvalID"))
+		add(DeploymentTag, field("DeploymentID"))
+	case "Evaluation":
+		add(EvaluationTag, field("ID"))
+		add(job, field("JobID"))
+	case "Deployment":
+		add(DeploymentTag, field("ID"))
+		add(job, field("JobID"))
+	case "Service":
+		add(ServiceTag, field("ServiceName"))
+	}
+
+	return tags
+}
@@ -0,0 +1,265 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Check event types
+const (
+	TypeCheckFailed    = "CheckFailed"
+	TypeCheckRecovered = "CheckRecovered"
+)
+
+// Defaults for optional check settings
+const (
+	DefaultInterval = time.Minute
+	DefaultTopic    = "Check"
+)
+
+// payloadKeys is the payload field holding the item, matching the stream
+// event payload for the same resource
+var payloadKeys = map[string]string{
+	config.CheckQueryJobs:        "Job",
+	config.CheckQueryNodes:       "Node",
+	config.CheckQueryAllocations: "Allocation",
+	config.CheckQueryDeployments: "Deployment",
+}
+
+// Scheduler periodically runs checks against the Nomad API. An item fails a
+// check once its condition has held for the check's `for` duration, emitting a
+// CheckFailed event, and emits CheckRecovered once the condition stops holding.
+type Scheduler struct {
+	checks []*check
+	source Source
+	now    func() time.Time
+}
+
+type check struct {
+	name      string
+	query     string
+	namespace string
+	topic     string
+	interval  time.Duration
+	holdFor   time.Duration
+	condition cel.Program
+
+	mu    sync.Mutex
+	items map[string]*itemState // namespace/ID -> state
+}
+
+type itemState struct {
+	matchingSince time.Time
+	failed        bool
+}
+
+// NewScheduler compiles the configured checks. The source may be nil when
+// only validating the configuration.
+func NewScheduler(cfgs []config.CheckConfig, source Source) (*Scheduler, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("item", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	s := &Scheduler{source: source, now: time.Now}
+	for _, cfg := range cfgs {
+		ast, issues := env.Compile(cfg.Condition)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("check %q: invalid condition: %w", cfg.Name, issues.Err())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: failed to create program: %w", cfg.Name, err)
+		}
+
+		c := &check{
+			name:      cfg.Name,
+			query:     cfg.Query,
+			namespace: cfg.Namespace,
+			topic:     cfg.Topic,
+			interval:  DefaultInterval,
+			condition: program,
+			items:     make(map[string]*itemState),
+		}
+		if c.topic == "" {
+			c.topic = DefaultTopic
+		}
+		if cfg.Interval != "" {
+			if c.interval, err = time.ParseDuration(cfg.Interval); err != nil {
+				return nil, fmt.Errorf("check %q: invalid interval %q: %w", cfg.Name, cfg.Interval, err)
+			}
+		}
+		if cfg.For != "" {
+			if c.holdFor, err = time.ParseDuration(cfg.For); err != nil {
+				return nil, fmt.Errorf("check %q: invalid for %q: %w", cfg.Name, cfg.For, err)
+			}
+		}
+
+		s.checks = append(s.checks, c)
+	}
+
+	return s, nil
+}
+
+// Run runs every check on its own interval, starting immediately, and sends
+// the resulting events to eventChan until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context, eventChan chan<- nomad.Event) {
+	var wg sync.WaitGroup
+	for _, c := range s.checks {
+		wg.Add(1)
+		go func(c *check) {
+			defer wg.Done()
+			s.runLoop(ctx, c, eventChan)
+		}(c)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) runLoop(ctx context.Context, c *check, eventChan chan<- nomad.Event) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, event := range s.run(ctx, c) {
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run queries the check's resource once and returns the events for items
+// whose state changed. Queries that fail leave the state untouched.
+func (s *Scheduler) run(ctx context.Context, c *check) []nomad.Event {
+	queryCtx, cancel := context.WithTimeout(ctx, c.interval)
+	defer cancel()
+
+	items, index, err := s.source.List(queryCtx, c.query, c.namespace)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Check query failed", "check", c.name, "query", c.query, "error", err)
+		}
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := s.now()
+	seen := make(map[string]bool, len(items))
+	var events []nomad.Event
+
+	for _, item := range items {
+		id, _ := item["ID"].(string)
+		if id == "" {
+			continue
+		}
+		namespace, _ := item["Namespace"].(string)
+		key := namespace + "/" + id
+		seen[key] = true
+
+		result, _, err := c.condition.Eval(map[string]interface{}{"item": item})
+		if err != nil {
+			slog.Debug("Check condition evaluation failed", "check", c.name, "id", id, "error", err)
+			continue
+		}
+		matching := result == types.True
+
+		st, tracked := c.items[key]
+		switch {
+		case matching && !tracked:
+			st = &itemState{matchingSince: now}
+			c.items[key] = st
+		case !matching && tracked:
+			delete(c.items, key)
+			if st.failed {
+				events = append(events, c.event(TypeCheckRecovered, id, namespace, index, item, st, now))
+			}
+			continue
+		case !matching:
+			continue
+		}
+
+		if !st.failed && now.Sub(st.matchingSince) >= c.holdFor {
+			st.failed = true
+			events = append(events, c.event(TypeCheckFailed, id, namespace, index, item, st, now))
+		}
+	}
+
+	// Items that no longer exist, e.g. garbage collected jobs, have recovered
+	keys := make([]string, 0, len(c.items))
+	for key := range c.items {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		st := c.items[key]
+		delete(c.items, key)
+		if st.failed {
+			namespace, id := splitKey(key)
+			events = append(events, c.event(TypeCheckRecovered, id, namespace, index, nil, st, now))
+		}
+	}
+
+	return events
+}
+
+// event builds a check event. Callers must hold c.mu.
+func (c *check) event(eventType, id, namespace string, index uint64, item map[string]interface{}, st *itemState, now time.Time) nomad.Event {
+	duration := now.Sub(st.matchingSince).Truncate(time.Second)
+	kind := strings.ToLower(payloadKeys[c.query])
+
+	var message string
+	if eventType == TypeCheckFailed {
+		message = fmt.Sprintf("Check %s failed for %s %s (failing for %s)", c.name, kind, id, duration)
+	} else {
+		message = fmt.Sprintf("Check %s recovered for %s %s after %s", c.name, kind, id, duration)
+	}
+
+	payload := map[string]interface{}{
+		"Message":  message,
+		"Check":    c.name,
+		"Since":    st.matchingSince.UTC().Format(time.RFC3339),
+		"Duration": duration.String(),
+	}
+	if item != nil {
+		payload[payloadKeys[c.query]] = item
+	}
+
+	return nomad.Event{
+		Topic:     c.topic,
+		Type:      eventType,
+		Key:       id,
+		Namespace: namespace,
+		Index:     index,
+		Payload:   payload,
+	}
+}
+
+func splitKey(key string) (namespace, id string) {
+	namespace, id, _ = strings.Cut(key, "/")
+	return namespace, id
+}
@@ -0,0 +1,241 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource returns a fixed list of items for every query
+type fakeSource struct {
+	items []map[string]interface{}
+	err   error
+	query string
+}
+
+func (f *fakeSource) List(ctx context.Context, query, namespace string) ([]map[string]interface{}, uint64, error) {
+	f.query = query
+	return f.items, 42, f.err
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestScheduler(t *testing.T, cfg config.CheckConfig, source Source) (*Scheduler, *testClock) {
+	s, err := NewScheduler([]config.CheckConfig{cfg}, source)
+	require.NoError(t, err)
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s.now = clock.Now
+	return s, clock
+}
+
+func node(id, eligibility string) map[string]interface{} {
+	return map[string]interface{}{
+		"ID":                    id,
+		"Name":                  "client-" + id,
+		"SchedulingEligibility": eligibility,
+	}
+}
+
+func eventTypes(events []nomad.Event) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = event.Type
+	}
+	return result
+}
+
+func TestSchedulerFailsAfterFor(t *testing.T) {
+	source := &fakeSource{items: []map[string]interface{}{node("node-1", "ineligible"), node("node-2", "eligible")}}
+	s, clock := newTestScheduler(t, config.CheckConfig{
+		Name:      "node_ineligible",
+		Query:     config.CheckQueryNodes,
+		Condition: "item.SchedulingEligibility == 'ineligible'",
+		For:       "24h",
+		Topic:     "NodeHealth",
+	}, source)
+	c := s.checks[0]
+
+	assert.Empty(t, s.run(context.Background(), c))
+	assert.Equal(t, config.CheckQueryNodes, source.query)
+
+	clock.Advance(23 * time.Hour)
+	assert.Empty(t, s.run(context.Background(), c))
+
+	clock.Advance(time.Hour)
+	events := s.run(context.Background(), c)
+	require.Equal(t, []string{TypeCheckFailed}, eventTypes(events))
+
+	event := events[0]
+	assert.Equal(t, "NodeHealth", event.Topic)
+	assert.Equal(t, "node-1", event.Key)
+	assert.Equal(t, uint64(42), event.Index)
+
+	payload := event.Payload.(map[string]interface{})
+	assert.Equal(t, "Check node_ineligible failed for node node-1 (failing for 24h0m0s)", payload["Message"])
+	assert.Equal(t, "node_ineligible", payload["Check"])
+	assert.Equal(t, "client-node-1", payload["Node"].(map[string]interface{})["Name"])
+
+	// Failing items are reported once
+	clock.Advance(time.Minute)
+	assert.Empty(t, s.run(context.Background(), c))
+
+	source.items[0]["SchedulingEligibility"] = "eligible"
+	events = s.run(context.Background(), c)
+	require.Equal(t, []string{TypeCheckRecovered}, eventTypes(events))
+	assert.Equal(t, "Check node_ineligible recovered for node node-1 after 24h1m0s", events[0].Payload.(map[string]interface{})["Message"])
+}
+
+func TestSchedulerResetsWhenConditionClears(t *testing.T) {
+	source := &fakeSource{items: []map[string]interface{}{node("node-1", "ineligible")}}
+	s, clock := newTestScheduler(t, config.CheckConfig{
+		Name:      "node_ineligible",
+		Query:     config.CheckQueryNodes,
+		Condition: "item.SchedulingEligibility == 'ineligible'",
+		For:       "10m",
+	}, source)
+	c := s.checks[0]
+
+	s.run(context.Background(), c)
+	clock.Advance(5 * time.Minute)
+
+	// Clearing before the check failed emits nothing and restarts the timer
+	source.items[0]["SchedulingEligibility"] = "eligible"
+	assert.Empty(t, s.run(context.Background(), c))
+
+	source.items[0]["SchedulingEligibility"] = "ineligible"
+	s.run(context.Background(), c)
+	clock.Advance(9 * time.Minute)
+	assert.Empty(t, s.run(context.Background(), c))
+
+	clock.Advance(time.Minute)
+	events := s.run(context.Background(), c)
+	require.Equal(t, []string{TypeCheckFailed}, eventTypes(events))
+	assert.Equal(t, DefaultTopic, events[0].Topic)
+}
+
+func TestSchedulerRecoversRemovedItems(t *testing.T) {
+	source := &fakeSource{items: []map[string]interface{}{
+		{"ID": "web", "Namespace": "prod", "Status": "dead"},
+	}}
+	s, _ := newTestScheduler(t, config.CheckConfig{
+		Name:      "job_dead",
+		Query:     config.CheckQueryJobs,
+		Condition: "item.Status == 'dead'",
+	}, source)
+	c := s.checks[0]
+
+	events := s.run(context.Background(), c)
+	require.Equal(t, []string{TypeCheckFailed}, eventTypes(events))
+	assert.Equal(t, "prod", events[0].Namespace)
+
+	source.items = nil
+	events = s.run(context.Background(), c)
+	require.Equal(t, []string{TypeCheckRecovered}, eventTypes(events))
+	assert.Equal(t, "web", events[0].Key)
+	assert.Equal(t, "prod", events[0].Namespace)
+	assert.NotContains(t, events[0].Payload.(map[string]interface{}), "Job")
+}
+
+func TestSchedulerQueryErrorKeepsState(t *testing.T) {
+	source := &fakeSource{items: []map[string]interface{}{{"ID": "web", "Status": "dead"}}}
+	s, _ := newTestScheduler(t, config.CheckConfig{
+		Name:      "job_dead",
+		Query:     config.CheckQueryJobs,
+		Condition: "item.Status == 'dead'",
+	}, source)
+	c := s.checks[0]
+
+	require.Len(t, s.run(context.Background(), c), 1)
+
+	source.err = errors.New("connection refused")
+	assert.Empty(t, s.run(context.Background(), c))
+	assert.Contains(t, c.items, "/web")
+
+	source.err = nil
+	assert.Empty(t, s.run(context.Background(), c))
+}
+
+func TestSchedulerRun(t *testing.T) {
+	source := &fakeSource{items: []map[string]interface{}{{"ID": "web", "Status": "dead"}}}
+	s, _ := newTestScheduler(t, config.CheckConfig{
+		Name:      "job_dead",
+		Query:     config.CheckQueryJobs,
+		Condition: "item.Status == 'dead'",
+		Interval:  "1h",
+	}, source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	eventChan := make(chan nomad.Event, 1)
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, eventChan)
+		close(done)
+	}()
+
+	select {
+	case event := <-eventChan:
+		assert.Equal(t, TypeCheckFailed, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("check did not run immediately")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestNewSchedulerInvalidCondition(t *testing.T) {
+	_, err := NewScheduler([]config.CheckConfig{{
+		Name:      "broken",
+		Query:     config.CheckQueryJobs,
+		Condition: "item.Status ==",
+	}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `check "broken": invalid condition`)
+}
+
+func TestAPISourceList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/nodes", r.URL.Path)
+		assert.Equal(t, "*", r.URL.Query().Get("namespace"))
+		w.Header().Set("X-Nomad-Index", "17")
+		w.Write([]byte(`[{"ID": "node-1", "Status": "ready", "SchedulingEligibility": "ineligible"}]`))
+	}))
+	defer server.Close()
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.Address = server.URL
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+
+	items, index, err := NewAPISource(client).List(context.Background(), config.CheckQueryNodes, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(17), index)
+	require.Len(t, items, 1)
+	assert.Equal(t, "node-1", items[0]["ID"])
+	assert.Equal(t, "ineligible", items[0]["SchedulingEligibility"])
+}
@@ -0,0 +1,72 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"nomad-events/internal/config"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Source lists the items of a resource from the Nomad API, as JSON-decoded
+// maps, along with the index the result was read at
+type Source interface {
+	List(ctx context.Context, query, namespace string) ([]map[string]interface{}, uint64, error)
+}
+
+// APISource lists resources through a Nomad API client
+type APISource struct {
+	client *api.Client
+}
+
+// NewAPISource creates a Source backed by a Nomad API client
+func NewAPISource(client *api.Client) *APISource {
+	return &APISource{client: client}
+}
+
+// List queries a resource. An empty namespace lists all namespaces.
+func (s *APISource) List(ctx context.Context, query, namespace string) ([]map[string]interface{}, uint64, error) {
+	if namespace == "" {
+		namespace = "*"
+	}
+	q := (&api.QueryOptions{Namespace: namespace}).WithContext(ctx)
+
+	var (
+		items interface{}
+		meta  *api.QueryMeta
+		err   error
+	)
+	switch query {
+	case config.CheckQueryJobs:
+		items, meta, err = s.client.Jobs().List(q)
+	case config.CheckQueryNodes:
+		items, meta, err = s.client.Nodes().List(q)
+	case config.CheckQueryAllocations:
+		items, meta, err = s.client.Allocations().List(q)
+	case config.CheckQueryDeployments:
+		items, meta, err = s.client.Deployments().List(q)
+	default:
+		return nil, 0, fmt.Errorf("unknown query %q", query)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list %s: %w", query, err)
+	}
+
+	// Round-trip through JSON so items look like event stream payloads to CEL
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal %s: %w", query, err)
+	}
+	var result []map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal %s: %w", query, err)
+	}
+
+	var index uint64
+	if meta != nil {
+		index = meta.LastIndex
+	}
+	return result, index, nil
+}
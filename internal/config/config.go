@@ -2,21 +2,169 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Nomad   NomadConfig       `yaml:"nomad"`
-	Outputs map[string]Output `yaml:"outputs"`
-	Routes  []Route           `yaml:"routes"`
+	Nomad    NomadConfig       `yaml:"nomad"`
+	API      *APIConfig        `yaml:"api,omitempty"`
+	Silences *SilencesConfig   `yaml:"silences,omitempty"`
+	Outputs  map[string]Output `yaml:"outputs"`
+	Routes   []Route           `yaml:"routes"`
+
+	// Named time intervals referenced by routes
+	TimeIntervals map[string][]TimeInterval `yaml:"time_intervals,omitempty"`
+
+	// Synthetic events derived from the event stream
+	DerivedEvents *DerivedEventsConfig `yaml:"derived_events,omitempty"`
+
+	// Periodic queries of the Nomad API that emit synthetic events
+	Checks []CheckConfig `yaml:"checks,omitempty"`
+
+	// Nomad objects looked up once per event before routing
+	Enrichment *EnrichmentConfig `yaml:"enrichment,omitempty"`
+
+	// Named templates any output can call with {{ template "name" . }}, and a
+	// directory of .tmpl files defining more, reloaded when they change
+	Templates   map[string]string `yaml:"templates,omitempty"`
+	TemplateDir string            `yaml:"template_dir,omitempty"`
+
+	// Slack buttons and menus that run Nomad operations, served on their own
+	// listen address apart from the API server
+	Interactions *InteractionsConfig `yaml:"interactions,omitempty"`
+}
+
+// Nomad operations that Slack actions can run
+const (
+	OperationRestartAlloc      = "restart_alloc"
+	OperationStopJob           = "stop_job"
+	OperationRevertJob         = "revert_job"
+	OperationPromoteDeployment = "promote_deployment"
+	OperationFailDeployment    = "fail_deployment"
+	OperationSilence           = "silence"
+)
+
+// InteractionsConfig receives the payloads Slack posts when someone clicks a
+// button or picks from a menu, and runs the operation allowed for its action_id
+type InteractionsConfig struct {
+	Listen        string         `yaml:"listen"`              // Address of the endpoint Slack posts to, e.g. "0.0.0.0:8687"; kept apart from the management API
+	SigningSecret string         `yaml:"signing_secret"`      // Signing secret of the Slack app
+	TeamID        string         `yaml:"team_id,omitempty"`   // Slack workspace ID allowed to run actions; any workspace the app is installed in if empty
+	AuditLog      string         `yaml:"audit_log,omitempty"` // JSON lines file recording every action; logged only if empty
+	Actions       []ActionConfig `yaml:"actions"`
+}
+
+// ActionConfig allows an action_id to run a Nomad operation on the object
+// named by the action's value
+type ActionConfig struct {
+	ActionID  string   `yaml:"action_id"`
+	Operation string   `yaml:"operation"`           // restart_alloc, stop_job, revert_job, promote_deployment, fail_deployment or silence
+	Users     []string `yaml:"users"`               // Slack user IDs allowed to run it, e.g. U024BE7LH
+	Namespace string   `yaml:"namespace,omitempty"` // Namespace of the object (default: nomad.namespace, then "default")
+	Duration  string   `yaml:"duration,omitempty"`  // How long silences last (default: 1h)
+}
+
+// EnrichmentConfig selects the objects attached to events as Enriched.Job,
+// Enriched.Node and Enriched.Allocation before they are routed
+type EnrichmentConfig struct {
+	Job        bool   `yaml:"job,omitempty"`
+	Node       bool   `yaml:"node,omitempty"`
+	Allocation bool   `yaml:"allocation,omitempty"`
+	CacheTTL   string `yaml:"cache_ttl,omitempty"` // How long lookups are cached (default: 30s)
+	Timeout    string `yaml:"timeout,omitempty"`   // Timeout of each lookup (default: 5s)
+
+	// Log excerpts attached to Allocation events with failed tasks as Logs
+	Logs *LogExcerptConfig `yaml:"logs,omitempty"`
+}
+
+// LogExcerptConfig limits and redacts the task logs attached to failure events
+type LogExcerptConfig struct {
+	Lines    int      `yaml:"lines,omitempty"`     // Lines of stderr and stdout per task (default: 20)
+	MaxBytes int      `yaml:"max_bytes,omitempty"` // Size limit of each excerpt (default: 4096)
+	Redact   []string `yaml:"redact,omitempty"`    // Regular expressions replaced with [REDACTED], in addition to the built-in ones
+}
+
+// Resources that checks can query
+const (
+	CheckQueryJobs        = "jobs"
+	CheckQueryNodes       = "nodes"
+	CheckQueryAllocations = "allocations"
+	CheckQueryDeployments = "deployments"
+)
+
+// CheckConfig periodically lists a resource from the Nomad API and evaluates a
+// CEL condition against each item
+type CheckConfig struct {
+	Name      string `yaml:"name"`
+	Query     string `yaml:"query"`               // jobs, nodes, allocations or deployments
+	Condition string `yaml:"condition"`           // CEL expression over `item` that is true while the item is failing
+	For       string `yaml:"for,omitempty"`       // How long the condition must hold before the check fails, e.g. "15m"
+	Interval  string `yaml:"interval,omitempty"`  // How often to query (default: 1m)
+	Topic     string `yaml:"topic,omitempty"`     // Topic of the emitted events (default: "Check")
+	Namespace string `yaml:"namespace,omitempty"` // Namespace to query (default: all namespaces)
+}
+
+// DerivedEventsConfig enables trackers that emit synthetic events with the
+// "Derived" topic. Each tracker is disabled unless its section is present.
+type DerivedEventsConfig struct {
+	Allocations *AllocationTrackingConfig `yaml:"allocations,omitempty"`
+	Deployments *DeploymentTrackingConfig `yaml:"deployments,omitempty"`
+	Alerts      []AlertConfig             `yaml:"alerts,omitempty"`
+}
+
+// AlertConfig pairs a problem and a recovery condition into an alert that
+// fires once per key and resolves when the recovery condition matches
+type AlertConfig struct {
+	Name     string `yaml:"name"`
+	Problem  string `yaml:"problem"`           // CEL expression that opens the alert
+	Recovery string `yaml:"recovery"`          // CEL expression that resolves it
+	Key      string `yaml:"key"`               // Template identifying the alert instance, e.g. "{{ .Payload.Node.ID }}"
+	MaxAge   string `yaml:"max_age,omitempty"` // How long the alert stays open without recovering (default: 168h)
+}
+
+// AllocationTrackingConfig controls the allocation lifecycle tracker
+type AllocationTrackingConfig struct {
+	RestartThreshold int    `yaml:"restart_threshold,omitempty"` // Restarts within restart_window that make a loop (default: 3)
+	RestartWindow    string `yaml:"restart_window,omitempty"`    // e.g., "10m" (default: 10m)
+	PendingTimeout   string `yaml:"pending_timeout,omitempty"`   // How long an allocation may stay pending (default: 5m)
+	Retention        string `yaml:"retention,omitempty"`         // How long finished allocations are remembered (default: 1h)
+}
+
+// DeploymentTrackingConfig controls the deployment lifecycle aggregator
+type DeploymentTrackingConfig struct {
+	Progress *bool `yaml:"progress,omitempty"` // Emit DeploymentProgress when allocation health changes (default: true)
+}
+
+// APIConfig enables the HTTP API used to manage silences at runtime
+type APIConfig struct {
+	Address string `yaml:"address"` // e.g., "127.0.0.1:8686"
+}
+
+// SilencesConfig controls where silences are stored
+type SilencesConfig struct {
+	Path      string `yaml:"path,omitempty"`      // JSON file to persist silences (default: silences.json in the working directory)
+	Retention string `yaml:"retention,omitempty"` // How long expired silences are kept (default: 120h)
 }
 
 type NomadConfig struct {
-	Address string     `yaml:"address"`
-	Token   string     `yaml:"token"`
-	TLS     *TLSConfig `yaml:"tls,omitempty"`
+	Address   string          `yaml:"address"`
+	Token     string          `yaml:"token"`
+	Namespace string          `yaml:"namespace,omitempty"` // Namespace of template function lookups (default: "default")
+	UIURL     string          `yaml:"ui_url,omitempty"`    // Base URL of the Nomad UI for nomadUIURL (default: the address)
+	TLS       *TLSConfig      `yaml:"tls,omitempty"`
+	Cache     *APICacheConfig `yaml:"cache,omitempty"`
+}
+
+// APICacheConfig controls the cache of Nomad API lookups made by template functions
+type APICacheConfig struct {
+	TTL     string `yaml:"ttl,omitempty"`     // How long lookups are cached (default: 30s)
+	Timeout string `yaml:"timeout,omitempty"` // Timeout of each lookup (default: 5s)
 }
 
 type TLSConfig struct {
@@ -29,21 +177,87 @@ type TLSConfig struct {
 }
 
 type Output struct {
-	Type       string                 `yaml:"type"`
-	Retry      *RetryConfig           `yaml:"retry,omitempty"`
-	Properties map[string]interface{} `yaml:",inline"`
+	Type          string                 `yaml:"type"`
+	Retry         *RetryConfig           `yaml:"retry,omitempty"`
+	Dedupe        *DedupeConfig          `yaml:"dedupe,omitempty"`
+	GroupBy       []string               `yaml:"group_by,omitempty"`       // Event fields to group on, e.g. ["Topic", "Payload.Job.ID"]
+	GroupWait     string                 `yaml:"group_wait,omitempty"`     // Wait before the first delivery of a new group (default: 30s)
+	GroupInterval string                 `yaml:"group_interval,omitempty"` // Wait between deliveries of an existing group (default: 5m)
+	MaxBatch      int                    `yaml:"max_batch,omitempty"`      // Deliver immediately once a group holds this many events
+	RateLimit     *RateLimitConfig       `yaml:"rate_limit,omitempty"`
+	Properties    map[string]interface{} `yaml:",inline"`
+}
+
+// Grouped reports whether events for this output should be grouped into batches
+func (o Output) Grouped() bool {
+	return o.GroupBy != nil || o.GroupWait != "" || o.GroupInterval != "" || o.MaxBatch > 0
 }
 
 type RetryConfig struct {
-	MaxRetries int    `yaml:"max_retries"`
-	BaseDelay  string `yaml:"base_delay"` // e.g., "1s", "500ms"
+	MaxRetries    int    `yaml:"max_retries"`
+	BaseDelay     string `yaml:"base_delay"`                // e.g., "1s", "500ms"
+	MaxRetryAfter string `yaml:"max_retry_after,omitempty"` // Longest Retry-After to wait for (default: "1m")
+}
+
+// RateLimitConfig is a token bucket allowing Limit deliveries per Per, with bursts up to Burst
+type RateLimitConfig struct {
+	Limit    int    `yaml:"limit"`               // Deliveries allowed per period
+	Per      string `yaml:"per"`                 // e.g., "1m" (default: "1m")
+	Burst    int    `yaml:"burst,omitempty"`     // Bucket size (default: limit)
+	Action   string `yaml:"action,omitempty"`    // drop, queue or collapse (default: drop)
+	MaxQueue int    `yaml:"max_queue,omitempty"` // Queue capacity for the queue action (default: 1000)
+}
+
+// DedupeConfig suppresses events whose rendered key was already seen within the TTL
+type DedupeConfig struct {
+	Key        string `yaml:"key"`                   // Go template, e.g. "{{ .Key }}-{{ .Type }}"
+	TTL        string `yaml:"ttl"`                   // e.g., "5m", "30s"
+	MaxEntries int    `yaml:"max_entries,omitempty"` // LRU capacity (default: 10000)
+	Path       string `yaml:"path,omitempty"`        // Optional file to persist seen keys across restarts
 }
 
 type Route struct {
-	Filter   string  `yaml:"filter"`
-	Output   string  `yaml:"output,omitempty"`   // Optional - parent routes can just filter
-	Continue *bool   `yaml:"continue,omitempty"` // Optional - defaults to true
-	Routes   []Route `yaml:"routes,omitempty"`   // Child routes
+	Filter              string               `yaml:"filter"`
+	Output              string               `yaml:"output,omitempty"`                // Optional - parent routes can just filter
+	Continue            *bool                `yaml:"continue,omitempty"`              // Optional - defaults to true
+	Dedupe              *DedupeConfig        `yaml:"dedupe,omitempty"`                // Optional - suppress duplicates matched by this route
+	ActiveTimeIntervals []string             `yaml:"active_time_intervals,omitempty"` // Optional - only match during these intervals
+	MuteTimeIntervals   []string             `yaml:"mute_time_intervals,omitempty"`   // Optional - never match during these intervals
+	TimeSource          string               `yaml:"time_source,omitempty"`           // "wall_clock" (default) or "event"
+	FlapDetection       *FlapDetectionConfig `yaml:"flap_detection,omitempty"`        // Optional - suppress events for keys that change state too often
+	Routes              []Route              `yaml:"routes,omitempty"`                // Child routes
+}
+
+// FlapDetectionConfig detects keys whose state changes too often. While a key
+// is flapping its events are replaced by FlappingStarted/FlappingEnded events.
+type FlapDetectionConfig struct {
+	Key       string `yaml:"key"`                  // Template identifying what flaps, e.g. "{{ .Payload.Node.ID }}"
+	State     string `yaml:"state"`                // Template rendering the state, e.g. "{{ .Payload.Node.Status }}"
+	Threshold int    `yaml:"threshold"`            // State transitions within the window that start flapping
+	Window    string `yaml:"window"`               // Sliding window for counting transitions, e.g. "10m"
+	StableFor string `yaml:"stable_for,omitempty"` // Time without transitions that ends flapping (default: window)
+}
+
+// Time sources for evaluating route time intervals
+const (
+	TimeSourceWallClock = "wall_clock"
+	TimeSourceEvent     = "event"
+)
+
+// TimeInterval matches times that satisfy every field it sets. Ranges are
+// written "start:end" and are inclusive.
+type TimeInterval struct {
+	Weekdays    []string    `yaml:"weekdays,omitempty"`      // e.g., ["monday:friday", "sunday"]
+	Times       []TimeRange `yaml:"times,omitempty"`         // Times of day
+	DaysOfMonth []string    `yaml:"days_of_month,omitempty"` // e.g., ["1:7", "-1"] (negative counts from month end)
+	Months      []string    `yaml:"months,omitempty"`        // e.g., ["december", "1:3"]
+	Location    string      `yaml:"location,omitempty"`      // IANA time zone (default: UTC)
+}
+
+// TimeRange is a time of day range in 24-hour "HH:MM" format; the end is exclusive
+type TimeRange struct {
+	StartTime string `yaml:"start_time"`
+	EndTime   string `yaml:"end_time"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -74,6 +288,78 @@ func (c *Config) validate() error {
 		return err
 	}
 
+	if c.Nomad.UIURL != "" {
+		if u, err := url.Parse(c.Nomad.UIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("nomad.ui_url: invalid URL %q - use the Nomad UI's base URL (e.g., \"https://nomad.example.com\")", c.Nomad.UIURL)
+		}
+	}
+
+	if cache := c.Nomad.Cache; cache != nil {
+		for field, value := range map[string]string{"ttl": cache.TTL, "timeout": cache.Timeout} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("nomad.cache.%s: invalid duration %q - use a positive duration like \"30s\"", field, value)
+			}
+		}
+	}
+
+	if c.API != nil && c.API.Address == "" {
+		return fmt.Errorf("api.address is required when the api section is present (e.g., \"127.0.0.1:8686\")")
+	}
+
+	if c.Silences != nil && c.Silences.Retention != "" {
+		if d, err := time.ParseDuration(c.Silences.Retention); err != nil || d <= 0 {
+			return fmt.Errorf("silences.retention: invalid duration %q - use a positive duration like \"120h\"", c.Silences.Retention)
+		}
+	}
+
+	if err := validateDerivedEvents(c.DerivedEvents); err != nil {
+		return err
+	}
+
+	if err := validateChecks(c.Checks); err != nil {
+		return err
+	}
+
+	if e := c.Enrichment; e != nil {
+		for field, value := range map[string]string{"cache_ttl": e.CacheTTL, "timeout": e.Timeout} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("enrichment.%s: invalid duration %q - use a positive duration like \"30s\"", field, value)
+			}
+		}
+
+		if logs := e.Logs; logs != nil {
+			if logs.Lines < 0 || logs.MaxBytes < 0 {
+				return fmt.Errorf("enrichment.logs: lines and max_bytes cannot be negative")
+			}
+			for i, pattern := range logs.Redact {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("enrichment.logs.redact[%d]: invalid regular expression: %w", i, err)
+				}
+			}
+		}
+	}
+
+	for name := range c.Templates {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("templates: template names cannot be empty")
+		}
+	}
+
+	if c.Interactions != nil {
+		if err := validateInteractions(c.Interactions); err != nil {
+			return err
+		}
+		if c.API != nil && c.API.Address == c.Interactions.Listen {
+			return fmt.Errorf("interactions.listen must differ from api.address - the management API must not be reachable by Slack")
+		}
+	}
+
 	if len(c.Outputs) == 0 {
 		return fmt.Errorf("at least one output must be defined - add an output configuration under the 'outputs' section")
 	}
@@ -82,6 +368,15 @@ func (c *Config) validate() error {
 		if output.Type == "" {
 			return fmt.Errorf("output %q: type is required - specify one of the supported output types", name)
 		}
+		if err := validateDedupe(output.Dedupe); err != nil {
+			return fmt.Errorf("output %q: %w", name, err)
+		}
+		if err := validateGrouping(output); err != nil {
+			return fmt.Errorf("output %q: %w", name, err)
+		}
+		if err := validateRateLimit(output.RateLimit); err != nil {
+			return fmt.Errorf("output %q: %w", name, err)
+		}
 	}
 
 	if len(c.Routes) == 0 {
@@ -97,6 +392,62 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validateTimeIntervals checks that a route's time intervals exist and its time source is known
+func (c *Config) validateTimeIntervals(route Route) error {
+	for _, field := range []struct {
+		name  string
+		names []string
+	}{
+		{"active_time_intervals", route.ActiveTimeIntervals},
+		{"mute_time_intervals", route.MuteTimeIntervals},
+	} {
+		for _, name := range field.names {
+			if _, exists := c.TimeIntervals[name]; !exists {
+				return fmt.Errorf("%s: time interval %q does not exist - define it under the 'time_intervals' section", field.name, name)
+			}
+		}
+	}
+
+	switch route.TimeSource {
+	case "", TimeSourceWallClock, TimeSourceEvent:
+		return nil
+	default:
+		return fmt.Errorf("time_source: invalid value %q - use \"wall_clock\" or \"event\"", route.TimeSource)
+	}
+}
+
+// validateFlapDetection validates a route's flap_detection settings
+func validateFlapDetection(route Route) error {
+	flap := route.FlapDetection
+	if flap == nil {
+		return nil
+	}
+
+	if route.Output == "" {
+		return fmt.Errorf("flap_detection requires the route to have an output")
+	}
+	if flap.Key == "" {
+		return fmt.Errorf("flap_detection.key is required - specify a key template such as \"{{ .Payload.Node.ID }}\"")
+	}
+	if flap.State == "" {
+		return fmt.Errorf("flap_detection.state is required - specify a state template such as \"{{ .Payload.Node.Status }}\"")
+	}
+	if flap.Threshold < 2 {
+		return fmt.Errorf("flap_detection.threshold must be at least 2")
+	}
+
+	for field, value := range map[string]string{"window": flap.Window, "stable_for": flap.StableFor} {
+		if value == "" && field == "stable_for" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("flap_detection.%s: invalid duration %q - use a positive duration like \"10m\"", field, value)
+		}
+	}
+
+	return nil
+}
+
 // validateRoute recursively validates a route and its children
 func (c *Config) validateRoute(route Route, path string) error {
 	// Route must have either an output or child routes (or both)
@@ -123,6 +474,18 @@ func (c *Config) validateRoute(route Route, path string) error {
 		}
 	}
 
+	if err := validateDedupe(route.Dedupe); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := c.validateTimeIntervals(route); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := validateFlapDetection(route); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
 	// Recursively validate child routes
 	for i, childRoute := range route.Routes {
 		childPath := fmt.Sprintf("%s.routes[%d]", path, i)
@@ -134,6 +497,170 @@ func (c *Config) validateRoute(route Route, path string) error {
 	return nil
 }
 
+// validateDedupe validates an optional dedupe block
+func validateDedupe(dedupe *DedupeConfig) error {
+	if dedupe == nil {
+		return nil
+	}
+
+	if dedupe.Key == "" {
+		return fmt.Errorf("dedupe.key is required - specify a key template such as \"{{ .Key }}-{{ .Type }}\"")
+	}
+
+	ttl, err := time.ParseDuration(dedupe.TTL)
+	if err != nil {
+		return fmt.Errorf("dedupe.ttl: invalid duration %q - use a duration like \"30s\", \"5m\"", dedupe.TTL)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("dedupe.ttl must be greater than zero")
+	}
+
+	if dedupe.MaxEntries < 0 {
+		return fmt.Errorf("dedupe.max_entries cannot be negative")
+	}
+
+	return nil
+}
+
+// validateDerivedEvents validates the settings of the derived event trackers
+func validateDerivedEvents(derived *DerivedEventsConfig) error {
+	if derived == nil {
+		return nil
+	}
+
+	if allocs := derived.Allocations; allocs != nil {
+		if allocs.RestartThreshold < 0 {
+			return fmt.Errorf("derived_events.allocations.restart_threshold cannot be negative")
+		}
+		for field, value := range map[string]string{
+			"restart_window":  allocs.RestartWindow,
+			"pending_timeout": allocs.PendingTimeout,
+			"retention":       allocs.Retention,
+		} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("derived_events.allocations.%s: invalid duration %q - use a positive duration like \"10m\"", field, value)
+			}
+		}
+	}
+
+	names := make(map[string]bool, len(derived.Alerts))
+	for i, alert := range derived.Alerts {
+		if alert.Name == "" {
+			return fmt.Errorf("derived_events.alerts[%d]: name is required", i)
+		}
+		if names[alert.Name] {
+			return fmt.Errorf("derived_events.alerts[%d]: duplicate alert name %q", i, alert.Name)
+		}
+		names[alert.Name] = true
+
+		if alert.Problem == "" || alert.Recovery == "" {
+			return fmt.Errorf("alert %q: problem and recovery expressions are required", alert.Name)
+		}
+		if alert.Key == "" {
+			return fmt.Errorf("alert %q: key is required - specify a key template such as \"{{ .Payload.Node.ID }}\"", alert.Name)
+		}
+		if alert.MaxAge != "" {
+			if d, err := time.ParseDuration(alert.MaxAge); err != nil || d <= 0 {
+				return fmt.Errorf("alert %q: invalid max_age %q - use a positive duration like \"24h\"", alert.Name, alert.MaxAge)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateChecks validates the periodic API checks
+func validateChecks(checks []CheckConfig) error {
+	names := make(map[string]bool, len(checks))
+	for i, check := range checks {
+		if check.Name == "" {
+			return fmt.Errorf("checks[%d]: name is required", i)
+		}
+		if names[check.Name] {
+			return fmt.Errorf("checks[%d]: duplicate check name %q", i, check.Name)
+		}
+		names[check.Name] = true
+
+		switch check.Query {
+		case CheckQueryJobs, CheckQueryNodes, CheckQueryAllocations, CheckQueryDeployments:
+		default:
+			return fmt.Errorf("check %q: invalid query %q - must be one of %q, %q, %q or %q", check.Name, check.Query,
+				CheckQueryJobs, CheckQueryNodes, CheckQueryAllocations, CheckQueryDeployments)
+		}
+
+		if check.Condition == "" {
+			return fmt.Errorf("check %q: condition is required", check.Name)
+		}
+
+		for field, value := range map[string]string{"interval": check.Interval, "for": check.For} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("check %q: %s: invalid duration %q - use a positive duration like \"1m\"", check.Name, field, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateGrouping validates the group_* and max_batch settings of an output
+func validateGrouping(output Output) error {
+	for field, value := range map[string]string{"group_wait": output.GroupWait, "group_interval": output.GroupInterval} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("%s: invalid duration %q - use a positive duration like \"30s\", \"5m\"", field, value)
+		}
+	}
+
+	if output.MaxBatch < 0 {
+		return fmt.Errorf("max_batch cannot be negative")
+	}
+
+	for _, field := range output.GroupBy {
+		if field == "" {
+			return fmt.Errorf("group_by entries cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+// validateRateLimit validates an optional rate_limit block
+func validateRateLimit(rateLimit *RateLimitConfig) error {
+	if rateLimit == nil {
+		return nil
+	}
+
+	if rateLimit.Limit <= 0 {
+		return fmt.Errorf("rate_limit.limit must be greater than zero")
+	}
+
+	if rateLimit.Per != "" {
+		if d, err := time.ParseDuration(rateLimit.Per); err != nil || d <= 0 {
+			return fmt.Errorf("rate_limit.per: invalid duration %q - use a positive duration like \"1s\", \"1m\"", rateLimit.Per)
+		}
+	}
+
+	if rateLimit.Burst < 0 || rateLimit.MaxQueue < 0 {
+		return fmt.Errorf("rate_limit.burst and rate_limit.max_queue cannot be negative")
+	}
+
+	switch rateLimit.Action {
+	case "", "drop", "queue", "collapse":
+	default:
+		return fmt.Errorf("rate_limit.action: invalid action %q - must be one of drop, queue, collapse", rateLimit.Action)
+	}
+
+	return nil
+}
+
 // validateTLS validates TLS configuration
 func (c *Config) validateTLS() error {
 	if c.Nomad.TLS == nil || !c.Nomad.TLS.Enabled {
@@ -174,3 +701,55 @@ func (c *Config) validateTLS() error {
 
 	return nil
 }
+
+// slackUserID matches Slack user IDs, such as U024BE7LH or W012A3CDE for
+// Enterprise Grid users
+var slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+func validateInteractions(i *InteractionsConfig) error {
+	if i.Listen == "" {
+		return fmt.Errorf("interactions.listen is required - the address Slack posts actions to, e.g. \"0.0.0.0:8687\"")
+	}
+	if i.SigningSecret == "" {
+		return fmt.Errorf("interactions.signing_secret is required - use the signing secret from the Slack app's Basic Information page")
+	}
+	if len(i.Actions) == 0 {
+		return fmt.Errorf("interactions.actions: at least one action is required")
+	}
+
+	seen := make(map[string]bool)
+	for idx, action := range i.Actions {
+		if action.ActionID == "" {
+			return fmt.Errorf("interactions.actions[%d]: action_id is required", idx)
+		}
+		if seen[action.ActionID] {
+			return fmt.Errorf("interactions.actions[%d]: duplicate action_id %q", idx, action.ActionID)
+		}
+		seen[action.ActionID] = true
+
+		switch action.Operation {
+		case OperationRestartAlloc, OperationStopJob, OperationRevertJob, OperationPromoteDeployment, OperationFailDeployment, OperationSilence:
+		default:
+			return fmt.Errorf("interactions.actions[%d]: invalid operation %q - use restart_alloc, stop_job, revert_job, promote_deployment, fail_deployment or silence", idx, action.Operation)
+		}
+
+		if len(action.Users) == 0 {
+			return fmt.Errorf("interactions.actions[%d]: users is required - list the Slack user IDs allowed to run it", idx)
+		}
+		for _, user := range action.Users {
+			if !slackUserID.MatchString(user) {
+				return fmt.Errorf("interactions.actions[%d].users: %q is not a Slack user ID - use IDs such as U024BE7LH, which cannot be changed like usernames", idx, user)
+			}
+		}
+
+		if action.Duration != "" {
+			if action.Operation != OperationSilence {
+				return fmt.Errorf("interactions.actions[%d]: duration only applies to the silence operation", idx)
+			}
+			if d, err := time.ParseDuration(action.Duration); err != nil || d <= 0 {
+				return fmt.Errorf("interactions.actions[%d].duration: invalid duration %q - use a positive duration like \"1h\"", idx, action.Duration)
+			}
+		}
+	}
+	return nil
+}
@@ -106,6 +106,242 @@ routes:
 			expectError: true,
 			errorMsg:    "route must have either an output or child routes",
 		},
+		{
+			name: "empty template name",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+templates:
+  "": "{{ .Topic }}"
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    "templates: template names cannot be empty",
+		},
+		{
+			name: "valid interactions",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+api:
+  address: "127.0.0.1:8686"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  signing_secret: "8f742231b10e8888abcd99yyyzzz85a5"
+  team_id: T0001
+  actions:
+    - action_id: restart_alloc
+      operation: restart_alloc
+      users: ["U024BE7LH"]
+    - action_id: silence_job
+      operation: silence
+      users: ["U024BE7LH", "W012A3CDE"]
+      duration: 2h
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: false,
+		},
+		{
+			name: "interactions without listen address",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+interactions:
+  signing_secret: "secret"
+  actions:
+    - action_id: restart_alloc
+      operation: restart_alloc
+      users: ["U024BE7LH"]
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    "interactions.listen is required",
+		},
+		{
+			name: "interactions on the api address",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+api:
+  address: "0.0.0.0:8686"
+
+interactions:
+  listen: "0.0.0.0:8686"
+  signing_secret: "secret"
+  actions:
+    - action_id: restart_alloc
+      operation: restart_alloc
+      users: ["U024BE7LH"]
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    "interactions.listen must differ from api.address",
+		},
+		{
+			name: "interactions action without users",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  signing_secret: "secret"
+  actions:
+    - action_id: restart_alloc
+      operation: restart_alloc
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    "interactions.actions[0]: users is required",
+		},
+		{
+			name: "interactions users by name",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  signing_secret: "secret"
+  actions:
+    - action_id: restart_alloc
+      operation: restart_alloc
+      users: ["U024BE7LH", "alice"]
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    `interactions.actions[0].users: "alice" is not a Slack user ID`,
+		},
+		{
+			name: "interactions with unknown operation",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+api:
+  address: "127.0.0.1:8686"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  signing_secret: "secret"
+  actions:
+    - action_id: drain
+      operation: drain_node
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    `interactions.actions[0]: invalid operation "drain_node"`,
+		},
+		{
+			name: "interactions with duplicate action_id",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+api:
+  address: "127.0.0.1:8686"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  signing_secret: "secret"
+  actions:
+    - action_id: stop
+      operation: stop_job
+      users: ["U024BE7LH"]
+    - action_id: stop
+      operation: fail_deployment
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    `interactions.actions[1]: duplicate action_id "stop"`,
+		},
+		{
+			name: "interactions without signing secret",
+			configYAML: `
+nomad:
+  address: "http://localhost:4646"
+
+api:
+  address: "127.0.0.1:8686"
+
+interactions:
+  listen: "0.0.0.0:8687"
+  actions:
+    - action_id: stop
+      operation: stop_job
+
+outputs:
+  test_stdout:
+    type: stdout
+
+routes:
+  - filter: ""
+    output: test_stdout
+`,
+			expectError: true,
+			errorMsg:    "interactions.signing_secret is required",
+		},
 	}
 
 	for _, tt := range tests {
@@ -185,6 +421,333 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expected: "type is required",
 		},
+		{
+			name: "valid dedupe on output and route",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", Dedupe: &DedupeConfig{Key: "{{ .Key }}", TTL: "5m"}},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test", Dedupe: &DedupeConfig{Key: "{{ .Key }}", TTL: "1m", MaxEntries: 100}},
+				},
+			},
+			expected: "",
+		},
+		{
+			name: "output with invalid group_wait",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", GroupBy: []string{"Topic"}, GroupWait: "-1s"},
+				},
+			},
+			expected: "group_wait: invalid duration",
+		},
+		{
+			name: "output with negative max_batch",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", MaxBatch: -1},
+				},
+			},
+			expected: "max_batch cannot be negative",
+		},
+		{
+			name: "rate limit without limit",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", RateLimit: &RateLimitConfig{Per: "1m"}},
+				},
+			},
+			expected: "rate_limit.limit must be greater than zero",
+		},
+		{
+			name: "rate limit with unknown action",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", RateLimit: &RateLimitConfig{Limit: 10, Action: "defer"}},
+				},
+			},
+			expected: "rate_limit.action: invalid action \"defer\"",
+		},
+		{
+			name: "dedupe without key",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout", Dedupe: &DedupeConfig{TTL: "5m"}},
+				},
+			},
+			expected: "dedupe.key is required",
+		},
+		{
+			name: "route dedupe with invalid ttl",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test", Dedupe: &DedupeConfig{Key: "{{ .Key }}", TTL: "forever"}},
+				},
+			},
+			expected: "route 0: dedupe.ttl: invalid duration",
+		},
+		{
+			name: "valid api and silences",
+			config: Config{
+				Nomad:    NomadConfig{Address: "http://localhost:4646"},
+				API:      &APIConfig{Address: "127.0.0.1:8686"},
+				Silences: &SilencesConfig{Path: "/var/lib/nomad-events/silences.json", Retention: "72h"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test"},
+				},
+			},
+			expected: "",
+		},
+		{
+			name: "api without address",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				API:   &APIConfig{},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "api.address is required",
+		},
+		{
+			name: "silences with invalid retention",
+			config: Config{
+				Nomad:    NomadConfig{Address: "http://localhost:4646"},
+				Silences: &SilencesConfig{Retention: "-1h"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "silences.retention: invalid duration",
+		},
+		{
+			name: "route with valid time intervals",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				TimeIntervals: map[string][]TimeInterval{
+					"business_hours": {{Weekdays: []string{"monday:friday"}}},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test", ActiveTimeIntervals: []string{"business_hours"}, TimeSource: TimeSourceEvent},
+				},
+			},
+			expected: "",
+		},
+		{
+			name: "route referencing undefined time interval",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test", MuteTimeIntervals: []string{"weekends"}},
+				},
+			},
+			expected: "route 0: mute_time_intervals: time interval \"weekends\" does not exist",
+		},
+		{
+			name: "route with invalid time source",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{Filter: "", Output: "test", TimeSource: "server"},
+				},
+			},
+			expected: "time_source: invalid value \"server\"",
+		},
+		{
+			name: "allocation tracking with invalid window",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				DerivedEvents: &DerivedEventsConfig{
+					Allocations: &AllocationTrackingConfig{RestartThreshold: 5, RestartWindow: "0s"},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "derived_events.allocations.restart_window: invalid duration",
+		},
+		{
+			name: "flap detection on route without output",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{
+						Filter:        "",
+						FlapDetection: &FlapDetectionConfig{Key: "{{ .Key }}", State: "{{ .Type }}", Threshold: 3, Window: "10m"},
+						Routes:        []Route{{Filter: "", Output: "test"}},
+					},
+				},
+			},
+			expected: "route 0: flap_detection requires the route to have an output",
+		},
+		{
+			name: "flap detection with low threshold",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{
+						Filter:        "",
+						Output:        "test",
+						FlapDetection: &FlapDetectionConfig{Key: "{{ .Key }}", State: "{{ .Type }}", Threshold: 1, Window: "10m"},
+					},
+				},
+			},
+			expected: "flap_detection.threshold must be at least 2",
+		},
+		{
+			name: "alerts with duplicate names",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				DerivedEvents: &DerivedEventsConfig{
+					Alerts: []AlertConfig{
+						{Name: "node_down", Problem: "true", Recovery: "false", Key: "{{ .Key }}"},
+						{Name: "node_down", Problem: "true", Recovery: "false", Key: "{{ .Key }}"},
+					},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "derived_events.alerts[1]: duplicate alert name \"node_down\"",
+		},
+		{
+			name: "alert without recovery",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				DerivedEvents: &DerivedEventsConfig{
+					Alerts: []AlertConfig{{Name: "job_dead", Problem: "true", Key: "{{ .Key }}"}},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "alert \"job_dead\": problem and recovery expressions are required",
+		},
+		{
+			name: "alert with invalid max_age",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				DerivedEvents: &DerivedEventsConfig{
+					Alerts: []AlertConfig{{Name: "job_dead", Problem: "true", Recovery: "false", Key: "{{ .Key }}", MaxAge: "forever"}},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "alert \"job_dead\": invalid max_age \"forever\"",
+		},
+		{
+			name: "valid checks",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Checks: []CheckConfig{
+					{Name: "node_ineligible", Query: "nodes", Condition: "item.SchedulingEligibility == 'ineligible'", For: "24h", Interval: "5m"},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+				Routes: []Route{
+					{Filter: "true", Output: "test"},
+				},
+			},
+		},
+		{
+			name: "check with unknown query",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Checks: []CheckConfig{
+					{Name: "volumes", Query: "volumes", Condition: "true"},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "check \"volumes\": invalid query \"volumes\"",
+		},
+		{
+			name: "check with invalid interval",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646"},
+				Checks: []CheckConfig{
+					{Name: "job_dead", Query: "jobs", Condition: "true", Interval: "often"},
+				},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "check \"job_dead\": interval: invalid duration \"often\"",
+		},
+		{
+			name: "enrichment with invalid timeout",
+			config: Config{
+				Nomad:      NomadConfig{Address: "http://localhost:4646"},
+				Enrichment: &EnrichmentConfig{Job: true, Timeout: "-1s"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "enrichment.timeout: invalid duration \"-1s\"",
+		},
+		{
+			name: "enrichment with invalid log redaction",
+			config: Config{
+				Nomad:      NomadConfig{Address: "http://localhost:4646"},
+				Enrichment: &EnrichmentConfig{Logs: &LogExcerptConfig{Redact: []string{"secret=("}}},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "enrichment.logs.redact[0]: invalid regular expression",
+		},
+		{
+			name: "nomad cache with invalid ttl",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646", Cache: &APICacheConfig{TTL: "forever"}},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "nomad.cache.ttl: invalid duration \"forever\"",
+		},
+		{
+			name: "nomad ui_url without scheme",
+			config: Config{
+				Nomad: NomadConfig{Address: "http://localhost:4646", UIURL: "nomad.example.com"},
+				Outputs: map[string]Output{
+					"test": {Type: "stdout"},
+				},
+			},
+			expected: "nomad.ui_url: invalid URL \"nomad.example.com\"",
+		},
 	}
 
 	for _, tt := range tests {
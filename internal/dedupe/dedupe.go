@@ -0,0 +1,100 @@
+package dedupe
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+)
+
+const defaultMaxEntries = 10000
+
+// Deduplicator suppresses events whose rendered key was already seen within a TTL window
+type Deduplicator struct {
+	keyTemplate    string
+	store          Store
+	templateEngine *template.Engine
+	now            func() time.Time
+}
+
+// New creates a Deduplicator from a dedupe configuration block
+func New(cfg config.DedupeConfig) (*Deduplicator, error) {
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("dedupe key template is required")
+	}
+
+	templateEngine := template.NewEngine()
+	if err := templateEngine.Compile(cfg.Key); err != nil {
+		return nil, fmt.Errorf("invalid dedupe key template: %w", err)
+	}
+
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dedupe ttl %q: %w", cfg.TTL, err)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("dedupe ttl must be greater than zero")
+	}
+
+	maxEntries := cfg.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	var store Store
+	if cfg.Path != "" {
+		store, err = NewFileStore(cfg.Path, ttl, maxEntries)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		store = NewMemoryStore(ttl, maxEntries)
+	}
+
+	d := NewWithStore(cfg.Key, store)
+	d.templateEngine = templateEngine
+	return d, nil
+}
+
+// NewWithStore creates a Deduplicator backed by the given store
+func NewWithStore(keyTemplate string, store Store) *Deduplicator {
+	return &Deduplicator{
+		keyTemplate:    keyTemplate,
+		store:          store,
+		templateEngine: template.NewEngine(),
+		now:            time.Now,
+	}
+}
+
+// IsDuplicate reports whether an event with the same key was seen within the window.
+// Events whose key cannot be rendered are never treated as duplicates.
+func (d *Deduplicator) IsDuplicate(event nomad.Event) bool {
+	key, err := d.templateEngine.ProcessText(d.keyTemplate, event)
+	if err != nil {
+		slog.Warn("Failed to render dedupe key, delivering event",
+			"error", err,
+			"topic", event.Topic,
+			"type", event.Type)
+		return false
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return false
+	}
+
+	return d.store.CheckAndMark(key, d.now())
+}
+
+// Close writes keys held by a persisted store
+func (d *Deduplicator) Close() error {
+	if closer, ok := d.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allocEvent(allocID, clientStatus string) nomad.Event {
+	return nomad.Event{
+		Topic: "Allocation",
+		Type:  "AllocationUpdated",
+		Key:   allocID,
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":           allocID,
+				"ClientStatus": clientStatus,
+			},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.DedupeConfig
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name: "memory store",
+			cfg:  config.DedupeConfig{Key: "{{ .Key }}", TTL: "5m"},
+		},
+		{
+			name: "file store",
+			cfg:  config.DedupeConfig{Key: "{{ .Key }}", TTL: "5m", Path: t.TempDir() + "/dedupe.json"},
+		},
+		{
+			name:        "missing key",
+			cfg:         config.DedupeConfig{TTL: "5m"},
+			expectError: true,
+			errorMsg:    "key template is required",
+		},
+		{
+			name:        "invalid key template",
+			cfg:         config.DedupeConfig{Key: "{{ .Key", TTL: "5m"},
+			expectError: true,
+			errorMsg:    "invalid dedupe key template",
+		},
+		{
+			name:        "invalid ttl",
+			cfg:         config.DedupeConfig{Key: "{{ .Key }}", TTL: "soon"},
+			expectError: true,
+			errorMsg:    "invalid dedupe ttl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deduplicator, err := New(tt.cfg)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, deduplicator)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, deduplicator)
+			}
+		})
+	}
+}
+
+func TestDeduplicatorIsDuplicate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	deduplicator, err := New(config.DedupeConfig{
+		Key: "{{ .Key }}-{{ .Payload.Allocation.ClientStatus }}",
+		TTL: "1m",
+	})
+	require.NoError(t, err)
+	deduplicator.now = func() time.Time { return now }
+
+	assert.False(t, deduplicator.IsDuplicate(allocEvent("alloc-1", "pending")))
+	assert.True(t, deduplicator.IsDuplicate(allocEvent("alloc-1", "pending")))
+	assert.False(t, deduplicator.IsDuplicate(allocEvent("alloc-1", "running")), "status change is a new key")
+	assert.False(t, deduplicator.IsDuplicate(allocEvent("alloc-2", "pending")))
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, deduplicator.IsDuplicate(allocEvent("alloc-1", "pending")), "window has expired")
+}
+
+func TestDeduplicatorEmptyKey(t *testing.T) {
+	deduplicator, err := New(config.DedupeConfig{Key: "{{ .Namespace }}", TTL: "1m"})
+	require.NoError(t, err)
+
+	event := nomad.Event{Topic: "Node", Type: "NodeRegistration"}
+	assert.False(t, deduplicator.IsDuplicate(event))
+	assert.False(t, deduplicator.IsDuplicate(event), "events without a key are never suppressed")
+}
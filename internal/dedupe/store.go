@@ -0,0 +1,257 @@
+package dedupe
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"nomad-events/internal/fsutil"
+)
+
+// Store records dedupe keys and reports whether they were seen recently
+type Store interface {
+	// CheckAndMark returns true if key was marked within the TTL, otherwise it marks the key and returns false
+	CheckAndMark(key string, now time.Time) bool
+}
+
+type entry struct {
+	key     string
+	expires time.Time
+}
+
+// MemoryStore is an in-memory LRU store with per-key expiry
+type MemoryStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List // front = most recently marked
+	entries    map[string]*list.Element
+}
+
+// NewMemoryStore creates an LRU store holding at most maxEntries keys for ttl each
+func NewMemoryStore(ttl time.Duration, maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryStore) CheckAndMark(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.entries[key]; exists {
+		if now.Before(elem.Value.(*entry).expires) {
+			return true
+		}
+		s.remove(elem)
+	}
+
+	s.add(key, now.Add(s.ttl))
+	return false
+}
+
+// add inserts a key, evicting the least recently marked keys when over capacity
+func (s *MemoryStore) add(key string, expires time.Time) {
+	s.entries[key] = s.order.PushFront(&entry{key: key, expires: expires})
+
+	for s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *MemoryStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*entry).key)
+}
+
+// snapshot returns the unexpired entries and their expiry times
+func (s *MemoryStore) snapshot(now time.Time) map[string]time.Time {
+	result := make(map[string]time.Time, len(s.entries))
+	for elem := s.order.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		if now.Before(e.expires) {
+			result[e.key] = e.expires
+		}
+	}
+	return result
+}
+
+// Len returns the number of keys currently held, including expired ones not yet evicted
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// saveDelay is how long a FileStore waits after a new key before writing, so
+// a burst of keys is persisted with one write
+const saveDelay = time.Second
+
+// FileStore is a MemoryStore that persists its keys to a JSON file so
+// suppression survives restarts and configuration reloads. Keys are marked in
+// memory and written shortly after, and when the store is closed. A closed
+// store no longer writes, so a router still draining after a reload cannot
+// overwrite the file of the store that replaced it.
+type FileStore struct {
+	*MemoryStore
+	path      string
+	saveDelay time.Duration
+	saveMu    sync.Mutex // held while writing, see write
+
+	timerMu sync.Mutex
+	timer   *time.Timer // pending save, nil if none
+	closed  bool        // set when the last owner closes the store
+	refs    int         // owners sharing the store, see NewFileStore
+}
+
+var (
+	fileStoresMu sync.Mutex
+	fileStores   = make(map[string]*FileStore)
+)
+
+// NewFileStore creates a persisted store, loading any unexpired keys from path.
+// Stores are shared by path until every owner has closed them, so a store
+// opened by a new configuration sees keys the previous one has not written yet.
+func NewFileStore(path string, ttl time.Duration, maxEntries int) (*FileStore, error) {
+	fileStoresMu.Lock()
+	defer fileStoresMu.Unlock()
+
+	if store, exists := fileStores[path]; exists {
+		store.mu.Lock()
+		store.ttl = ttl
+		store.maxEntries = maxEntries
+		store.mu.Unlock()
+		store.refs++
+		return store, nil
+	}
+
+	store := &FileStore{
+		MemoryStore: NewMemoryStore(ttl, maxEntries),
+		path:        path,
+		saveDelay:   saveDelay,
+		refs:        1,
+	}
+
+	if err := store.load(time.Now()); err != nil {
+		return nil, err
+	}
+
+	fileStores[path] = store
+	return store, nil
+}
+
+func (s *FileStore) CheckAndMark(key string, now time.Time) bool {
+	if s.MemoryStore.CheckAndMark(key, now) {
+		return true
+	}
+
+	s.timerMu.Lock()
+	if s.timer == nil && !s.closed {
+		s.timer = time.AfterFunc(s.saveDelay, s.flush)
+	}
+	s.timerMu.Unlock()
+	return false
+}
+
+// Close writes pending keys once the last owner of the store closes it
+func (s *FileStore) Close() error {
+	fileStoresMu.Lock()
+	s.refs--
+	last := s.refs <= 0
+	if last && fileStores[s.path] == s {
+		delete(fileStores, s.path)
+	}
+	fileStoresMu.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	// A save that already fired but has not written yet sees the store is
+	// closed and leaves the final write to Close
+	s.timerMu.Lock()
+	pending := s.timer != nil
+	if pending {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.closed = true
+	s.timerMu.Unlock()
+
+	// Wait for a write in progress, so none lands after Close returns
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if !pending {
+		return nil
+	}
+	return s.write(time.Now())
+}
+
+// flush writes the keys marked since the last write, unless the store was
+// closed meanwhile. A failed write only loses persistence; the in-memory state
+// is still correct.
+func (s *FileStore) flush() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.timerMu.Lock()
+	closed := s.closed
+	s.timer = nil
+	s.timerMu.Unlock()
+	if closed {
+		return
+	}
+
+	if err := s.write(time.Now()); err != nil {
+		slog.Warn("Failed to persist dedupe store", "path", s.path, "error", err)
+	}
+}
+
+func (s *FileStore) load(now time.Time) error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read dedupe store %s: %w", s.path, err)
+	}
+
+	var stored map[string]time.Time
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse dedupe store %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, expires := range stored {
+		if now.Before(expires) {
+			s.add(key, expires)
+		}
+	}
+
+	return nil
+}
+
+// write replaces the file with the unexpired keys. Callers must hold s.saveMu,
+// which serializes writes so the newest snapshot always lands last.
+func (s *FileStore) write(now time.Time) error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.snapshot(now))
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal dedupe store: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write dedupe store %s: %w", s.path, err)
+	}
+	return nil
+}
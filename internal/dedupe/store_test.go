@@ -0,0 +1,146 @@
+package dedupe
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("marks and detects keys within ttl", func(t *testing.T) {
+		store := NewMemoryStore(time.Minute, 10)
+
+		assert.False(t, store.CheckAndMark("a", now))
+		assert.True(t, store.CheckAndMark("a", now.Add(30*time.Second)))
+		assert.False(t, store.CheckAndMark("b", now))
+	})
+
+	t.Run("expired keys are marked again", func(t *testing.T) {
+		store := NewMemoryStore(time.Minute, 10)
+
+		assert.False(t, store.CheckAndMark("a", now))
+		assert.False(t, store.CheckAndMark("a", now.Add(2*time.Minute)))
+		assert.True(t, store.CheckAndMark("a", now.Add(2*time.Minute+time.Second)))
+	})
+
+	t.Run("evicts least recently marked keys", func(t *testing.T) {
+		store := NewMemoryStore(time.Hour, 2)
+
+		store.CheckAndMark("a", now)
+		store.CheckAndMark("b", now)
+		store.CheckAndMark("c", now)
+
+		assert.Equal(t, 2, store.Len())
+		assert.False(t, store.CheckAndMark("a", now), "a should have been evicted")
+		assert.True(t, store.CheckAndMark("c", now))
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe.json")
+	now := time.Now()
+
+	store, err := NewFileStore(path, time.Hour, 10)
+	require.NoError(t, err)
+	assert.False(t, store.CheckAndMark("alloc-1", now))
+	assert.NoFileExists(t, path, "keys are written after a delay, not on every event")
+	require.NoError(t, store.Close())
+	assert.FileExists(t, path)
+
+	t.Run("keys survive reopening the store", func(t *testing.T) {
+		reopened, err := NewFileStore(path, time.Hour, 10)
+		require.NoError(t, err)
+		defer reopened.Close()
+		assert.True(t, reopened.CheckAndMark("alloc-1", now))
+		assert.False(t, reopened.CheckAndMark("alloc-2", now))
+	})
+
+	t.Run("stores are shared by path until closed", func(t *testing.T) {
+		sharedPath := filepath.Join(t.TempDir(), "shared.json")
+		first, err := NewFileStore(sharedPath, time.Hour, 10)
+		require.NoError(t, err)
+		first.CheckAndMark("alloc-1", now)
+
+		// A reloaded configuration sees keys not written yet
+		second, err := NewFileStore(sharedPath, time.Hour, 10)
+		require.NoError(t, err)
+		assert.True(t, second.CheckAndMark("alloc-1", now))
+
+		require.NoError(t, first.Close())
+		assert.NoFileExists(t, sharedPath, "the store is written when its last owner closes it")
+		require.NoError(t, second.Close())
+
+		reopened, err := NewFileStore(sharedPath, time.Hour, 10)
+		require.NoError(t, err)
+		defer reopened.Close()
+		assert.True(t, reopened.CheckAndMark("alloc-1", now))
+	})
+
+	t.Run("keys are written after a delay", func(t *testing.T) {
+		delayedPath := filepath.Join(t.TempDir(), "delayed.json")
+		delayed, err := NewFileStore(delayedPath, time.Hour, 10)
+		require.NoError(t, err)
+		defer delayed.Close()
+		delayed.saveDelay = 10 * time.Millisecond
+
+		delayed.CheckAndMark("alloc-1", now)
+		delayed.CheckAndMark("alloc-2", now)
+		assert.Eventually(t, func() bool {
+			data, err := os.ReadFile(delayedPath)
+			return err == nil && strings.Contains(string(data), "alloc-2")
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("closed stores no longer write", func(t *testing.T) {
+		closedPath := filepath.Join(t.TempDir(), "closed.json")
+		closed, err := NewFileStore(closedPath, time.Hour, 10)
+		require.NoError(t, err)
+		closed.saveDelay = 10 * time.Millisecond
+		closed.CheckAndMark("alloc-1", now)
+		require.NoError(t, closed.Close())
+
+		// The store that replaces it after a reload owns the file
+		replacement, err := NewFileStore(closedPath, time.Hour, 10)
+		require.NoError(t, err)
+		assert.NotSame(t, closed, replacement)
+		replacement.CheckAndMark("alloc-2", now)
+		require.NoError(t, replacement.Close())
+
+		// A router still draining marks the closed store
+		assert.False(t, closed.CheckAndMark("alloc-3", now))
+		time.Sleep(50 * time.Millisecond)
+
+		data, err := os.ReadFile(closedPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "alloc-2")
+		assert.NotContains(t, string(data), "alloc-3")
+	})
+
+	t.Run("expired keys are not loaded", func(t *testing.T) {
+		shortPath := filepath.Join(t.TempDir(), "short.json")
+		short, err := NewFileStore(shortPath, time.Millisecond, 10)
+		require.NoError(t, err)
+		short.CheckAndMark("alloc-1", now.Add(-time.Second))
+		require.NoError(t, short.Close())
+
+		reopened, err := NewFileStore(shortPath, time.Millisecond, 10)
+		require.NoError(t, err)
+		defer reopened.Close()
+		assert.Equal(t, 0, reopened.Len())
+	})
+
+	t.Run("missing directory is reported on write only", func(t *testing.T) {
+		missing, err := NewFileStore(filepath.Join(t.TempDir(), "missing", "dedupe.json"), time.Hour, 10)
+		require.NoError(t, err)
+		assert.False(t, missing.CheckAndMark("a", now))
+		assert.True(t, missing.CheckAndMark("a", now))
+		assert.Error(t, missing.Close())
+	})
+}
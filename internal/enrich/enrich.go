@@ -0,0 +1,259 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"nomad-events/internal/cache"
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Enricher attaches the job, node and allocation an event refers to, so
+// filters and templates can use them without each making their own API calls
+type Enricher struct {
+	fetcher    Fetcher
+	job        bool
+	node       bool
+	allocation bool
+	logs       *logExcerpts
+	cache      *cache.Cache
+}
+
+// Fetcher looks up Nomad objects, returning them as JSON-decoded maps. A
+// missing object is returned as nil without an error.
+type Fetcher interface {
+	Job(ctx context.Context, namespace, id string) (map[string]interface{}, error)
+	Node(ctx context.Context, id string) (map[string]interface{}, error)
+	Allocation(ctx context.Context, namespace, id string) (map[string]interface{}, error)
+
+	// Logs returns the last lines of a task's "stderr" or "stdout" log,
+	// reading at most maxBytes from its end
+	Logs(ctx context.Context, namespace, allocID, task, logType string, lines int, maxBytes int64) (string, error)
+}
+
+// New creates an Enricher from its configuration
+func New(cfg config.EnrichmentConfig, fetcher Fetcher) (*Enricher, error) {
+	var ttl, timeout time.Duration
+	var err error
+	if cfg.CacheTTL != "" {
+		if ttl, err = time.ParseDuration(cfg.CacheTTL); err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl %q: %w", cfg.CacheTTL, err)
+		}
+	}
+	if cfg.Timeout != "" {
+		if timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", cfg.Timeout, err)
+		}
+	}
+
+	e := &Enricher{
+		fetcher:    fetcher,
+		job:        cfg.Job,
+		node:       cfg.Node,
+		allocation: cfg.Allocation,
+		cache:      cache.New(ttl, timeout),
+	}
+	if cfg.Logs != nil {
+		if e.logs, err = newLogExcerpts(*cfg.Logs); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Enrich returns the event with Enriched set to the objects it refers to.
+// Lookups that fail are logged and left out; the event is always returned.
+// Cached objects changed by the event itself are looked up again.
+func (e *Enricher) Enrich(ctx context.Context, event nomad.Event) nomad.Event {
+	e.cache.Observe(event)
+
+	refs := references(event)
+	enriched := make(map[string]interface{})
+
+	if e.job && refs.jobID != "" {
+		e.lookup(ctx, enriched, "Job", cache.JobTag(refs.namespace, refs.jobID), event, func(ctx context.Context) (map[string]interface{}, error) {
+			return e.fetcher.Job(ctx, refs.namespace, refs.jobID)
+		})
+	}
+	if e.node && refs.nodeID != "" {
+		e.lookup(ctx, enriched, "Node", cache.NodeTag(refs.nodeID), event, func(ctx context.Context) (map[string]interface{}, error) {
+			return e.fetcher.Node(ctx, refs.nodeID)
+		})
+	}
+	if e.allocation && refs.allocID != "" {
+		e.lookup(ctx, enriched, "Allocation", cache.AllocationTag(refs.allocID), event, func(ctx context.Context) (map[string]interface{}, error) {
+			return e.fetcher.Allocation(ctx, refs.namespace, refs.allocID)
+		})
+	}
+
+	if len(enriched) > 0 {
+		event.Enriched = enriched
+	}
+
+	if e.logs != nil {
+		e.attachLogs(ctx, &event)
+	}
+	return event
+}
+
+// lookup fetches an object through the cache, keyed and tagged by the object.
+// Missing objects are cached too, so deleted jobs are not looked up for every event.
+func (e *Enricher) lookup(ctx context.Context, enriched map[string]interface{}, field, tag string, event nomad.Event, fetch func(context.Context) (map[string]interface{}, error)) {
+	value, err := e.cache.Get(ctx, tag, []string{tag}, func(ctx context.Context) (interface{}, uint64, error) {
+		object, err := fetch(ctx)
+		return object, 0, err
+	})
+	if err != nil {
+		slog.Warn("Failed to enrich event",
+			"field", field,
+			"error", err,
+			"topic", event.Topic,
+			"type", event.Type,
+			"key", event.Key)
+		return
+	}
+
+	if object, _ := value.(map[string]interface{}); object != nil {
+		enriched[field] = object
+	}
+}
+
+// Prune removes expired lookups from the cache
+func (e *Enricher) Prune() {
+	e.cache.Prune()
+}
+
+// Cache returns the cache of enrichment lookups
+func (e *Enricher) Cache() *cache.Cache {
+	return e.cache
+}
+
+// refs are the IDs of the objects an event refers to
+type refs struct {
+	namespace, jobID, nodeID, allocID string
+}
+
+// references finds the job, node and allocation IDs in an event payload. The
+// ID of a Job, Node or Allocation object wins; otherwise the JobID, NodeID and
+// AllocID fields of any payload object are used.
+func references(event nomad.Event) refs {
+	r := refs{namespace: event.Namespace}
+
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return r
+	}
+
+	names := make([]string, 0, len(payload))
+	for name := range payload {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fields, ok := payload[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if ns, _ := fields["Namespace"].(string); ns != "" && r.namespace == "" {
+			r.namespace = ns
+		}
+
+		id, _ := fields["ID"].(string)
+		switch name {
+		case "Job":
+			setRef(&r.jobID, id, true)
+		case "Node":
+			setRef(&r.nodeID, id, true)
+		case "Allocation":
+			setRef(&r.allocID, id, true)
+		}
+
+		jobID, _ := fields["JobID"].(string)
+		setRef(&r.jobID, jobID, false)
+		nodeID, _ := fields["NodeID"].(string)
+		setRef(&r.nodeID, nodeID, false)
+		allocID, _ := fields["AllocID"].(string)
+		setRef(&r.allocID, allocID, false)
+	}
+
+	if r.namespace == "" {
+		r.namespace = "default"
+	}
+	return r
+}
+
+// setRef sets a reference if it is unset, or always for the object's own ID
+func setRef(ref *string, id string, own bool) {
+	if id != "" && (own || *ref == "") {
+		*ref = id
+	}
+}
+
+// APIFetcher looks up objects through a Nomad API client
+type APIFetcher struct {
+	client *api.Client
+}
+
+// NewAPIFetcher creates a Fetcher backed by a Nomad API client
+func NewAPIFetcher(client *api.Client) *APIFetcher {
+	return &APIFetcher{client: client}
+}
+
+// Job returns a job by namespace and ID
+func (f *APIFetcher) Job(ctx context.Context, namespace, id string) (map[string]interface{}, error) {
+	job, _, err := f.client.Jobs().Info(id, (&api.QueryOptions{Namespace: namespace}).WithContext(ctx))
+	return toMap(job, err)
+}
+
+// Node returns a node by ID
+func (f *APIFetcher) Node(ctx context.Context, id string) (map[string]interface{}, error) {
+	node, _, err := f.client.Nodes().Info(id, (&api.QueryOptions{}).WithContext(ctx))
+	return toMap(node, err)
+}
+
+// Allocation returns an allocation by namespace and ID
+func (f *APIFetcher) Allocation(ctx context.Context, namespace, id string) (map[string]interface{}, error) {
+	alloc, _, err := f.client.Allocations().Info(id, (&api.QueryOptions{Namespace: namespace}).WithContext(ctx))
+	return toMap(alloc, err)
+}
+
+// Logs returns the tail of a task's log through the client FS API
+func (f *APIFetcher) Logs(ctx context.Context, namespace, allocID, task, logType string, lines int, maxBytes int64) (string, error) {
+	alloc, _, err := f.client.Allocations().Info(allocID, (&api.QueryOptions{Namespace: namespace}).WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to get allocation: %w", err)
+	}
+	return nomad.TailLogs(ctx, f.client, alloc, task, logType, lines, maxBytes)
+}
+
+// toMap converts an API object to a map so CEL and templates see the same
+// shape as event payloads. Not found errors become a nil object.
+func toMap(object interface{}, err error) (map[string]interface{}, error) {
+	if err != nil {
+		if nomad.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return result, nil
+}
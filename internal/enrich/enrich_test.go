@@ -0,0 +1,223 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFetcher records lookups and returns fixed objects
+type fakeFetcher struct {
+	calls []string
+	err   error
+	logs  map[string]string // log type -> content
+}
+
+func (f *fakeFetcher) Job(ctx context.Context, namespace, id string) (map[string]interface{}, error) {
+	f.calls = append(f.calls, "job "+namespace+"/"+id)
+	if id == "missing" {
+		return nil, nil
+	}
+	return map[string]interface{}{"ID": id, "Namespace": namespace, "Status": "running"}, f.err
+}
+
+func (f *fakeFetcher) Node(ctx context.Context, id string) (map[string]interface{}, error) {
+	f.calls = append(f.calls, "node "+id)
+	return map[string]interface{}{"ID": id, "Name": "client-1"}, f.err
+}
+
+func (f *fakeFetcher) Allocation(ctx context.Context, namespace, id string) (map[string]interface{}, error) {
+	f.calls = append(f.calls, "alloc "+namespace+"/"+id)
+	return map[string]interface{}{"ID": id, "ClientStatus": "running"}, f.err
+}
+
+func (f *fakeFetcher) Logs(ctx context.Context, namespace, allocID, task, logType string, lines int, maxBytes int64) (string, error) {
+	f.calls = append(f.calls, "logs "+allocID+"/"+task+"/"+logType)
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.logs[logType], nil
+}
+
+func allocationEvent() nomad.Event {
+	return nomad.Event{
+		Topic: "Allocation",
+		Type:  "AllocationUpdated",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":        "alloc-1",
+				"JobID":     "web",
+				"NodeID":    "node-1",
+				"Namespace": "prod",
+			},
+		},
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	enricher, err := New(config.EnrichmentConfig{Job: true, Node: true}, fetcher)
+	require.NoError(t, err)
+
+	event := enricher.Enrich(context.Background(), allocationEvent())
+	require.NotNil(t, event.Enriched)
+	assert.Equal(t, "running", event.Enriched["Job"].(map[string]interface{})["Status"])
+	assert.Equal(t, "client-1", event.Enriched["Node"].(map[string]interface{})["Name"])
+	assert.NotContains(t, event.Enriched, "Allocation")
+	assert.Equal(t, []string{"job prod/web", "node node-1"}, fetcher.calls)
+}
+
+func TestEnrichCaches(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	enricher, err := New(config.EnrichmentConfig{Job: true, Node: true}, fetcher)
+	require.NoError(t, err)
+
+	enricher.Enrich(context.Background(), allocationEvent())
+	enricher.Enrich(context.Background(), allocationEvent())
+	assert.Equal(t, []string{"job prod/web", "node node-1"}, fetcher.calls)
+
+	// An update to the job invalidates it before the job event is enriched
+	event := enricher.Enrich(context.Background(), nomad.Event{
+		Topic: "Job",
+		Type:  "JobRegistered",
+		Index: 10,
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web", "Namespace": "prod"},
+		},
+	})
+	assert.Contains(t, event.Enriched, "Job")
+	assert.Equal(t, []string{"job prod/web", "node node-1", "job prod/web"}, fetcher.calls)
+
+	stats := enricher.Cache().Stats()
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Invalidations)
+}
+
+func TestEnrichCachesJobsPerNamespace(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	enricher, err := New(config.EnrichmentConfig{Job: true}, fetcher)
+	require.NoError(t, err)
+
+	staging := allocationEvent()
+	staging.Payload.(map[string]interface{})["Allocation"].(map[string]interface{})["Namespace"] = "staging"
+
+	prodEvent := enricher.Enrich(context.Background(), allocationEvent())
+	stagingEvent := enricher.Enrich(context.Background(), staging)
+	assert.Equal(t, "prod", prodEvent.Enriched["Job"].(map[string]interface{})["Namespace"])
+	assert.Equal(t, "staging", stagingEvent.Enriched["Job"].(map[string]interface{})["Namespace"])
+	assert.Equal(t, []string{"job prod/web", "job staging/web"}, fetcher.calls)
+
+	// Updating the job in one namespace leaves the other cached
+	enricher.Enrich(context.Background(), nomad.Event{
+		Topic: "Job",
+		Type:  "JobRegistered",
+		Index: 10,
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web", "Namespace": "staging"},
+		},
+	})
+	enricher.Enrich(context.Background(), allocationEvent())
+	assert.Equal(t, []string{"job prod/web", "job staging/web", "job staging/web"}, fetcher.calls)
+}
+
+func TestEnrichMissingAndFailedLookups(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	enricher, err := New(config.EnrichmentConfig{Job: true}, fetcher)
+	require.NoError(t, err)
+
+	// Missing objects are left out but still cached
+	event := allocationEvent()
+	event.Payload.(map[string]interface{})["Allocation"].(map[string]interface{})["JobID"] = "missing"
+	assert.Nil(t, enricher.Enrich(context.Background(), event).Enriched)
+	enricher.Enrich(context.Background(), event)
+	assert.Len(t, fetcher.calls, 1)
+
+	// Errors are not cached
+	fetcher.err = errors.New("connection refused")
+	assert.Nil(t, enricher.Enrich(context.Background(), allocationEvent()).Enriched)
+	enricher.Enrich(context.Background(), allocationEvent())
+	assert.Len(t, fetcher.calls, 3)
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    nomad.Event
+		expected refs
+	}{
+		{
+			name:     "allocation",
+			event:    allocationEvent(),
+			expected: refs{namespace: "prod", jobID: "web", nodeID: "node-1", allocID: "alloc-1"},
+		},
+		{
+			name: "job",
+			event: nomad.Event{Topic: "Job", Payload: map[string]interface{}{
+				"Job": map[string]interface{}{"ID": "web", "Namespace": "default"},
+			}},
+			expected: refs{namespace: "default", jobID: "web"},
+		},
+		{
+			name: "service registration",
+			event: nomad.Event{Topic: "Service", Payload: map[string]interface{}{
+				"Service": map[string]interface{}{"ID": "_nomad-task-1", "AllocID": "alloc-2", "NodeID": "node-2", "JobID": "api"},
+			}},
+			expected: refs{namespace: "default", jobID: "api", nodeID: "node-2", allocID: "alloc-2"},
+		},
+		{
+			name:     "no payload",
+			event:    nomad.Event{Topic: "Node"},
+			expected: refs{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, references(tt.event))
+		})
+	}
+}
+
+func TestNewInvalidDuration(t *testing.T) {
+	_, err := New(config.EnrichmentConfig{CacheTTL: "later"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid cache_ttl")
+}
+
+func TestAPIFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/job/web":
+			assert.Equal(t, "prod", r.URL.Query().Get("namespace"))
+			w.Write([]byte(`{"ID": "web", "Status": "running"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.Address = server.URL
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+	fetcher := NewAPIFetcher(client)
+
+	job, err := fetcher.Job(context.Background(), "prod", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "running", job["Status"])
+
+	node, err := fetcher.Node(context.Background(), "gone")
+	require.NoError(t, err)
+	assert.Nil(t, node)
+}
@@ -0,0 +1,175 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+)
+
+// Defaults for log excerpts
+const (
+	DefaultLogLines    = 20
+	DefaultLogMaxBytes = 4096
+
+	// logReadBytes is how much of the end of each log is read before lines are taken
+	logReadBytes = 64 * 1024
+)
+
+// builtinRedactions hide common credentials, keeping the name they were assigned to
+var builtinRedactions = []redaction{
+	{regexp.MustCompile(`(?i)\b(password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)(\s*[=:]\s*)("[^"]*"|'[^']*'|\S+)`), "${1}${2}[REDACTED]"},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]{8,}`), "${1} [REDACTED]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED]"},
+	{regexp.MustCompile(`\bhv[sbr]\.[A-Za-z0-9_-]{20,}`), "[REDACTED]"},
+	{regexp.MustCompile(`://([^:/@\s]+):([^@/\s]+)@`), "://${1}:[REDACTED]@"},
+}
+
+type redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// logExcerpts configures the logs attached to events with failed tasks
+type logExcerpts struct {
+	lines      int
+	maxBytes   int
+	redactions []redaction
+}
+
+func newLogExcerpts(cfg config.LogExcerptConfig) (*logExcerpts, error) {
+	l := &logExcerpts{
+		lines:      DefaultLogLines,
+		maxBytes:   DefaultLogMaxBytes,
+		redactions: append([]redaction(nil), builtinRedactions...),
+	}
+	if cfg.Lines > 0 {
+		l.lines = cfg.Lines
+	}
+	if cfg.MaxBytes > 0 {
+		l.maxBytes = cfg.MaxBytes
+	}
+
+	for _, pattern := range cfg.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		l.redactions = append(l.redactions, redaction{re, "[REDACTED]"})
+	}
+
+	return l, nil
+}
+
+// excerpt limits and redacts the tail of a log
+func (l *logExcerpts) excerpt(log string) string {
+	log = nomad.LastLines(log, l.lines)
+	for _, r := range l.redactions {
+		log = r.pattern.ReplaceAllString(log, r.replacement)
+	}
+
+	// Keep the end of the log, starting at a line boundary where possible,
+	// and otherwise at the start of a character
+	if len(log) > l.maxBytes {
+		log = log[len(log)-l.maxBytes:]
+		if i := strings.IndexByte(log, '\n'); i >= 0 && i < len(log)-1 {
+			log = log[i+1:]
+		}
+		for len(log) > 0 && !utf8.RuneStart(log[0]) {
+			log = log[1:]
+		}
+	}
+	return log
+}
+
+// attachLogs sets the logs of every failed task of an Allocation event.
+// The logs of a dead task only change when it restarts, so they are read once
+// per allocation, task and restart, and later updates to the allocation are
+// served from the cache. Failed reads are cached too, so a down node does not
+// hold up every update of its allocations.
+func (e *Enricher) attachLogs(ctx context.Context, event *nomad.Event) {
+	alloc, ok := payloadAllocation(event)
+	if !ok {
+		return
+	}
+	tasks := failedTasks(alloc)
+	if len(tasks) == 0 {
+		return
+	}
+
+	allocID, _ := alloc["ID"].(string)
+	namespace, _ := alloc["Namespace"].(string)
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	logs := make(map[string]nomad.TaskLogs, len(tasks))
+	for _, task := range tasks {
+		var taskLogs nomad.TaskLogs
+		for _, stream := range []struct {
+			logType string
+			target  *string
+		}{{"stderr", &taskLogs.Stderr}, {"stdout", &taskLogs.Stdout}} {
+			key := fmt.Sprintf("logs/%s/%s/%d/%s", allocID, task.name, task.restarts, stream.logType)
+			value, _ := e.cache.Get(ctx, key, nil, func(ctx context.Context) (interface{}, uint64, error) {
+				log, err := e.fetcher.Logs(ctx, namespace, allocID, task.name, stream.logType, e.logs.lines, logReadBytes)
+				if err != nil {
+					slog.Warn("Failed to fetch task logs",
+						"alloc_id", allocID,
+						"task", task.name,
+						"type", stream.logType,
+						"error", err)
+					return "", 0, nil
+				}
+				return e.logs.excerpt(log), 0, nil
+			})
+			*stream.target, _ = value.(string)
+		}
+		logs[task.name] = taskLogs
+	}
+
+	event.Logs = logs
+}
+
+func payloadAllocation(event *nomad.Event) (map[string]interface{}, bool) {
+	if event.Topic != "Allocation" {
+		return nil, false
+	}
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	alloc, ok := payload["Allocation"].(map[string]interface{})
+	return alloc, ok
+}
+
+// failedTask is a dead, failed task and how often it was restarted
+type failedTask struct {
+	name     string
+	restarts int
+}
+
+// failedTasks returns the dead, failed tasks of an allocation by name
+func failedTasks(alloc map[string]interface{}) []failedTask {
+	states, _ := alloc["TaskStates"].(map[string]interface{})
+
+	var tasks []failedTask
+	for name, state := range states {
+		fields, ok := state.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fields["State"] == "dead" && fields["Failed"] == true {
+			restarts, _ := fields["Restarts"].(float64)
+			tasks = append(tasks, failedTask{name: name, restarts: int(restarts)})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].name < tasks[j].name })
+	return tasks
+}
@@ -0,0 +1,276 @@
+package flap
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+)
+
+// Flapping event types
+const (
+	TypeFlappingStarted = "FlappingStarted"
+	TypeFlappingEnded   = "FlappingEnded"
+)
+
+// Detector tracks state transitions per key over a sliding window. A key that
+// reaches the threshold is flapping: its events are suppressed until it has
+// been stable for a while, with a FlappingStarted and FlappingEnded event in
+// their place.
+type Detector struct {
+	keyTemplate    string
+	stateTemplate  string
+	threshold      int
+	window         time.Duration
+	stableFor      time.Duration
+	templateEngine *template.Engine
+	now            func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+type keyState struct {
+	state          string
+	lastSeen       time.Time
+	lastTransition time.Time
+	transitions    []time.Time
+	states         []string // states seen while flapping, oldest first
+
+	flapping     bool
+	flappingFrom time.Time
+	suppressed   int
+	namespace    string
+	index        uint64
+}
+
+// New creates a Detector from a flap_detection configuration block
+func New(cfg config.FlapDetectionConfig) (*Detector, error) {
+	if cfg.Key == "" || cfg.State == "" {
+		return nil, fmt.Errorf("flap detection key and state templates are required")
+	}
+	if cfg.Threshold < 2 {
+		return nil, fmt.Errorf("flap detection threshold must be at least 2")
+	}
+
+	window, err := time.ParseDuration(cfg.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flap detection window %q: %w", cfg.Window, err)
+	}
+
+	stableFor := window
+	if cfg.StableFor != "" {
+		stableFor, err = time.ParseDuration(cfg.StableFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flap detection stable_for %q: %w", cfg.StableFor, err)
+		}
+	}
+
+	templateEngine := template.NewEngine()
+	if err := templateEngine.Compile(cfg.Key); err != nil {
+		return nil, fmt.Errorf("invalid flap detection key template: %w", err)
+	}
+	if err := templateEngine.Compile(cfg.State); err != nil {
+		return nil, fmt.Errorf("invalid flap detection state template: %w", err)
+	}
+
+	return &Detector{
+		keyTemplate:    cfg.Key,
+		stateTemplate:  cfg.State,
+		threshold:      cfg.Threshold,
+		window:         window,
+		stableFor:      stableFor,
+		templateEngine: templateEngine,
+		now:            time.Now,
+		keys:           make(map[string]*keyState),
+	}, nil
+}
+
+// Observe records the event's state and reports whether the event should be
+// suppressed, along with any FlappingStarted/FlappingEnded events it caused.
+// Events whose key or state cannot be rendered are never suppressed.
+func (d *Detector) Observe(event nomad.Event) (bool, []nomad.Event) {
+	key, ok := d.render(d.keyTemplate, event)
+	if !ok {
+		return false, nil
+	}
+	state, ok := d.render(d.stateTemplate, event)
+	if !ok {
+		return false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	var events []nomad.Event
+
+	ks, exists := d.keys[key]
+	if !exists {
+		ks = &keyState{state: state}
+		d.keys[key] = ks
+	}
+	ks.lastSeen = now
+	ks.namespace = event.Namespace
+	ks.index = event.Index
+
+	// A flapping key that has since been stable ends before this event is considered
+	if ks.flapping && now.Sub(ks.lastTransition) >= d.stableFor {
+		events = append(events, d.end(key, ks, now))
+	}
+
+	if exists && state != ks.state {
+		ks.transitions = append(ks.transitions, now)
+		ks.lastTransition = now
+	}
+	ks.state = state
+	ks.transitions = pruneBefore(ks.transitions, now.Add(-d.window))
+
+	if ks.flapping {
+		ks.suppressed++
+		ks.states = append(ks.states, state)
+		return true, events
+	}
+
+	if len(ks.transitions) >= d.threshold {
+		ks.flapping = true
+		ks.flappingFrom = now
+		ks.suppressed = 1
+		ks.states = []string{state}
+		events = append(events, d.started(key, ks, event))
+		return true, events
+	}
+
+	return false, events
+}
+
+// Sweep ends flapping for keys that have been stable long enough and forgets
+// keys that have not been seen for a window
+func (d *Detector) Sweep() []nomad.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	var events []nomad.Event
+
+	keys := make([]string, 0, len(d.keys))
+	for key := range d.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		ks := d.keys[key]
+		if ks.flapping {
+			if now.Sub(ks.lastTransition) >= d.stableFor {
+				events = append(events, d.end(key, ks, now))
+			}
+			continue
+		}
+		if now.Sub(ks.lastSeen) > d.window {
+			delete(d.keys, key)
+		}
+	}
+
+	return events
+}
+
+// End ends flapping for every key, for a detector that is being replaced
+func (d *Detector) End() []nomad.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	keys := make([]string, 0, len(d.keys))
+	for key, ks := range d.keys {
+		if ks.flapping {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	now := d.now()
+	events := make([]nomad.Event, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, d.end(key, d.keys[key], now))
+	}
+	return events
+}
+
+// started builds a FlappingStarted event. Callers must hold d.mu.
+func (d *Detector) started(key string, ks *keyState, event nomad.Event) nomad.Event {
+	return d.flappingEvent(TypeFlappingStarted, key, ks, fmt.Sprintf(
+		"%s is flapping: %d state changes in %s, now %s", key, len(ks.transitions), d.window, ks.state),
+		map[string]interface{}{
+			"Transitions": len(ks.transitions),
+			"Window":      d.window.String(),
+			"Event":       nomad.EventMap(event),
+		})
+}
+
+// end stops flapping for a key and builds a FlappingEnded event. Callers must hold d.mu.
+func (d *Detector) end(key string, ks *keyState, now time.Time) nomad.Event {
+	duration := now.Sub(ks.flappingFrom).Truncate(time.Second)
+	event := d.flappingEvent(TypeFlappingEnded, key, ks, fmt.Sprintf(
+		"%s stopped flapping after %s and is %s; %d events suppressed", key, duration, ks.state, ks.suppressed),
+		map[string]interface{}{
+			"Suppressed": ks.suppressed,
+			"States":     ks.states,
+			"Duration":   duration.String(),
+		})
+
+	ks.flapping = false
+	ks.suppressed = 0
+	ks.states = nil
+	ks.transitions = nil
+	return event
+}
+
+func (d *Detector) flappingEvent(eventType, key string, ks *keyState, message string, fields map[string]interface{}) nomad.Event {
+	payload := map[string]interface{}{
+		"Message":   message,
+		"FlapKey":   key,
+		"State":     ks.state,
+		"Since":     ks.flappingFrom.UTC().Format(time.RFC3339),
+		"StableFor": d.stableFor.String(),
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+
+	return nomad.Event{
+		Topic:     nomad.TopicDerived,
+		Type:      eventType,
+		Key:       key,
+		Namespace: ks.namespace,
+		Index:     ks.index,
+		Payload:   payload,
+	}
+}
+
+func (d *Detector) render(text string, event nomad.Event) (string, bool) {
+	rendered, err := d.templateEngine.ProcessText(text, event)
+	if err != nil {
+		slog.Warn("Failed to render flap detection template, delivering event",
+			"error", err,
+			"topic", event.Topic,
+			"type", event.Type)
+		return "", false
+	}
+
+	rendered = strings.TrimSpace(rendered)
+	return rendered, rendered != ""
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,209 @@
+package flap
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nodeEvent(id, status string) nomad.Event {
+	return nomad.Event{
+		Topic: "Node",
+		Type:  "NodeEvent",
+		Key:   id,
+		Payload: map[string]interface{}{
+			"Node": map[string]interface{}{"ID": id, "Status": status},
+		},
+	}
+}
+
+func newTestDetector(t *testing.T, cfg config.FlapDetectionConfig) (*Detector, *time.Time) {
+	detector, err := New(cfg)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	detector.now = func() time.Time { return now }
+	return detector, &now
+}
+
+func TestDetector(t *testing.T) {
+	detector, now := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Payload.Node.ID }}",
+		State:     "{{ .Payload.Node.Status }}",
+		Threshold: 3,
+		Window:    "10m",
+		StableFor: "5m",
+	})
+
+	observe := func(status string) (bool, []nomad.Event) {
+		*now = now.Add(time.Minute)
+		return detector.Observe(nodeEvent("node-1", status))
+	}
+
+	// Two transitions are below the threshold
+	for _, status := range []string{"ready", "down", "ready"} {
+		suppress, events := observe(status)
+		assert.False(t, suppress)
+		assert.Empty(t, events)
+	}
+
+	// Repeating the same state is not a transition
+	suppress, _ := observe("ready")
+	assert.False(t, suppress)
+
+	// The third transition starts flapping
+	suppress, events := observe("down")
+	assert.True(t, suppress)
+	require.Len(t, events, 1)
+	assert.Equal(t, nomad.TopicDerived, events[0].Topic)
+	assert.Equal(t, TypeFlappingStarted, events[0].Type)
+	assert.Equal(t, "node-1", events[0].Key)
+
+	payload := events[0].Payload.(map[string]interface{})
+	assert.Equal(t, "node-1 is flapping: 3 state changes in 10m0s, now down", payload["Message"])
+	assert.Equal(t, 3, payload["Transitions"])
+	assert.Equal(t, "down", payload["State"])
+
+	// Events are suppressed while flapping
+	suppress, events = observe("ready")
+	assert.True(t, suppress)
+	assert.Empty(t, events)
+
+	// Not yet stable
+	*now = now.Add(4 * time.Minute)
+	assert.Empty(t, detector.Sweep())
+
+	*now = now.Add(time.Minute)
+	events = detector.Sweep()
+	require.Len(t, events, 1)
+	assert.Equal(t, TypeFlappingEnded, events[0].Type)
+
+	payload = events[0].Payload.(map[string]interface{})
+	assert.Equal(t, 2, payload["Suppressed"])
+	assert.Equal(t, []string{"down", "ready"}, payload["States"])
+	assert.Equal(t, "ready", payload["State"])
+	assert.Equal(t, "6m0s", payload["Duration"])
+
+	// Events are delivered again
+	suppress, events = observe("ready")
+	assert.False(t, suppress)
+	assert.Empty(t, events)
+}
+
+func TestDetectorEndsOnNextEvent(t *testing.T) {
+	detector, now := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Payload.Node.ID }}",
+		State:     "{{ .Payload.Node.Status }}",
+		Threshold: 2,
+		Window:    "10m",
+	})
+
+	detector.Observe(nodeEvent("node-1", "ready"))
+	detector.Observe(nodeEvent("node-1", "down"))
+	suppress, events := detector.Observe(nodeEvent("node-1", "ready"))
+	assert.True(t, suppress)
+	require.Len(t, events, 1)
+
+	// stable_for defaults to the window; the next event ends flapping first
+	*now = now.Add(10 * time.Minute)
+	suppress, events = detector.Observe(nodeEvent("node-1", "ready"))
+	assert.False(t, suppress)
+	require.Len(t, events, 1)
+	assert.Equal(t, TypeFlappingEnded, events[0].Type)
+}
+
+func TestDetectorEnd(t *testing.T) {
+	detector, _ := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Payload.Node.ID }}",
+		State:     "{{ .Payload.Node.Status }}",
+		Threshold: 2,
+		Window:    "10m",
+	})
+
+	for _, id := range []string{"node-2", "node-1"} {
+		detector.Observe(nodeEvent(id, "ready"))
+		detector.Observe(nodeEvent(id, "down"))
+		detector.Observe(nodeEvent(id, "ready"))
+	}
+	detector.Observe(nodeEvent("node-3", "ready"))
+
+	events := detector.End()
+	require.Len(t, events, 2, "only flapping keys end")
+	assert.Equal(t, TypeFlappingEnded, events[0].Type)
+	assert.Equal(t, "node-1", events[0].Key)
+	assert.Equal(t, "node-2", events[1].Key)
+	assert.Empty(t, detector.End())
+}
+
+func TestDetectorKeysAreIndependent(t *testing.T) {
+	detector, _ := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Payload.Node.ID }}",
+		State:     "{{ .Payload.Node.Status }}",
+		Threshold: 2,
+		Window:    "10m",
+	})
+
+	detector.Observe(nodeEvent("node-1", "ready"))
+	detector.Observe(nodeEvent("node-1", "down"))
+	suppress, _ := detector.Observe(nodeEvent("node-1", "ready"))
+	assert.True(t, suppress)
+
+	suppress, _ = detector.Observe(nodeEvent("node-2", "down"))
+	assert.False(t, suppress)
+}
+
+func TestDetectorForgetsIdleKeys(t *testing.T) {
+	detector, now := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Payload.Node.ID }}",
+		State:     "{{ .Payload.Node.Status }}",
+		Threshold: 2,
+		Window:    "10m",
+	})
+
+	detector.Observe(nodeEvent("node-1", "ready"))
+	*now = now.Add(11 * time.Minute)
+	detector.Sweep()
+	assert.Empty(t, detector.keys)
+}
+
+func TestDetectorEmptyKey(t *testing.T) {
+	detector, _ := newTestDetector(t, config.FlapDetectionConfig{
+		Key:       "{{ .Namespace }}",
+		State:     "{{ .Type }}",
+		Threshold: 2,
+		Window:    "10m",
+	})
+
+	for i := 0; i < 5; i++ {
+		suppress, events := detector.Observe(nomad.Event{Topic: "Node", Type: []string{"A", "B"}[i%2]})
+		assert.False(t, suppress)
+		assert.Empty(t, events)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.FlapDetectionConfig
+		errMsg string
+	}{
+		{"missing templates", config.FlapDetectionConfig{Threshold: 2, Window: "1m"}, "templates are required"},
+		{"low threshold", config.FlapDetectionConfig{Key: "k", State: "s", Threshold: 1, Window: "1m"}, "at least 2"},
+		{"invalid window", config.FlapDetectionConfig{Key: "k", State: "s", Threshold: 2, Window: "x"}, "invalid flap detection window"},
+		{"invalid stable_for", config.FlapDetectionConfig{Key: "k", State: "s", Threshold: 2, Window: "1m", StableFor: "x"}, "stable_for"},
+		{"invalid state template", config.FlapDetectionConfig{Key: "k", State: "{{ .Payload", Threshold: 2, Window: "1m"}, "invalid flap detection state template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	require.NoError(t, WriteFileAtomic(path, []byte(`{"a":1}`)))
+	require.NoError(t, WriteFileAtomic(path, []byte(`{"b":2}`)))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":2}`, string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "state.json"), nil))
+}
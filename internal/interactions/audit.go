@@ -0,0 +1,96 @@
+package interactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit results
+const (
+	ResultOK     = "ok"
+	ResultDenied = "denied"
+	ResultFailed = "failed"
+)
+
+// AuditEntry records who ran which action from Slack, and how it went
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name,omitempty"`
+	TeamID    string    `json:"team_id,omitempty"`
+	ChannelID string    `json:"channel_id,omitempty"`
+	ActionID  string    `json:"action_id"`
+	Operation string    `json:"operation,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Target    string    `json:"target,omitempty"` // The action's value, such as an allocation ID
+	Result    string    `json:"result"`           // ok, denied or failed
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditLog logs every action, and appends it to a JSON lines file when one is configured
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog opens the audit file for appending. An empty path only logs.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if path == "" {
+		return &AuditLog{}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{file: file}, nil
+}
+
+// Record logs an entry and appends it to the audit file
+func (a *AuditLog) Record(entry AuditEntry) {
+	attrs := []any{
+		"user_id", entry.UserID,
+		"user_name", entry.UserName,
+		"action_id", entry.ActionID,
+		"operation", entry.Operation,
+		"namespace", entry.Namespace,
+		"target", entry.Target,
+		"result", entry.Result,
+	}
+	if entry.Error != "" {
+		attrs = append(attrs, "error", entry.Error)
+	}
+	if entry.Result == ResultOK {
+		slog.Info("Slack action", attrs...)
+	} else {
+		slog.Warn("Slack action", attrs...)
+	}
+
+	if a.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("Failed to encode audit entry", "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		slog.Error("Failed to write audit log", "error", err, "path", a.file.Name())
+	}
+}
+
+// Close closes the audit file
+func (a *AuditLog) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
@@ -0,0 +1,47 @@
+package interactions
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	at := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	log, err := OpenAuditLog(path)
+	require.NoError(t, err)
+	log.Record(AuditEntry{Time: at, UserID: "U1", ActionID: "restart", Operation: "restart_alloc", Target: "a1", Result: ResultOK})
+	require.NoError(t, log.Close())
+
+	// Reopening appends rather than truncating
+	log, err = OpenAuditLog(path)
+	require.NoError(t, err)
+	log.Record(AuditEntry{Time: at, UserID: "U2", ActionID: "stop", Result: ResultDenied, Error: "user is not allowed"})
+	require.NoError(t, log.Close())
+
+	entries := readAudit(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "U1", entries[0].UserID)
+	assert.Equal(t, "a1", entries[0].Target)
+	assert.True(t, at.Equal(entries[0].Time))
+	assert.Equal(t, ResultDenied, entries[1].Result)
+	assert.Equal(t, "user is not allowed", entries[1].Error)
+
+	t.Run("without a file", func(t *testing.T) {
+		log, err := OpenAuditLog("")
+		require.NoError(t, err)
+		log.Record(AuditEntry{UserID: "U1", ActionID: "restart", Result: ResultOK})
+		assert.NoError(t, log.Close())
+	})
+
+	t.Run("unwritable path", func(t *testing.T) {
+		_, err := OpenAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open audit log")
+	})
+}
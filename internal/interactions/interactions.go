@@ -0,0 +1,333 @@
+// Package interactions runs Nomad operations when someone clicks a button or
+// picks from a menu in a Slack message sent by nomad-events
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/silence"
+
+	"github.com/slack-go/slack"
+)
+
+// DefaultSilenceDuration is how long silences created from Slack last
+const DefaultSilenceDuration = time.Hour
+
+// maxPayloadSize bounds the interaction payloads read from Slack
+const maxPayloadSize = 1 << 20
+
+// operationVerbs describe each operation in the replies posted to Slack
+var operationVerbs = map[string]string{
+	config.OperationRestartAlloc:      "restarted allocation",
+	config.OperationStopJob:           "stopped job",
+	config.OperationRevertJob:         "reverted job",
+	config.OperationPromoteDeployment: "promoted deployment",
+	config.OperationFailDeployment:    "failed deployment",
+	config.OperationSilence:           "silenced",
+}
+
+// Handler receives Slack interaction payloads and runs the operation allowed
+// for each action_id. Actions whose action_id is not configured are refused.
+type Handler struct {
+	secret    string
+	teamID    string
+	actions   map[string]config.ActionConfig
+	namespace string
+	nomad     Nomad
+	silences  *silence.Manager
+	audit     *AuditLog
+
+	httpClient *http.Client
+	now        func() time.Time
+	wg         sync.WaitGroup
+}
+
+// NewHandler creates a handler for the configured actions. Operations on
+// objects default to namespace when their action does not set one.
+func NewHandler(cfg *config.InteractionsConfig, namespace string, nomad Nomad, silences *silence.Manager) (*Handler, error) {
+	audit, err := OpenAuditLog(cfg.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	actions := make(map[string]config.ActionConfig, len(cfg.Actions))
+	for _, action := range cfg.Actions {
+		actions[action.ActionID] = action
+	}
+
+	return &Handler{
+		secret:     cfg.SigningSecret,
+		teamID:     cfg.TeamID,
+		actions:    actions,
+		namespace:  namespace,
+		nomad:      nomad,
+		silences:   silences,
+		audit:      audit,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}, nil
+}
+
+// Register adds the interaction endpoint to a mux. It is the Request URL of
+// the Slack app's Interactivity settings, so the mux should be served on its
+// own listener rather than with the management API.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/slack/interactions", h.handle)
+}
+
+// Close waits for running actions and closes the audit log
+func (h *Handler) Close() error {
+	h.wg.Wait()
+	return h.audit.Close()
+}
+
+func (h *Handler) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verify(r.Header, body); err != nil {
+		slog.Warn("Rejected Slack interaction with an invalid signature", "error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// Slack gives up on requests after three seconds, so actions run after
+	// the request is acknowledged and report back through the response URL
+	if callback.Type == slack.InteractionTypeBlockActions {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			for _, action := range callback.ActionCallback.BlockActions {
+				h.run(callback, action)
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the request was signed with the app's signing secret in the
+// last five minutes
+func (h *Handler) verify(header http.Header, body []byte) error {
+	verifier, err := slack.NewSecretsVerifier(header, h.secret)
+	if err != nil {
+		return err
+	}
+	if _, err := verifier.Write(body); err != nil {
+		return err
+	}
+	return verifier.Ensure()
+}
+
+// run authorizes and runs one action, recording it in the audit log and
+// replying in Slack
+func (h *Handler) run(callback slack.InteractionCallback, action *slack.BlockAction) {
+	entry := AuditEntry{
+		Time:      h.now(),
+		UserID:    callback.User.ID,
+		UserName:  userName(callback.User),
+		TeamID:    callback.Team.ID,
+		ChannelID: callback.Channel.ID,
+		ActionID:  action.ActionID,
+		Target:    actionValue(action),
+	}
+
+	actionConfig, ok := h.actions[action.ActionID]
+	if !ok {
+		entry.Result, entry.Error = ResultDenied, "action_id is not allowed"
+		h.audit.Record(entry)
+		h.reply(callback.ResponseURL, "ephemeral", fmt.Sprintf("❌ `%s` is not an allowed action", action.ActionID))
+		return
+	}
+	entry.Operation = actionConfig.Operation
+	entry.Namespace = h.actionNamespace(actionConfig)
+
+	if h.teamID != "" && callback.Team.ID != h.teamID {
+		entry.Result, entry.Error = ResultDenied, "team is not allowed"
+		h.audit.Record(entry)
+		h.reply(callback.ResponseURL, "ephemeral", fmt.Sprintf("❌ `%s` cannot be run from this workspace", action.ActionID))
+		return
+	}
+
+	if !allowed(actionConfig.Users, callback.User) {
+		entry.Result, entry.Error = ResultDenied, "user is not allowed"
+		h.audit.Record(entry)
+		h.reply(callback.ResponseURL, "ephemeral", fmt.Sprintf("❌ You are not allowed to run `%s`", action.ActionID))
+		return
+	}
+
+	if err := h.execute(actionConfig, entry); err != nil {
+		entry.Result, entry.Error = ResultFailed, err.Error()
+		h.audit.Record(entry)
+		h.reply(callback.ResponseURL, "ephemeral", fmt.Sprintf("❌ `%s` failed: %s", action.ActionID, err))
+		return
+	}
+
+	entry.Result = ResultOK
+	h.audit.Record(entry)
+	h.reply(callback.ResponseURL, "in_channel", fmt.Sprintf("✅ <@%s> %s `%s`", callback.User.ID, operationVerbs[actionConfig.Operation], entry.Target))
+}
+
+// execute runs the operation of an action on its target
+func (h *Handler) execute(action config.ActionConfig, entry AuditEntry) error {
+	if entry.Target == "" {
+		return fmt.Errorf("the action has no value naming its target")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	switch action.Operation {
+	case config.OperationRestartAlloc:
+		return h.nomad.RestartAllocation(ctx, entry.Namespace, entry.Target)
+	case config.OperationStopJob:
+		return h.nomad.StopJob(ctx, entry.Namespace, entry.Target)
+	case config.OperationRevertJob:
+		jobID, version, err := parseJobVersion(entry.Target)
+		if err != nil {
+			return err
+		}
+		return h.nomad.RevertJob(ctx, entry.Namespace, jobID, version)
+	case config.OperationPromoteDeployment:
+		return h.nomad.PromoteDeployment(ctx, entry.Namespace, entry.Target)
+	case config.OperationFailDeployment:
+		return h.nomad.FailDeployment(ctx, entry.Namespace, entry.Target)
+	case config.OperationSilence:
+		return h.silence(action, entry)
+	default:
+		return fmt.Errorf("unknown operation %q", action.Operation)
+	}
+}
+
+// silence mutes the events matched by the action's value, a comma-separated
+// list of matchers such as "Payload.Job.ID=web,Topic=Job"
+func (h *Handler) silence(action config.ActionConfig, entry AuditEntry) error {
+	if h.silences == nil {
+		return fmt.Errorf("silences are not available")
+	}
+
+	var matchers []silence.Matcher
+	for _, part := range strings.Split(entry.Target, ",") {
+		matcher, err := silence.ParseMatcher(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	duration := DefaultSilenceDuration
+	if action.Duration != "" {
+		duration, _ = time.ParseDuration(action.Duration)
+	}
+
+	now := h.now()
+	_, err := h.silences.Add(silence.Silence{
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(duration),
+		CreatedBy: "slack:" + entry.UserName,
+		Comment:   fmt.Sprintf("Silenced from Slack with %s", entry.ActionID),
+	})
+	return err
+}
+
+func (h *Handler) actionNamespace(action config.ActionConfig) string {
+	if action.Namespace != "" {
+		return action.Namespace
+	}
+	return h.namespace
+}
+
+// reply posts a message to an interaction's response URL. in_channel replies
+// are seen by everyone in the channel, ephemeral ones only by the user.
+func (h *Handler) reply(responseURL, responseType, text string) {
+	if responseURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"response_type":    responseType,
+		"replace_original": false,
+		"text":             text,
+	})
+	if err != nil {
+		return
+	}
+
+	resp, err := h.httpClient.Post(responseURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		slog.Warn("Failed to reply to Slack action", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("Failed to reply to Slack action", "status", resp.StatusCode)
+	}
+}
+
+// actionValue is the value of a button, or of the option picked from a menu
+func actionValue(action *slack.BlockAction) string {
+	if action.Value != "" {
+		return action.Value
+	}
+	return action.SelectedOption.Value
+}
+
+// allowed reports whether a user may run an action. Users are listed by ID,
+// since usernames can be changed by their owners; an empty list allows no one.
+func allowed(users []string, user slack.User) bool {
+	return user.ID != "" && slices.Contains(users, user.ID)
+}
+
+func userName(user slack.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.ID
+}
+
+// parseJobVersion splits a revert_job value written as "job:version"
+func parseJobVersion(value string) (string, uint64, error) {
+	i := strings.LastIndex(value, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid value %q: use \"job:version\"", value)
+	}
+
+	version, err := strconv.ParseUint(value[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid value %q: use \"job:version\"", value)
+	}
+	return value[:i], version, nil
+}
@@ -0,0 +1,341 @@
+package interactions
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/silence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+type fakeNomad struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (f *fakeNomad) record(call string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	return f.err
+}
+
+func (f *fakeNomad) RestartAllocation(_ context.Context, namespace, allocID string) error {
+	return f.record("restart " + namespace + "/" + allocID)
+}
+
+func (f *fakeNomad) StopJob(_ context.Context, namespace, jobID string) error {
+	return f.record("stop " + namespace + "/" + jobID)
+}
+
+func (f *fakeNomad) RevertJob(_ context.Context, namespace, jobID string, version uint64) error {
+	return f.record(fmt.Sprintf("revert %s/%s@%d", namespace, jobID, version))
+}
+
+func (f *fakeNomad) PromoteDeployment(_ context.Context, namespace, deploymentID string) error {
+	return f.record("promote " + namespace + "/" + deploymentID)
+}
+
+func (f *fakeNomad) FailDeployment(_ context.Context, namespace, deploymentID string) error {
+	return f.record("fail " + namespace + "/" + deploymentID)
+}
+
+// interaction builds a block_actions payload for one action
+func interaction(userID, userName, actionID, value, responseURL string) string {
+	return teamInteraction("T1", userID, userName, actionID, value, responseURL)
+}
+
+// teamInteraction builds a block_actions payload sent from a workspace
+func teamInteraction(teamID, userID, userName, actionID, value, responseURL string) string {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"id": userID, "username": userName, "name": userName},
+		"team":         map[string]string{"id": teamID},
+		"channel":      map[string]string{"id": "C1"},
+		"response_url": responseURL,
+		"actions":      []map[string]string{{"block_id": "actions", "action_id": actionID, "value": value}},
+	})
+	return url.Values{"payload": {string(payload)}}.Encode()
+}
+
+func signedRequest(t *testing.T, target, secret, body string, ts time.Time) *http.Request {
+	t.Helper()
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		replies []map[string]interface{}
+	)
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reply map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &reply))
+		mu.Lock()
+		replies = append(replies, reply)
+		mu.Unlock()
+	}))
+	defer slackServer.Close()
+
+	cfg := &config.InteractionsConfig{
+		SigningSecret: testSecret,
+		AuditLog:      filepath.Join(t.TempDir(), "audit.log"),
+		Actions: []config.ActionConfig{
+			{ActionID: "restart", Operation: config.OperationRestartAlloc, Users: []string{"U1", "U2"}},
+			{ActionID: "stop", Operation: config.OperationStopJob, Users: []string{"U1"}, Namespace: "prod"},
+			{ActionID: "revert", Operation: config.OperationRevertJob, Users: []string{"U1"}},
+			{ActionID: "promote", Operation: config.OperationPromoteDeployment, Users: []string{"U1"}},
+			{ActionID: "fail", Operation: config.OperationFailDeployment, Users: []string{"U1"}},
+			{ActionID: "mute", Operation: config.OperationSilence, Users: []string{"U1"}, Duration: "30m"},
+			{ActionID: "nobody", Operation: config.OperationStopJob},
+		},
+	}
+
+	silences, err := silence.NewManager("", 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		user     string
+		actionID string
+		value    string
+		nomadErr error
+		call     string
+		result   string
+		reply    string
+	}{
+		{name: "restart allocation", user: "U2", actionID: "restart", value: "a1", call: "restart default/a1", result: ResultOK, reply: "✅ <@U2> restarted allocation `a1`"},
+		{name: "stop job in the action's namespace", user: "U1", actionID: "stop", value: "web", call: "stop prod/web", result: ResultOK, reply: "stopped job `web`"},
+		{name: "usernames are not user IDs", user: "U9:U1", actionID: "stop", value: "web", result: ResultDenied, reply: "not allowed to run `stop`"},
+		{name: "user not allowed", user: "U2", actionID: "stop", value: "web", result: ResultDenied, reply: "not allowed to run `stop`"},
+		{name: "action without users", user: "U1", actionID: "nobody", value: "web", result: ResultDenied, reply: "not allowed to run `nobody`"},
+		{name: "unknown action", user: "U1", actionID: "purge", value: "web", result: ResultDenied, reply: "`purge` is not an allowed action"},
+		{name: "revert job", user: "U1", actionID: "revert", value: "batch:etl:3", call: "revert default/batch:etl@3", result: ResultOK},
+		{name: "revert without version", user: "U1", actionID: "revert", value: "web", result: ResultFailed, reply: `use "job:version"`},
+		{name: "promote deployment", user: "U1", actionID: "promote", value: "d1", call: "promote default/d1", result: ResultOK},
+		{name: "failed operation", user: "U1", actionID: "fail", value: "d1", nomadErr: fmt.Errorf("permission denied"), call: "fail default/d1", result: ResultFailed, reply: "failed: permission denied"},
+		{name: "missing value", user: "U1", actionID: "restart", result: ResultFailed, reply: "no value"},
+		{name: "silence", user: "U1", actionID: "mute", value: "Topic=Job, Payload.Job.ID=web", result: ResultOK, reply: "silenced `Topic=Job, Payload.Job.ID=web`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeNomad{err: tt.nomadErr}
+			handler, err := NewHandler(cfg, "", fake, silences)
+			require.NoError(t, err)
+
+			mux := http.NewServeMux()
+			handler.Register(mux)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			mu.Lock()
+			replies = nil
+			mu.Unlock()
+
+			userID, userName, _ := strings.Cut(tt.user, ":")
+			body := interaction(userID, userName, tt.actionID, tt.value, slackServer.URL)
+			resp, err := http.DefaultClient.Do(signedRequest(t, server.URL+"/api/v1/slack/interactions", testSecret, body, time.Now()))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			require.NoError(t, handler.Close())
+
+			if tt.call != "" {
+				assert.Equal(t, []string{tt.call}, fake.calls)
+			} else {
+				assert.Empty(t, fake.calls)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Len(t, replies, 1)
+			assert.Contains(t, replies[0]["text"], tt.reply)
+			assert.Equal(t, false, replies[0]["replace_original"])
+			if tt.result == ResultOK {
+				assert.Equal(t, "in_channel", replies[0]["response_type"])
+			} else {
+				assert.Equal(t, "ephemeral", replies[0]["response_type"])
+			}
+
+			entries := readAudit(t, cfg.AuditLog)
+			last := entries[len(entries)-1]
+			assert.Equal(t, tt.result, last.Result)
+			assert.Equal(t, tt.actionID, last.ActionID)
+			assert.Equal(t, userID, last.UserID)
+			assert.Equal(t, tt.value, last.Target)
+			assert.Equal(t, "T1", last.TeamID)
+		})
+	}
+
+	t.Run("silence is created", func(t *testing.T) {
+		event := nomad.Event{Topic: "Job", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "web"}}}
+		matched := silences.Match(event, "")
+		require.NotNil(t, matched)
+		assert.Equal(t, "slack:U1", matched.CreatedBy)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), matched.EndsAt, time.Minute)
+		assert.Nil(t, silences.Match(nomad.Event{Topic: "Job", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "api"}}}, ""))
+	})
+}
+
+func TestHandlerTeam(t *testing.T) {
+	cfg := &config.InteractionsConfig{
+		SigningSecret: testSecret,
+		TeamID:        "T1",
+		AuditLog:      filepath.Join(t.TempDir(), "audit.log"),
+		Actions:       []config.ActionConfig{{ActionID: "restart", Operation: config.OperationRestartAlloc, Users: []string{"U1"}}},
+	}
+
+	for _, tt := range []struct {
+		team   string
+		result string
+	}{
+		{team: "T1", result: ResultOK},
+		{team: "T2", result: ResultDenied},
+	} {
+		t.Run(tt.team, func(t *testing.T) {
+			fake := &fakeNomad{}
+			handler, err := NewHandler(cfg, "", fake, nil)
+			require.NoError(t, err)
+
+			mux := http.NewServeMux()
+			handler.Register(mux)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			body := teamInteraction(tt.team, "U1", "alice", "restart", "a1", "")
+			resp, err := http.DefaultClient.Do(signedRequest(t, server.URL+"/api/v1/slack/interactions", testSecret, body, time.Now()))
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.NoError(t, handler.Close())
+
+			entries := readAudit(t, cfg.AuditLog)
+			assert.Equal(t, tt.result, entries[len(entries)-1].Result)
+			if tt.result == ResultOK {
+				assert.Equal(t, []string{"restart default/a1"}, fake.calls)
+			} else {
+				assert.Empty(t, fake.calls)
+				assert.Equal(t, "team is not allowed", entries[len(entries)-1].Error)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsUnsignedRequests(t *testing.T) {
+	fake := &fakeNomad{}
+	handler, err := NewHandler(&config.InteractionsConfig{
+		SigningSecret: testSecret,
+		Actions:       []config.ActionConfig{{ActionID: "restart", Operation: config.OperationRestartAlloc}},
+	}, "default", fake, nil)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	target := server.URL + "/api/v1/slack/interactions"
+	body := interaction("U1", "alice", "restart", "a1", "")
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{name: "wrong secret", req: signedRequest(t, target, "other", body, time.Now()), status: http.StatusUnauthorized},
+		{name: "stale timestamp", req: signedRequest(t, target, testSecret, body, time.Now().Add(-10*time.Minute)), status: http.StatusUnauthorized},
+		{name: "no signature", req: func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+			return req
+		}(), status: http.StatusUnauthorized},
+		{name: "invalid payload", req: signedRequest(t, target, testSecret, "payload=%7B", time.Now()), status: http.StatusBadRequest},
+		{name: "other interaction types are ignored", req: signedRequest(t, target, testSecret, url.Values{"payload": {`{"type":"view_submission"}`}}.Encode(), time.Now()), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(tt.req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	require.NoError(t, handler.Close())
+	assert.Empty(t, fake.calls)
+}
+
+func TestParseJobVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		jobID   string
+		version uint64
+		wantErr bool
+	}{
+		{value: "web:4", jobID: "web", version: 4},
+		{value: "batch/periodic-123:0", jobID: "batch/periodic-123", version: 0},
+		{value: "a:b:12", jobID: "a:b", version: 12},
+		{value: "web", wantErr: true},
+		{value: ":4", wantErr: true},
+		{value: "web:latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			jobID, version, err := parseJobVersion(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.jobID, jobID)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func readAudit(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry AuditEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,76 @@
+package interactions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// operationTimeout bounds each Nomad API call made for an action
+const operationTimeout = 10 * time.Second
+
+// Nomad runs the operations Slack actions are mapped to
+type Nomad interface {
+	RestartAllocation(ctx context.Context, namespace, allocID string) error
+	StopJob(ctx context.Context, namespace, jobID string) error
+	RevertJob(ctx context.Context, namespace, jobID string, version uint64) error
+	PromoteDeployment(ctx context.Context, namespace, deploymentID string) error
+	FailDeployment(ctx context.Context, namespace, deploymentID string) error
+}
+
+// APINomad runs operations through a Nomad API client
+type APINomad struct {
+	client *api.Client
+}
+
+// NewAPINomad creates a Nomad backed by a Nomad API client
+func NewAPINomad(client *api.Client) *APINomad {
+	return &APINomad{client: client}
+}
+
+// RestartAllocation restarts every running task of an allocation
+func (n *APINomad) RestartAllocation(ctx context.Context, namespace, allocID string) error {
+	q := (&api.QueryOptions{Namespace: namespace}).WithContext(ctx)
+	if err := n.client.Allocations().Restart(&api.Allocation{ID: allocID}, "", q); err != nil {
+		return fmt.Errorf("failed to restart allocation %s: %w", allocID, err)
+	}
+	return nil
+}
+
+// StopJob stops a job without purging it, so it can be started again
+func (n *APINomad) StopJob(ctx context.Context, namespace, jobID string) error {
+	w := (&api.WriteOptions{Namespace: namespace}).WithContext(ctx)
+	if _, _, err := n.client.Jobs().Deregister(jobID, false, w); err != nil {
+		return fmt.Errorf("failed to stop job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// RevertJob reverts a job to an earlier version
+func (n *APINomad) RevertJob(ctx context.Context, namespace, jobID string, version uint64) error {
+	w := (&api.WriteOptions{Namespace: namespace}).WithContext(ctx)
+	if _, _, err := n.client.Jobs().Revert(jobID, version, nil, w, "", ""); err != nil {
+		return fmt.Errorf("failed to revert job %s to version %d: %w", jobID, version, err)
+	}
+	return nil
+}
+
+// PromoteDeployment promotes the canaries of every task group of a deployment
+func (n *APINomad) PromoteDeployment(ctx context.Context, namespace, deploymentID string) error {
+	w := (&api.WriteOptions{Namespace: namespace}).WithContext(ctx)
+	if _, _, err := n.client.Deployments().PromoteAll(deploymentID, w); err != nil {
+		return fmt.Errorf("failed to promote deployment %s: %w", deploymentID, err)
+	}
+	return nil
+}
+
+// FailDeployment marks a deployment as failed, which rolls it back if its job has auto_revert
+func (n *APINomad) FailDeployment(ctx context.Context, namespace, deploymentID string) error {
+	w := (&api.WriteOptions{Namespace: namespace}).WithContext(ctx)
+	if _, _, err := n.client.Deployments().Fail(deploymentID, w); err != nil {
+		return fmt.Errorf("failed to fail deployment %s: %w", deploymentID, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package interactions
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPINomad(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(n *APINomad) error
+		method string
+		path   string
+		body   string
+	}{
+		{
+			name:   "restart allocation",
+			run:    func(n *APINomad) error { return n.RestartAllocation(context.Background(), "prod", "a1") },
+			method: http.MethodPut,
+			path:   "/v1/client/allocation/a1/restart",
+		},
+		{
+			name:   "stop job",
+			run:    func(n *APINomad) error { return n.StopJob(context.Background(), "prod", "web") },
+			method: http.MethodDelete,
+			path:   "/v1/job/web",
+		},
+		{
+			name:   "revert job",
+			run:    func(n *APINomad) error { return n.RevertJob(context.Background(), "prod", "web", 3) },
+			method: http.MethodPut,
+			path:   "/v1/job/web/revert",
+			body:   `"JobVersion":3`,
+		},
+		{
+			name:   "promote deployment",
+			run:    func(n *APINomad) error { return n.PromoteDeployment(context.Background(), "prod", "d1") },
+			method: http.MethodPut,
+			path:   "/v1/deployment/promote/d1",
+			body:   `"All":true`,
+		},
+		{
+			name:   "fail deployment",
+			run:    func(n *APINomad) error { return n.FailDeployment(context.Background(), "prod", "d1") },
+			method: http.MethodPut,
+			path:   "/v1/deployment/fail/d1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.method, r.Method)
+				assert.Equal(t, tt.path, r.URL.Path)
+				assert.Equal(t, "prod", r.URL.Query().Get("namespace"))
+				body, _ := io.ReadAll(r.Body)
+				assert.Contains(t, string(body), tt.body)
+				json.NewEncoder(w).Encode(map[string]interface{}{})
+			}))
+			defer server.Close()
+
+			apiConfig := api.DefaultConfig()
+			apiConfig.Address = server.URL
+			client, err := api.NewClient(apiConfig)
+			require.NoError(t, err)
+
+			require.NoError(t, tt.run(NewAPINomad(client)))
+		})
+	}
+
+	t.Run("errors name the target", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		apiConfig := api.DefaultConfig()
+		apiConfig.Address = server.URL
+		client, err := api.NewClient(apiConfig)
+		require.NoError(t, err)
+
+		err = NewAPINomad(client).StopJob(context.Background(), "prod", "web")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to stop job web")
+		assert.Contains(t, err.Error(), "Permission denied")
+	})
+}
@@ -3,8 +3,12 @@ package nomad
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"nomad-events/internal/config"
@@ -12,6 +16,12 @@ import (
 	"github.com/hashicorp/nomad/api"
 )
 
+// TopicDerived is the topic of synthetic events derived from the event stream
+const TopicDerived = "Derived"
+
+// DefaultDiffTimeout bounds the job version lookup made for a job event's diff
+const DefaultDiffTimeout = 10 * time.Second
+
 type Event struct {
 	Topic     string      `json:"Topic"`
 	Type      string      `json:"Type"`
@@ -20,6 +30,97 @@ type Event struct {
 	Index     uint64      `json:"Index"`
 	Payload   interface{} `json:"Payload"`
 	Diff      interface{} `json:"Diff,omitempty"`
+
+	// Set on alert events: "firing" or "resolved", and a fingerprint that is
+	// the same for both
+	Status      string `json:"Status,omitempty"`
+	Fingerprint string `json:"Fingerprint,omitempty"`
+
+	// Nomad objects looked up by the enrichment stage, e.g. "Job" and "Node"
+	Enriched map[string]interface{} `json:"Enriched,omitempty"`
+
+	// Log excerpts of failed tasks by task name, set by the enrichment stage
+	Logs map[string]TaskLogs `json:"Logs,omitempty"`
+}
+
+// TaskLogs are the last lines of a task's logs
+type TaskLogs struct {
+	Stderr string `json:"Stderr"`
+	Stdout string `json:"Stdout"`
+}
+
+// EventMap returns the event as the map exposed to CEL expressions as `event`
+func EventMap(event Event) map[string]interface{} {
+	return map[string]interface{}{
+		"Topic":       event.Topic,
+		"Type":        event.Type,
+		"Key":         event.Key,
+		"Namespace":   event.Namespace,
+		"Index":       event.Index,
+		"Payload":     event.Payload,
+		"Diff":        event.Diff,
+		"Status":      event.Status,
+		"Fingerprint": event.Fingerprint,
+		"Enriched":    enrichedMap(event.Enriched),
+	}
+}
+
+// enrichedMap never returns nil, so CEL expressions can use has(event.Enriched.Job)
+func enrichedMap(enriched map[string]interface{}) map[string]interface{} {
+	if enriched == nil {
+		return map[string]interface{}{}
+	}
+	return enriched
+}
+
+// LookupField resolves a dotted path such as "Payload.Job.ID" in an event
+// map or template data
+func LookupField(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, part := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// EventTime returns when the object in the event payload was last modified,
+// taken from its ModifyTime (nanoseconds). The object named after the topic
+// is checked first, then the others by name. Events without one return false.
+func EventTime(event Event) (time.Time, bool) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return time.Time{}, false
+	}
+
+	names := make([]string, 0, len(payload))
+	for name := range payload {
+		if name != event.Topic {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := payload[event.Topic]; ok {
+		names = append([]string{event.Topic}, names...)
+	}
+
+	for _, name := range names {
+		fields, ok := payload[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if nanos, ok := fields["ModifyTime"].(float64); ok && nanos > 0 {
+			return time.Unix(0, int64(nanos)), true
+		}
+	}
+
+	return time.Time{}, false
 }
 
 type EventStream struct {
@@ -27,6 +128,7 @@ type EventStream struct {
 	lastIndex    uint64
 	retryBackoff time.Duration
 	maxRetries   int
+	diffTimeout  time.Duration
 }
 
 func NewEventStream(nomadConfig config.NomadConfig) (*EventStream, error) {
@@ -50,6 +152,7 @@ func NewEventStream(nomadConfig config.NomadConfig) (*EventStream, error) {
 		client:       client,
 		retryBackoff: time.Second,
 		maxRetries:   10,
+		diffTimeout:  DefaultDiffTimeout,
 	}, nil
 }
 
@@ -153,6 +256,9 @@ func (es *EventStream) connectAndStream(ctx context.Context, eventChan chan<- Ev
 		return fmt.Errorf("failed to start event stream: %w", err)
 	}
 
+	pipeline := es.newDiffPipeline(ctx, eventChan)
+	defer pipeline.close()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -172,37 +278,88 @@ func (es *EventStream) connectAndStream(ctx context.Context, eventChan chan<- Ev
 					Payload:   event.Payload,
 				}
 
-				// Fetch job diff for JobRegistered events
-				if string(event.Topic) == "Job" && event.Type == "JobRegistered" {
-					if jobData, ok := event.Payload["Job"].(map[string]interface{}); ok {
-						if jobID, ok := jobData["ID"].(string); ok {
-							// Only fetch diff if job version > 1 (has previous version to compare)
-							if version, ok := jobData["Version"].(float64); ok && version > 1 {
-								diff, err := es.fetchJobDiff(jobID)
-								if err != nil {
-									slog.Warn("Failed to fetch job diff", "job_id", jobID, "error", err)
-									// Continue without diff - don't fail the entire event
-								} else {
-									nomadEvent.Diff = diff
-								}
-							}
-							// For version 1 jobs, nomadEvent.Diff remains nil (no previous version to compare)
-						}
-					}
-				}
-
 				es.lastIndex = event.Index
 
-				select {
-				case eventChan <- nomadEvent:
-				case <-ctx.Done():
-					return ctx.Err()
+				if err := pipeline.add(nomadEvent); err != nil {
+					return err
 				}
 			}
 		}
 	}
 }
 
+// diffPipelineSize bounds how many events may wait for a job diff lookup
+// ahead of them before the stream stops being read
+const diffPipelineSize = 256
+
+// diffPipeline sends events in stream order while their job diffs are fetched
+// concurrently. Each event is queued with a channel that receives it once it
+// is ready, and a single sender passes them on in the order they were queued,
+// so a slow lookup delays the events behind it without blocking the reader.
+type diffPipeline struct {
+	es      *EventStream
+	ctx     context.Context
+	pending chan chan Event
+	done    chan struct{}
+}
+
+func (es *EventStream) newDiffPipeline(ctx context.Context, eventChan chan<- Event) *diffPipeline {
+	p := &diffPipeline{
+		es:      es,
+		ctx:     ctx,
+		pending: make(chan chan Event, diffPipelineSize),
+		done:    make(chan struct{}),
+	}
+	go p.send(eventChan)
+	return p
+}
+
+// add queues an event, starting the lookup of its diff if it is a job event.
+// It blocks only while the pipeline is full.
+func (p *diffPipeline) add(event Event) error {
+	ready := make(chan Event, 1)
+	if target, ok := jobDiffTargetFor(event); ok {
+		go func() {
+			ready <- p.es.withDiff(p.ctx, event, target)
+		}()
+	} else {
+		ready <- event
+	}
+
+	select {
+	case p.pending <- ready:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// send passes queued events on in order until the pipeline is closed
+func (p *diffPipeline) send(eventChan chan<- Event) {
+	defer close(p.done)
+
+	for ready := range p.pending {
+		var event Event
+		select {
+		case event = <-ready:
+		case <-p.ctx.Done():
+			return
+		}
+
+		select {
+		case eventChan <- event:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// close waits until the events already queued have been sent
+func (p *diffPipeline) close() {
+	close(p.pending)
+	<-p.done
+}
+
 func (es *EventStream) exponentialBackoff() {
 	es.retryBackoff = es.retryBackoff * 2
 	if es.retryBackoff > time.Minute {
@@ -214,35 +371,108 @@ func (es *EventStream) Client() *api.Client {
 	return es.client
 }
 
-// fetchJobDiff fetches the diff between the current job version and the previous version
-func (es *EventStream) fetchJobDiff(jobID string) (interface{}, error) {
+// jobDiffTarget identifies the job version a diff is fetched for
+type jobDiffTarget struct {
+	namespace string
+	jobID     string
+	version   uint64
+}
+
+// jobDiffTargetFor returns the job version to fetch a diff for. Registering,
+// stopping and batch deregistering a job each create a new version.
+func jobDiffTargetFor(event Event) (jobDiffTarget, bool) {
+	if event.Topic != "Job" {
+		return jobDiffTarget{}, false
+	}
+	switch event.Type {
+	case "JobRegistered", "JobDeregistered", "JobBatchDeregistered":
+	default:
+		return jobDiffTarget{}, false
+	}
+
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return jobDiffTarget{}, false
+	}
+	job, ok := payload["Job"].(map[string]interface{})
+	if !ok {
+		return jobDiffTarget{}, false
+	}
+
+	jobID, _ := job["ID"].(string)
+	version, ok := job["Version"].(float64)
+	if jobID == "" || !ok || version < 0 {
+		return jobDiffTarget{}, false
+	}
+	namespace, _ := job["Namespace"].(string)
+
+	return jobDiffTarget{namespace: namespace, jobID: jobID, version: uint64(version)}, true
+}
+
+// withDiff attaches the diff of a job version to an event. The event is
+// returned without a diff if the lookup fails or times out.
+func (es *EventStream) withDiff(ctx context.Context, event Event, target jobDiffTarget) Event {
+	diffCtx, cancel := context.WithTimeout(ctx, es.diffTimeout)
+	diff, err := es.fetchJobDiff(diffCtx, target)
+	cancel()
+
+	switch {
+	case IsNotFound(err):
+		// Purged jobs have no versions left to compare
+		slog.Debug("Job not found for diff", "job_id", target.jobID, "namespace", target.namespace)
+	case err != nil:
+		slog.Warn("Failed to fetch job diff", "job_id", target.jobID, "namespace", target.namespace, "version", target.version, "error", err)
+	case diff != nil:
+		event.Diff = diff
+	}
+	return event
+}
+
+// IsNotFound reports whether an error is a Nomad API response with a 404 status
+func IsNotFound(err error) bool {
+	var respErr api.UnexpectedResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode() == http.StatusNotFound
+}
+
+// fetchJobDiff fetches the diff between a job version and the version before
+// it. The first version of a job has no diff and returns nil.
+func (es *EventStream) fetchJobDiff(ctx context.Context, target jobDiffTarget) (map[string]interface{}, error) {
 	if es.client == nil {
 		return nil, fmt.Errorf("nomad client not available")
 	}
+	if target.jobID == "" {
+		return nil, fmt.Errorf("job ID is required")
+	}
 
-	// Get job versions with diffs enabled
-	_, diffs, _, err := es.client.Jobs().Versions(jobID, true, nil)
+	q := (&api.QueryOptions{Namespace: target.namespace}).WithContext(ctx)
+	versions, diffs, _, err := es.client.Jobs().Versions(target.jobID, true, q)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job versions: %w", err)
 	}
 
-	if len(diffs) == 0 {
-		return nil, fmt.Errorf("no job diffs available")
-	}
+	// Versions are returned newest first, and diffs[i] compares versions[i]
+	// with versions[i+1]
+	for i, job := range versions {
+		if job.Version == nil || *job.Version != target.version {
+			continue
+		}
+		if i >= len(diffs) {
+			return nil, nil
+		}
 
-	// Convert JobDiff struct to map for CEL compatibility
-	// This ensures that CEL expressions like has(event.Diff.Type) work correctly
-	diff := diffs[0]
-	diffBytes, err := json.Marshal(diff)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal job diff: %w", err)
-	}
+		// Convert JobDiff struct to map for CEL compatibility
+		// This ensures that CEL expressions like has(event.Diff.Type) work correctly
+		diffBytes, err := json.Marshal(diffs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal job diff: %w", err)
+		}
 
-	var diffMap map[string]interface{}
-	err = json.Unmarshal(diffBytes, &diffMap)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal job diff: %w", err)
+		var diffMap map[string]interface{}
+		if err := json.Unmarshal(diffBytes, &diffMap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job diff: %w", err)
+		}
+		return diffMap, nil
 	}
 
-	return diffMap, nil
+	return nil, fmt.Errorf("job version %d not found", target.version)
 }
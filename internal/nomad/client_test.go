@@ -1,7 +1,11 @@
 package nomad
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -211,90 +215,324 @@ func TestEventWithDiffSerialization(t *testing.T) {
 	assert.Equal(t, originalEvent.Diff, deserializedEvent.Diff)
 }
 
-func TestEventStreamFetchJobDiff(t *testing.T) {
-	// Note: This test checks the error handling of fetchJobDiff
-	// since we can't mock the Nomad API easily without significant refactoring
+// versionsServer serves job versions 2, 1 and 0 of "web" in the "prod"
+// namespace, with the diffs between them
+func versionsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/job/web/versions":
+			assert.Equal(t, "prod", r.URL.Query().Get("namespace"))
+			assert.Equal(t, "true", r.URL.Query().Get("diffs"))
+			w.Write([]byte(`{
+				"Versions": [{"ID": "web", "Version": 2}, {"ID": "web", "Version": 1}, {"ID": "web", "Version": 0}],
+				"Diffs": [
+					{"Type": "Edited", "ID": "web", "TaskGroups": [{"Type": "Edited", "Name": "web", "Fields": [{"Type": "Edited", "Name": "Count", "Old": "3", "New": "5"}]}]},
+					{"Type": "Edited", "ID": "web", "Fields": [{"Type": "Edited", "Name": "Priority", "Old": "50", "New": "60"}]}
+				]
+			}`))
+		case "/v1/job/slow/versions":
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("job not found"))
+		}
+	}))
+}
 
-	stream, err := NewEventStream(config.NomadConfig{
-		Address: "http://localhost:4646",
-		Token:   "",
-	})
+func testEventStream(t *testing.T, address string) *EventStream {
+	stream, err := NewEventStream(config.NomadConfig{Address: address})
 	require.NoError(t, err)
+	return stream
+}
+
+func TestEventStreamFetchJobDiff(t *testing.T) {
+	server := versionsServer(t)
+	defer server.Close()
+	stream := testEventStream(t, server.URL)
 
 	tests := []struct {
 		name      string
-		jobID     string
+		target    jobDiffTarget
+		expected  interface{}
 		expectErr bool
 	}{
 		{
-			name:      "empty job ID",
-			jobID:     "",
+			name:     "latest version",
+			target:   jobDiffTarget{namespace: "prod", jobID: "web", version: 2},
+			expected: "Count",
+		},
+		{
+			name:     "earlier version",
+			target:   jobDiffTarget{namespace: "prod", jobID: "web", version: 1},
+			expected: "Priority",
+		},
+		{
+			name:   "first version has no diff",
+			target: jobDiffTarget{namespace: "prod", jobID: "web", version: 0},
+		},
+		{
+			name:      "unknown version",
+			target:    jobDiffTarget{namespace: "prod", jobID: "web", version: 7},
 			expectErr: true,
 		},
 		{
-			name:      "valid job ID but no connection",
-			jobID:     "test-job",
-			expectErr: true, // Will fail since we don't have a real Nomad connection
+			name:      "missing job",
+			target:    jobDiffTarget{namespace: "prod", jobID: "gone", version: 1},
+			expectErr: true,
+		},
+		{
+			name:      "empty job ID",
+			target:    jobDiffTarget{namespace: "prod"},
+			expectErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			diff, err := stream.fetchJobDiff(tt.jobID)
-
+			diff, err := stream.fetchJobDiff(context.Background(), tt.target)
 			if tt.expectErr {
 				assert.Error(t, err)
 				assert.Nil(t, diff)
-			} else {
-				assert.NoError(t, err)
-				assert.NotNil(t, diff)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, diff)
+				return
 			}
+			assert.Contains(t, fmt.Sprint(diff), tt.expected)
 		})
 	}
 }
 
-func TestJobVersionHandling(t *testing.T) {
+func TestEventStreamWithDiff(t *testing.T) {
+	server := versionsServer(t)
+	defer server.Close()
+	stream := testEventStream(t, server.URL)
+	stream.diffTimeout = 50 * time.Millisecond
+
+	jobEvent := func(jobID string, version float64) Event {
+		return Event{
+			Topic: "Job",
+			Type:  "JobRegistered",
+			Payload: map[string]interface{}{
+				"Job": map[string]interface{}{"ID": jobID, "Namespace": "prod", "Version": version},
+			},
+		}
+	}
+
+	t.Run("attaches the diff", func(t *testing.T) {
+		event := jobEvent("web", 2)
+		target, ok := jobDiffTargetFor(event)
+		require.True(t, ok)
+
+		received := stream.withDiff(context.Background(), event, target)
+		require.NotNil(t, received.Diff)
+		assert.Equal(t, "count 3→5", DiffSummary(received.Diff))
+	})
+
+	t.Run("returns the event without a diff after the timeout", func(t *testing.T) {
+		event := jobEvent("slow", 2)
+		target, _ := jobDiffTargetFor(event)
+
+		received := stream.withDiff(context.Background(), event, target)
+		assert.Nil(t, received.Diff)
+		assert.Equal(t, "JobRegistered", received.Type)
+	})
+
+	t.Run("returns the event without a diff for purged jobs", func(t *testing.T) {
+		event := jobEvent("gone", 2)
+		target, _ := jobDiffTargetFor(event)
+
+		received := stream.withDiff(context.Background(), event, target)
+		assert.Nil(t, received.Diff)
+	})
+}
+
+func TestDiffPipeline(t *testing.T) {
+	server := versionsServer(t)
+	defer server.Close()
+	stream := testEventStream(t, server.URL)
+	stream.diffTimeout = 200 * time.Millisecond
+
+	jobEvent := func(jobID string) Event {
+		return Event{
+			Topic: "Job",
+			Type:  "JobRegistered",
+			Payload: map[string]interface{}{
+				"Job": map[string]interface{}{"ID": jobID, "Namespace": "prod", "Version": float64(2)},
+			},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventChan := make(chan Event, 10)
+	pipeline := stream.newDiffPipeline(ctx, eventChan)
+
+	// A slow Versions call does not keep the next events from being read
+	start := time.Now()
+	require.NoError(t, pipeline.add(jobEvent("slow")))
+	require.NoError(t, pipeline.add(Event{Topic: "Node", Type: "NodeRegistration", Key: "node-1"}))
+	require.NoError(t, pipeline.add(jobEvent("web")))
+	assert.Less(t, time.Since(start), stream.diffTimeout)
+
+	// Events are still sent in stream order, each once it is ready
+	pipeline.close()
+	require.Len(t, eventChan, 3)
+	slow, node, web := <-eventChan, <-eventChan, <-eventChan
+	assert.Nil(t, slow.Diff)
+	assert.Equal(t, "node-1", node.Key)
+	assert.Equal(t, "count 3→5", DiffSummary(web.Diff))
+}
+
+func TestIsNotFound(t *testing.T) {
+	server := versionsServer(t)
+	defer server.Close()
+	stream := testEventStream(t, server.URL)
+
+	_, err := stream.fetchJobDiff(context.Background(), jobDiffTarget{namespace: "prod", jobID: "gone", version: 1})
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+
+	_, err = stream.fetchJobDiff(context.Background(), jobDiffTarget{namespace: "prod", jobID: "web", version: 7})
+	require.Error(t, err)
+	assert.False(t, IsNotFound(err))
+	assert.False(t, IsNotFound(nil))
+}
+
+func TestJobDiffTargetFor(t *testing.T) {
+	job := map[string]interface{}{"ID": "web", "Namespace": "prod", "Version": float64(0)}
+
 	tests := []struct {
-		name           string
-		jobVersion     float64
-		expectDiffCall bool
+		name     string
+		event    Event
+		expected jobDiffTarget
+		ok       bool
 	}{
 		{
-			name:           "version 1 job should not fetch diff",
-			jobVersion:     1.0,
-			expectDiffCall: false,
+			name:     "registered first version",
+			event:    Event{Topic: "Job", Type: "JobRegistered", Payload: map[string]interface{}{"Job": job}},
+			expected: jobDiffTarget{namespace: "prod", jobID: "web", version: 0},
+			ok:       true,
 		},
 		{
-			name:           "version 2 job should fetch diff",
-			jobVersion:     2.0,
-			expectDiffCall: true,
+			name:     "deregistered",
+			event:    Event{Topic: "Job", Type: "JobDeregistered", Payload: map[string]interface{}{"Job": job}},
+			expected: jobDiffTarget{namespace: "prod", jobID: "web", version: 0},
+			ok:       true,
 		},
 		{
-			name:           "version 5 job should fetch diff",
-			jobVersion:     5.0,
-			expectDiffCall: true,
+			name:     "batch deregistered",
+			event:    Event{Topic: "Job", Type: "JobBatchDeregistered", Payload: map[string]interface{}{"Job": job}},
+			expected: jobDiffTarget{namespace: "prod", jobID: "web", version: 0},
+			ok:       true,
+		},
+		{
+			name:  "other job event",
+			event: Event{Topic: "Job", Type: "PlanResult", Payload: map[string]interface{}{"Job": job}},
+		},
+		{
+			name:  "purged job without payload",
+			event: Event{Topic: "Job", Type: "JobDeregistered", Payload: map[string]interface{}{}},
+		},
+		{
+			name:  "other topic",
+			event: Event{Topic: "Allocation", Type: "JobRegistered", Payload: map[string]interface{}{"Job": job}},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// This test verifies that we only attempt diff fetching for jobs with version > 1
-			// The actual logic is tested implicitly through the version check in the code
+			target, ok := jobDiffTargetFor(tt.event)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
 
-			jobPayload := map[string]interface{}{
-				"Job": map[string]interface{}{
-					"ID":      "test-job",
-					"Version": tt.jobVersion,
-				},
-			}
+func TestLookupField(t *testing.T) {
+	data := map[string]interface{}{
+		"Topic":   "Job",
+		"Payload": map[string]interface{}{"Job": map[string]interface{}{"ID": "web", "Stop": false}},
+	}
 
-			// Verify the version extraction works
-			if jobData, ok := jobPayload["Job"].(map[string]interface{}); ok {
-				if version, ok := jobData["Version"].(float64); ok {
-					shouldFetchDiff := version > 1
-					assert.Equal(t, tt.expectDiffCall, shouldFetchDiff)
-				}
-			}
+	tests := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{path: "Topic", value: "Job", found: true},
+		{path: ".Payload.Job.ID", value: "web", found: true},
+		{path: "Payload.Job.Stop", value: false, found: true},
+		{path: "Payload.Job.Missing"},
+		{path: "Topic.Job"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, found := LookupField(data, tt.path)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.value, value)
 		})
 	}
 }
+
+func TestEventTime(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	t.Run("uses payload ModifyTime", func(t *testing.T) {
+		event := Event{
+			Topic: "Allocation",
+			Payload: map[string]interface{}{
+				"Allocation": map[string]interface{}{
+					"ID":         "alloc-1",
+					"ModifyTime": float64(modified.UnixNano()),
+				},
+			},
+		}
+
+		got, ok := EventTime(event)
+		require.True(t, ok)
+		assert.WithinDuration(t, modified, got, time.Microsecond)
+	})
+
+	t.Run("prefers the object named after the topic", func(t *testing.T) {
+		event := Event{
+			Topic: "Deployment",
+			Payload: map[string]interface{}{
+				"Allocation": map[string]interface{}{"ModifyTime": float64(modified.Add(-time.Hour).UnixNano())},
+				"Deployment": map[string]interface{}{"ModifyTime": float64(modified.UnixNano())},
+				"Job":        map[string]interface{}{"ModifyTime": float64(modified.Add(time.Hour).UnixNano())},
+			},
+		}
+
+		for i := 0; i < 20; i++ {
+			got, ok := EventTime(event)
+			require.True(t, ok)
+			assert.WithinDuration(t, modified, got, time.Microsecond)
+		}
+
+		// Without one, objects are checked by name
+		event.Topic = "Node"
+		got, ok := EventTime(event)
+		require.True(t, ok)
+		assert.WithinDuration(t, modified.Add(-time.Hour), got, time.Microsecond)
+	})
+
+	t.Run("missing ModifyTime", func(t *testing.T) {
+		event := Event{
+			Topic:   "Node",
+			Payload: map[string]interface{}{"Node": map[string]interface{}{"ID": "node-1"}},
+		}
+
+		_, ok := EventTime(event)
+		assert.False(t, ok)
+	})
+
+	t.Run("nil payload", func(t *testing.T) {
+		_, ok := EventTime(Event{Topic: "Job"})
+		assert.False(t, ok)
+	})
+}
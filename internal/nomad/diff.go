@@ -0,0 +1,95 @@
+package nomad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffSummary renders the changes in a job diff as a short, comma separated
+// list, e.g. "count 3→5, image v1→v2". Fields are named without the task
+// group, task or block they belong to. Diffs without changes return "".
+func DiffSummary(diff interface{}) string {
+	d, ok := diff.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	var changes []string
+	seen := make(map[string]bool)
+	add := func(change string) {
+		if change != "" && !seen[change] {
+			seen[change] = true
+			changes = append(changes, change)
+		}
+	}
+
+	summarizeObject(d, add)
+	for _, group := range diffList(d, "TaskGroups") {
+		if change := summarizeAddedOrDeleted(group, "group"); change != "" {
+			add(change)
+			continue
+		}
+		summarizeObject(group, add)
+		for _, task := range diffList(group, "Tasks") {
+			if change := summarizeAddedOrDeleted(task, "task"); change != "" {
+				add(change)
+				continue
+			}
+			summarizeObject(task, add)
+		}
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// summarizeObject adds the field changes of a diff object and its nested objects
+func summarizeObject(object map[string]interface{}, add func(string)) {
+	for _, field := range diffList(object, "Fields") {
+		add(summarizeField(field))
+	}
+	for _, nested := range diffList(object, "Objects") {
+		summarizeObject(nested, add)
+	}
+}
+
+// summarizeAddedOrDeleted describes a task group or task that was added or
+// removed as a whole, rather than listing every field
+func summarizeAddedOrDeleted(object map[string]interface{}, kind string) string {
+	name, _ := object["Name"].(string)
+	switch object["Type"] {
+	case "Added":
+		return fmt.Sprintf("%s %s added", kind, name)
+	case "Deleted":
+		return fmt.Sprintf("%s %s removed", kind, name)
+	}
+	return ""
+}
+
+func summarizeField(field map[string]interface{}) string {
+	name, _ := field["Name"].(string)
+	name = strings.ToLower(name)
+	oldValue, _ := field["Old"].(string)
+	newValue, _ := field["New"].(string)
+
+	switch field["Type"] {
+	case "Edited":
+		return fmt.Sprintf("%s %s→%s", name, oldValue, newValue)
+	case "Added":
+		return fmt.Sprintf("%s %s added", name, newValue)
+	case "Deleted":
+		return fmt.Sprintf("%s %s removed", name, oldValue)
+	}
+	return ""
+}
+
+// diffList returns the objects in a list field of a diff
+func diffList(object map[string]interface{}, key string) []map[string]interface{} {
+	items, _ := object[key].([]interface{})
+	list := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			list = append(list, m)
+		}
+	}
+	return list
+}
@@ -0,0 +1,93 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     interface{}
+		expected string
+	}{
+		{
+			name: "task group and task changes",
+			diff: map[string]interface{}{
+				"Type": "Edited",
+				"TaskGroups": []interface{}{
+					map[string]interface{}{
+						"Type": "Edited",
+						"Name": "web",
+						"Fields": []interface{}{
+							map[string]interface{}{"Type": "Edited", "Name": "Count", "Old": "3", "New": "5"},
+						},
+						"Tasks": []interface{}{
+							map[string]interface{}{
+								"Type": "Edited",
+								"Name": "server",
+								"Objects": []interface{}{
+									map[string]interface{}{
+										"Type": "Edited",
+										"Name": "Config",
+										"Fields": []interface{}{
+											map[string]interface{}{"Type": "Edited", "Name": "image", "Old": "v1", "New": "v2"},
+											map[string]interface{}{"Type": "None", "Name": "command", "Old": "serve", "New": "serve"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: "count 3→5, image v1→v2",
+		},
+		{
+			name: "job fields and added or removed groups",
+			diff: map[string]interface{}{
+				"Type": "Edited",
+				"Fields": []interface{}{
+					map[string]interface{}{"Type": "Edited", "Name": "Stop", "Old": "false", "New": "true"},
+					map[string]interface{}{"Type": "Added", "Name": "Meta[owner]", "New": "payments"},
+				},
+				"TaskGroups": []interface{}{
+					map[string]interface{}{"Type": "Added", "Name": "cache"},
+					map[string]interface{}{"Type": "Deleted", "Name": "legacy"},
+				},
+			},
+			expected: "stop false→true, meta[owner] payments added, group cache added, group legacy removed",
+		},
+		{
+			name: "same change in several groups is listed once",
+			diff: map[string]interface{}{
+				"TaskGroups": []interface{}{
+					map[string]interface{}{"Type": "Edited", "Name": "a", "Fields": []interface{}{
+						map[string]interface{}{"Type": "Edited", "Name": "Count", "Old": "1", "New": "2"},
+					}},
+					map[string]interface{}{"Type": "Edited", "Name": "b", "Fields": []interface{}{
+						map[string]interface{}{"Type": "Edited", "Name": "Count", "Old": "1", "New": "2"},
+					}},
+				},
+			},
+			expected: "count 1→2",
+		},
+		{
+			name:     "no changes",
+			diff:     map[string]interface{}{"Type": "None"},
+			expected: "",
+		},
+		{
+			name:     "no diff",
+			diff:     nil,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DiffSummary(tt.diff))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package nomad
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// TailLogs returns the last lines of a task's log ("stdout" or "stderr"),
+// reading at most maxBytes from the end of the log
+func TailLogs(ctx context.Context, client *api.Client, alloc *api.Allocation, task, logType string, lines int, maxBytes int64) (string, error) {
+	cancel := make(chan struct{})
+	frames, errCh := client.AllocFS().Logs(alloc, false, task, logType, "end", maxBytes, cancel, (&api.QueryOptions{}).WithContext(ctx))
+	defer func() {
+		close(cancel)
+		// Unblock the reader if we gave up before the end of the log
+		if frames != nil {
+			go func() {
+				for range frames {
+				}
+			}()
+		}
+	}()
+
+	var buf bytes.Buffer
+	for {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				return LastLines(buf.String(), lines), nil
+			}
+			buf.Write(frame.Data)
+		case err := <-errCh:
+			return "", err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// LastLines returns the last n lines of text, without a trailing newline
+func LastLines(text string, n int) string {
+	text = strings.TrimRight(text, "\n")
+	if n <= 0 || text == "" {
+		return ""
+	}
+
+	end := len(text)
+	for i := 0; i < n; i++ {
+		idx := strings.LastIndexByte(text[:end], '\n')
+		if idx < 0 {
+			return text
+		}
+		end = idx
+	}
+	return text[end+1:]
+}
@@ -0,0 +1,28 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		lines    int
+		expected string
+	}{
+		{name: "fewer lines than requested", text: "one\ntwo\n", lines: 5, expected: "one\ntwo"},
+		{name: "last lines", text: "one\ntwo\nthree\nfour\n", lines: 2, expected: "three\nfour"},
+		{name: "without trailing newline", text: "one\ntwo", lines: 1, expected: "two"},
+		{name: "empty", text: "", lines: 3, expected: ""},
+		{name: "zero lines", text: "one\n", lines: 0, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, LastLines(tt.text, tt.lines))
+		})
+	}
+}
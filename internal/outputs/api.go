@@ -0,0 +1,26 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Stats are the delivery counters of an output
+type Stats struct {
+	Dropped uint64 `json:"dropped"` // Deliveries dropped without being sent
+}
+
+// droppedCounter is implemented by outputs and wrappers that drop deliveries,
+// such as SlackOutput for messages Slack rejects for good
+type droppedCounter interface {
+	Dropped() uint64
+}
+
+// Register adds GET /api/v1/outputs to a mux, returning the stats of each
+// output of the manager current returns, which changes on reload
+func Register(mux *http.ServeMux, current func() *Manager) {
+	mux.HandleFunc("GET /api/v1/outputs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(current().Stats())
+	})
+}
@@ -0,0 +1,49 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	rateLimited := &sendErrorOutput{err: &SlackError{Status: 429, Code: "rate_limited", retryAfter: time.Hour}}
+	newManager := func() *Manager {
+		return &Manager{
+			outputs: map[string]Output{
+				"slack":  NewGroupOutput(NewRetryOutput(rateLimited, RetryConfig{MaxRetries: 2}), GroupConfig{GroupBy: []string{"Topic"}}),
+				"stdout": &MockOutput{},
+			},
+			inherited: make(map[string]Stats),
+		}
+	}
+
+	previous := newManager()
+	defer previous.Close()
+	assert.Error(t, unwrap(previous.outputs["slack"]).Send(nomad.Event{Topic: "Test"}))
+
+	// Counts carry over to the outputs that replace them on reload
+	current := newManager()
+	defer current.Close()
+	current.Inherit(previous)
+
+	mux := http.NewServeMux()
+	Register(mux, func() *Manager { return current })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/outputs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var stats map[string]Stats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, map[string]Stats{"slack": {Dropped: 1}, "stdout": {}}, stats)
+}
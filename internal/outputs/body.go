@@ -0,0 +1,432 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats a body template's output is parsed as, set with body.format
+const (
+	BodyFormatJSON = "json" // Default
+	BodyFormatYAML = "yaml"
+)
+
+// Content types a body is encoded as, set with body.content_type
+const (
+	BodyContentJSON = "json" // application/json (default)
+	BodyContentForm = "form" // application/x-www-form-urlencoded
+	BodyContentText = "text" // text/plain
+)
+
+var bodyMediaTypes = map[string]string{
+	BodyContentJSON: "application/json",
+	BodyContentForm: "application/x-www-form-urlencoded",
+	BodyContentText: "text/plain; charset=utf-8",
+}
+
+// outputBody shapes the body of HTTP, RabbitMQ and exec deliveries. The body is
+// a document built by a template, a CEL projection or a field allowlist, and
+// encoded as JSON, form fields or plain text. A template that produces an
+// invalid document fails the delivery instead of sending it.
+type outputBody struct {
+	text        string // Template
+	format      string
+	projection  cel.Program
+	fields      [][]string
+	contentType string
+	engine      *template.Engine
+	onError     templateErrorPolicy
+}
+
+// newOutputBody reads an output's body settings, compiling its template with
+// the output's engine. Outputs without a body send the event itself as JSON,
+// and so do deliveries whose body template fails with on_template_error: fallback.
+func newOutputBody(config map[string]interface{}, engine *template.Engine, onError templateErrorPolicy) (*outputBody, error) {
+	value, ok := config["body"]
+	if !ok {
+		return nil, nil
+	}
+
+	settings, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("body must be a map with a template, cel or fields setting")
+	}
+
+	body := &outputBody{engine: engine, onError: onError}
+
+	body.contentType, _ = settings["content_type"].(string)
+	if body.contentType == "" {
+		body.contentType = BodyContentJSON
+	}
+	if _, ok := bodyMediaTypes[body.contentType]; !ok {
+		return nil, fmt.Errorf("invalid body.content_type %q: must be one of json, form, text", body.contentType)
+	}
+
+	var modes []string
+	for _, mode := range []string{"template", "cel", "fields"} {
+		if _, ok := settings[mode]; ok {
+			modes = append(modes, mode)
+		}
+	}
+	if len(modes) != 1 {
+		return nil, fmt.Errorf("body must have exactly one of template, cel or fields")
+	}
+
+	switch modes[0] {
+	case "template":
+		body.text, _ = settings["template"].(string)
+		if body.text == "" {
+			return nil, fmt.Errorf("body.template must be a non-empty template")
+		}
+
+		body.format, _ = settings["format"].(string)
+		switch body.format {
+		case "":
+			body.format = BodyFormatJSON
+		case BodyFormatJSON, BodyFormatYAML:
+		default:
+			return nil, fmt.Errorf("invalid body.format %q: must be one of json, yaml", body.format)
+		}
+
+		if err := engine.Compile(body.text); err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+
+	case "cel":
+		expression, _ := settings["cel"].(string)
+		program, err := compileProjection(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body.cel: %w", err)
+		}
+		body.projection = program
+
+	case "fields":
+		fields, _ := settings["fields"].([]interface{})
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("body.fields must be a list of field paths such as \"Payload.Job.ID\"")
+		}
+		for i, field := range fields {
+			path, _ := field.(string)
+			if path == "" {
+				return nil, fmt.Errorf("body.fields[%d] must be a field path such as \"Payload.Job.ID\"", i)
+			}
+			body.fields = append(body.fields, strings.Split(path, "."))
+		}
+	}
+
+	if _, ok := settings["format"]; ok && body.text == "" {
+		return nil, fmt.Errorf("body.format only applies to body templates")
+	}
+
+	return body, nil
+}
+
+// compileProjection compiles a CEL expression over the `event` variable, as
+// exposed to route filters
+func compileProjection(expression string) (cel.Program, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return env.Program(ast)
+}
+
+// mediaType is the Content-Type of an output's body
+func (b *outputBody) mediaType() string {
+	if b == nil {
+		return bodyMediaTypes[BodyContentJSON]
+	}
+	return bodyMediaTypes[b.contentType]
+}
+
+// encode builds the body for an event or a Batch, or marshals it as JSON when
+// there is no body setting. Templates render with data; projections and
+// allowlists apply to each event of a batch. deliver is false when the
+// on_template_error policy skips the delivery.
+func (b *outputBody) encode(data func() map[string]interface{}, value interface{}) (body []byte, deliver bool, _ error) {
+	if b == nil {
+		return marshalBody(value)
+	}
+
+	if b.text != "" {
+		text, err := b.engine.ProcessTextWithData(b.text, data())
+		if err != nil {
+			return b.handle(err, value)
+		}
+
+		// Plain text bodies are sent as rendered
+		if b.contentType == BodyContentText {
+			return []byte(text), true, nil
+		}
+
+		document, err := parseBody(text, b.format)
+		if err != nil {
+			return nil, false, fmt.Errorf("body template rendered invalid %s: %w", strings.ToUpper(b.format), err)
+		}
+		return b.encodeDocument(document)
+	}
+
+	var document interface{}
+	switch v := value.(type) {
+	case nomad.Event:
+		projected, err := b.project(v)
+		if err != nil {
+			return nil, false, err
+		}
+		document = projected
+	case Batch:
+		events := make([]interface{}, len(v.Events))
+		for i, event := range v.Events {
+			projected, err := b.project(event)
+			if err != nil {
+				return nil, false, err
+			}
+			events[i] = projected
+		}
+		document = map[string]interface{}{"GroupLabels": v.GroupLabels, "Events": events}
+	default:
+		return nil, false, fmt.Errorf("unsupported body value %T", value)
+	}
+
+	return b.encodeDocument(document)
+}
+
+// handle applies the on_template_error policy to a body template that failed
+// to render. Bodies fall back to the event itself rather than fallback_template.
+func (b *outputBody) handle(err error, value interface{}) ([]byte, bool, error) {
+	var tmplErr *template.Error
+	if !errors.As(err, &tmplErr) || b.onError.action == TemplateErrorFail {
+		return nil, false, fmt.Errorf("failed to render body template: %w", err)
+	}
+
+	if b.onError.action == TemplateErrorSkip {
+		slog.Warn("Body template failed to render, skipping delivery", "error", err)
+		return nil, false, nil
+	}
+
+	slog.Warn("Body template failed to render, delivering the event instead", "error", err)
+	return marshalBody(value)
+}
+
+// project applies the CEL projection or field allowlist to an event
+func (b *outputBody) project(event nomad.Event) (interface{}, error) {
+	if b.projection != nil {
+		result, _, err := b.projection.Eval(map[string]interface{}{"event": nomad.EventMap(event)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate body.cel: %w", err)
+		}
+		return celToNative(result), nil
+	}
+
+	// Fields are picked from the event's JSON, so paths match the default body
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event to JSON: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var full map[string]interface{}
+	if err := decoder.Decode(&full); err != nil {
+		return nil, fmt.Errorf("failed to decode event JSON: %w", err)
+	}
+
+	picked := map[string]interface{}{}
+	for _, path := range b.fields {
+		pickField(full, picked, path)
+	}
+	return picked, nil
+}
+
+// pickField copies the value at path from src to dst, creating the maps
+// along the path. Missing fields are left out.
+func pickField(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+
+	next, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	child, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+	}
+	pickField(next, child, path[1:])
+	if len(child) > 0 {
+		dst[path[0]] = child
+	}
+}
+
+// encodeDocument encodes a body document in the output's content type
+func (b *outputBody) encodeDocument(document interface{}) ([]byte, bool, error) {
+	switch b.contentType {
+	case BodyContentForm:
+		fields, ok := document.(map[string]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("form bodies must be an object, not %T", document)
+		}
+		values := url.Values{}
+		flattenForm(values, "", fields)
+		return []byte(values.Encode()), true, nil
+
+	case BodyContentText:
+		if text, ok := document.(string); ok {
+			return []byte(text), true, nil
+		}
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		return nil, false, fmt.Errorf("the body cannot be encoded as JSON: %w", err)
+	}
+	return body, true, nil
+}
+
+// flattenForm adds a document's fields as form values, naming nested fields
+// with dotted paths such as Job.Meta.owner or Events.0.Key
+func flattenForm(values url.Values, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flattenForm(values, join(key), v[key])
+		}
+	case map[string]string:
+		for key, s := range v {
+			values.Set(join(key), s)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenForm(values, join(strconv.Itoa(i)), item)
+		}
+	case nil:
+		values.Set(prefix, "")
+	case string:
+		values.Set(prefix, v)
+	default:
+		encoded, _ := json.Marshal(v)
+		values.Set(prefix, string(encoded))
+	}
+}
+
+// marshalBody is the body of outputs without a body setting
+func marshalBody(value interface{}) ([]byte, bool, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal event to JSON: %w", err)
+	}
+	return body, true, nil
+}
+
+// parseBody parses a body template's rendered text as a JSON or YAML document
+func parseBody(text, format string) (interface{}, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("the template rendered an empty document")
+	}
+
+	var document interface{}
+	if format == BodyFormatJSON {
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		err := decoder.Decode(&document)
+		if err == nil && decoder.More() {
+			err = fmt.Errorf("unexpected content after the document")
+		}
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, column := position(text, syntaxErr.Offset)
+				return nil, fmt.Errorf("line %d, column %d: %w", line, column, err)
+			}
+			return nil, err
+		}
+		return document, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// position finds the line and column, both from 1, of the byte a JSON syntax
+// error's offset points just past
+func position(text string, offset int64) (line, column int) {
+	if offset > int64(len(text)) {
+		offset = int64(len(text))
+	}
+
+	before := text[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = len(before) - (strings.LastIndex(before, "\n") + 1)
+	return line, column
+}
+
+// celToNative converts a CEL value to the maps, lists and scalars it is
+// encoded from as JSON
+func celToNative(value ref.Val) interface{} {
+	switch v := value.(type) {
+	case traits.Mapper:
+		native := map[string]interface{}{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			native[fmt.Sprint(key.Value())] = celToNative(v.Get(key))
+		}
+		return native
+	case traits.Lister:
+		native := []interface{}{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			native = append(native, celToNative(it.Next()))
+		}
+		return native
+	case types.Null:
+		return nil
+	default:
+		return value.Value()
+	}
+}
@@ -0,0 +1,336 @@
+package outputs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		format   string
+		expected string
+		errorMsg string
+	}{
+		{
+			name:     "json",
+			text:     "{\n  \"job\": \"web\",\n  \"count\": 3\n}\n",
+			format:   BodyFormatJSON,
+			expected: `{"job":"web","count":3}`,
+		},
+		{
+			name:     "invalid json names the position",
+			text:     "{\n  \"message\": \"said \"hi\"\"\n}",
+			format:   BodyFormatJSON,
+			errorMsg: "line 2, column 21: invalid character 'h'",
+		},
+		{
+			name:     "yaml is encoded as json",
+			text:     "job: web\nmessage: |\n  line one\n  line \"two\"\ntags: [a, b]\n",
+			format:   BodyFormatYAML,
+			expected: `{"job":"web","message":"line one\nline \"two\"\n","tags":["a","b"]}`,
+		},
+		{
+			name:     "invalid yaml",
+			text:     "job: web\n  message: [unclosed\n",
+			format:   BodyFormatYAML,
+			errorMsg: "yaml: line",
+		},
+		{
+			name:     "trailing content",
+			text:     `{"job": "web"} {"job": "api"}`,
+			format:   BodyFormatJSON,
+			errorMsg: "unexpected content after the document",
+		},
+		{
+			name:     "empty document",
+			text:     "  \n",
+			format:   BodyFormatYAML,
+			errorMsg: "rendered an empty document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := parseBody(tt.text, tt.format)
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			body, err := json.Marshal(document)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestNewOutputBodyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     interface{}
+		errorMsg string
+	}{
+		{name: "not a map", body: "{{ .Topic }}", errorMsg: "body must be a map with a template, cel or fields setting"},
+		{name: "no mode", body: map[string]interface{}{"format": "yaml"}, errorMsg: "body must have exactly one of template, cel or fields"},
+		{name: "two modes", body: map[string]interface{}{"template": "{}", "cel": "event"}, errorMsg: "body must have exactly one of template, cel or fields"},
+		{name: "empty template", body: map[string]interface{}{"template": ""}, errorMsg: "body.template must be a non-empty template"},
+		{name: "invalid format", body: map[string]interface{}{"template": "{}", "format": "xml"}, errorMsg: `invalid body.format "xml"`},
+		{name: "invalid template", body: map[string]interface{}{"template": "{{ .Topic"}, errorMsg: "invalid body template"},
+		{name: "invalid cel", body: map[string]interface{}{"cel": "event.Topic +"}, errorMsg: "invalid body.cel"},
+		{name: "invalid fields", body: map[string]interface{}{"fields": []interface{}{"Topic", 3}}, errorMsg: "body.fields[1] must be a field path"},
+		{name: "format without template", body: map[string]interface{}{"fields": []interface{}{"Topic"}, "format": "yaml"}, errorMsg: "body.format only applies to body templates"},
+		{name: "invalid content type", body: map[string]interface{}{"template": "{}", "content_type": "xml"}, errorMsg: `invalid body.content_type "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPOutput(map[string]interface{}{"url": "http://localhost", "body": tt.body})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+
+	// Configuration errors name the output
+	_, err := NewManager(map[string]config.Output{
+		"webhook": {Type: "http", Properties: map[string]interface{}{
+			"url":  "http://localhost",
+			"body": map[string]interface{}{"template": "{{ .Topic"},
+		}},
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to create output "webhook": invalid body template`)
+}
+
+func TestHTTPOutputBodyTemplate(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	newOutput := func(body map[string]interface{}, onError string) *HTTPOutput {
+		output, err := NewHTTPOutput(map[string]interface{}{
+			"url":               server.URL,
+			"body":              body,
+			"on_template_error": onError,
+		})
+		require.NoError(t, err)
+		return output
+	}
+
+	event := nomad.Event{
+		Topic: "Job",
+		Type:  "JobRegistered",
+		Key:   "web",
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web", "Meta": map[string]interface{}{"note": "say \"hi\"\nthen leave"}},
+		},
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		received = nil
+		output := newOutput(map[string]interface{}{
+			"format":   "yaml",
+			"template": "job: {{ .Payload.Job.ID }}\nnote: {{ .Payload.Job.Meta.note | toJson }}\n",
+		}, "")
+		require.NoError(t, output.Send(event))
+		require.Len(t, received, 1)
+		assert.JSONEq(t, `{"job":"web","note":"say \"hi\"\nthen leave"}`, received[0])
+	})
+
+	t.Run("invalid json is not sent", func(t *testing.T) {
+		received = nil
+		output := newOutput(map[string]interface{}{
+			"template": `{"job": "{{ .Payload.Job.ID }}", "note": "{{ .Payload.Job.Meta.note }}"}`,
+		}, "")
+		err := output.Send(event)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "body template rendered invalid JSON: line 1")
+		assert.Empty(t, received)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		received = nil
+		output := newOutput(map[string]interface{}{
+			"template": `{"count": {{ .Count }}, "keys": [{{ range $i, $e := .Events }}{{ if $i }},{{ end }}{{ $e.Key | toJson }}{{ end }}]}`,
+		}, "")
+		require.NoError(t, output.SendBatch(Batch{Events: []nomad.Event{event, {Key: "api"}}}))
+		require.Len(t, received, 1)
+		assert.JSONEq(t, `{"count":2,"keys":["web","api"]}`, received[0])
+	})
+
+	// Deployment events have no .Payload.Job, so indexing it fails to render
+	failing := map[string]interface{}{"template": `{"owner": {{ index .Payload.Job.Meta "owner" | toJson }}}`}
+	deployment := nomad.Event{Topic: "Deployment", Type: "DeploymentStatusUpdate", Key: "deploy-1", Payload: map[string]interface{}{}}
+
+	t.Run("fallback sends the event", func(t *testing.T) {
+		received = nil
+		require.NoError(t, newOutput(failing, "").Send(deployment))
+		require.Len(t, received, 1)
+		assert.Contains(t, received[0], `"Topic":"Deployment"`)
+	})
+
+	t.Run("skip and fail", func(t *testing.T) {
+		received = nil
+		require.NoError(t, newOutput(failing, "skip").Send(deployment))
+
+		err := newOutput(failing, "fail").Send(deployment)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to render body template")
+		assert.Empty(t, received)
+	})
+}
+
+func TestExecOutputBodyTemplate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping exec tests on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "stdin.json")
+	output, err := NewExecOutput(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", "cat > " + path},
+		"body": map[string]interface{}{
+			"format":   "yaml",
+			"template": "topic: {{ .Topic }}\nkey: {{ .Key | toJson }}",
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, output.Send(nomad.Event{Topic: "Node", Key: "node: 1"}))
+
+	stdin, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"topic":"Node","key":"node: 1"}`, string(stdin))
+}
+
+func TestHTTPOutputBodyShaping(t *testing.T) {
+	type request struct {
+		contentType string
+		body        string
+	}
+	var received []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, request{contentType: r.Header.Get("Content-Type"), body: string(body)})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	event := nomad.Event{
+		Topic: "Job",
+		Type:  "JobRegistered",
+		Key:   "web",
+		Index: 42,
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{
+				"ID":     "web",
+				"Status": "running",
+				"Meta":   map[string]interface{}{"owner": "team-a", "token": "secret"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		body        map[string]interface{}
+		contentType string
+		expected    string
+	}{
+		{
+			name:        "fields",
+			body:        map[string]interface{}{"fields": []interface{}{"Type", "Index", "Payload.Job.ID", "Payload.Job.Meta.owner", "Payload.Missing.ID"}},
+			contentType: "application/json",
+			expected:    `{"Type":"JobRegistered","Index":42,"Payload":{"Job":{"ID":"web","Meta":{"owner":"team-a"}}}}`,
+		},
+		{
+			name:        "cel",
+			body:        map[string]interface{}{"cel": `{"job": event.Payload.Job.ID, "running": event.Payload.Job.Status == "running", "tags": [event.Topic, event.Type]}`},
+			contentType: "application/json",
+			expected:    `{"job":"web","running":true,"tags":["Job","JobRegistered"]}`,
+		},
+		{
+			name:        "form",
+			body:        map[string]interface{}{"cel": `{"job": event.Payload.Job.ID, "meta": {"owner": event.Payload.Job.Meta.owner}, "index": event.Index}`, "content_type": "form"},
+			contentType: "application/x-www-form-urlencoded",
+			expected:    "index=42&job=web&meta.owner=team-a",
+		},
+		{
+			name:        "form from a template",
+			body:        map[string]interface{}{"template": "text: {{ .Key }} {{ .Type }}", "format": "yaml", "content_type": "form"},
+			contentType: "application/x-www-form-urlencoded",
+			expected:    "text=web+JobRegistered",
+		},
+		{
+			name:        "text template",
+			body:        map[string]interface{}{"template": "{{ .Key }} was registered\n", "content_type": "text"},
+			contentType: "text/plain; charset=utf-8",
+			expected:    "web was registered\n",
+		},
+		{
+			name:        "text from cel",
+			body:        map[string]interface{}{"cel": `event.Key + " is " + event.Payload.Job.Status`, "content_type": "text"},
+			contentType: "text/plain; charset=utf-8",
+			expected:    "web is running",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			output, err := NewHTTPOutput(map[string]interface{}{"url": server.URL, "body": tt.body})
+			require.NoError(t, err)
+
+			require.NoError(t, output.Send(event))
+			require.Len(t, received, 1)
+			assert.Equal(t, tt.contentType, received[0].contentType)
+			if tt.contentType == "application/json" {
+				assert.JSONEq(t, tt.expected, received[0].body)
+			} else {
+				assert.Equal(t, tt.expected, received[0].body)
+			}
+		})
+	}
+
+	t.Run("projections apply to each event of a batch", func(t *testing.T) {
+		received = nil
+		output, err := NewHTTPOutput(map[string]interface{}{
+			"url":  server.URL,
+			"body": map[string]interface{}{"fields": []interface{}{"Key"}},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, output.SendBatch(Batch{GroupLabels: map[string]string{"Topic": "Job"}, Events: []nomad.Event{event, {Key: "api"}}}))
+		require.Len(t, received, 1)
+		assert.JSONEq(t, `{"GroupLabels":{"Topic":"Job"},"Events":[{"Key":"web"},{"Key":"api"}]}`, received[0].body)
+	})
+
+	t.Run("form bodies must be objects", func(t *testing.T) {
+		output, err := NewHTTPOutput(map[string]interface{}{
+			"url":  server.URL,
+			"body": map[string]interface{}{"cel": "[event.Key]", "content_type": "form"},
+		})
+		require.NoError(t, err)
+
+		err = output.Send(event)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "form bodies must be an object")
+	})
+}
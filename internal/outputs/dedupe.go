@@ -0,0 +1,41 @@
+package outputs
+
+import (
+	"errors"
+	"log/slog"
+
+	"nomad-events/internal/dedupe"
+	"nomad-events/internal/nomad"
+)
+
+// DedupeOutput wraps another Output and drops events already delivered within the dedupe window
+type DedupeOutput struct {
+	output       Output
+	deduplicator *dedupe.Deduplicator
+}
+
+// NewDedupeOutput creates a new DedupeOutput wrapper
+func NewDedupeOutput(output Output, deduplicator *dedupe.Deduplicator) *DedupeOutput {
+	return &DedupeOutput{
+		output:       output,
+		deduplicator: deduplicator,
+	}
+}
+
+// Send implements the Output interface, suppressing duplicate events
+func (d *DedupeOutput) Send(event nomad.Event) error {
+	if d.deduplicator.IsDuplicate(event) {
+		slog.Debug("Suppressed duplicate event",
+			"topic", event.Topic,
+			"type", event.Type,
+			"key", event.Key)
+		return nil
+	}
+
+	return d.output.Send(event)
+}
+
+// Close closes the wrapped output and writes the keys seen
+func (d *DedupeOutput) Close() error {
+	return errors.Join(closeOutput(d.output), d.deduplicator.Close())
+}
@@ -0,0 +1,53 @@
+package outputs
+
+import (
+	"testing"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/dedupe"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeOutput(t *testing.T) {
+	deduplicator, err := dedupe.New(config.DedupeConfig{Key: "{{ .Key }}-{{ .Type }}", TTL: "1m"})
+	require.NoError(t, err)
+
+	mock := &MockOutput{}
+	output := NewDedupeOutput(mock, deduplicator)
+
+	event := nomad.Event{Topic: "Allocation", Type: "AllocationUpdated", Key: "alloc-1"}
+
+	assert.NoError(t, output.Send(event))
+	assert.NoError(t, output.Send(event))
+	assert.Equal(t, 1, mock.sendCalls, "duplicate should be suppressed")
+
+	event.Type = "AllocationCreated"
+	assert.NoError(t, output.Send(event))
+	assert.Equal(t, 2, mock.sendCalls)
+}
+
+func TestCreateOutputWithDedupe(t *testing.T) {
+	t.Run("wraps output", func(t *testing.T) {
+		output, err := createOutput(config.Output{
+			Type:   "stdout",
+			Retry:  &config.RetryConfig{MaxRetries: 2},
+			Dedupe: &config.DedupeConfig{Key: "{{ .Key }}", TTL: "30s"},
+		}, nil)
+		require.NoError(t, err)
+		assert.IsType(t, &DedupeOutput{}, output)
+		assert.IsType(t, &RetryOutput{}, output.(*DedupeOutput).output)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		output, err := createOutput(config.Output{
+			Type:   "stdout",
+			Dedupe: &config.DedupeConfig{Key: "{{ .Key }}", TTL: "later"},
+		}, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid dedupe configuration")
+		assert.Nil(t, output)
+	})
+}
@@ -3,20 +3,22 @@ package outputs
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os/exec"
 	"strings"
 	"time"
 
 	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
 )
 
 type ExecOutput struct {
-	command []string
-	timeout time.Duration
-	workdir string
-	env     []string
+	command        []string
+	timeout        time.Duration
+	workdir        string
+	env            []string
+	templateEngine *template.Engine
+	body           *outputBody
 }
 
 func NewExecOutput(config map[string]interface{}) (*ExecOutput, error) {
@@ -64,18 +66,32 @@ func NewExecOutput(config map[string]interface{}) (*ExecOutput, error) {
 		}
 	}
 
+	templateEngine := template.NewEngine()
+	onError, err := newTemplateErrorPolicy(config, templateEngine, DefaultFallbackTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := newOutputBody(config, templateEngine, onError)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ExecOutput{
-		command: command,
-		timeout: timeout,
-		workdir: workdir,
-		env:     env,
+		command:        command,
+		timeout:        timeout,
+		workdir:        workdir,
+		env:            env,
+		templateEngine: templateEngine,
+		body:           body,
 	}, nil
 }
 
 func (o *ExecOutput) Send(event nomad.Event) error {
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event to JSON: %w", err)
+	eventJSON, deliver, err := o.body.encode(func() map[string]interface{} {
+		return o.templateEngine.CreateTemplateData(event)
+	}, event)
+	if err != nil || !deliver {
+		return err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
@@ -0,0 +1,177 @@
+package outputs
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+)
+
+// GroupConfig holds configuration for grouping events into batches
+type GroupConfig struct {
+	GroupBy       []string
+	GroupWait     time.Duration
+	GroupInterval time.Duration
+	MaxBatch      int
+}
+
+// GroupOutput wraps another Output and delivers events as AlertManager-style
+// digests: events are collected per group and sent as a single Batch after
+// GroupWait, then every GroupInterval while new events keep arriving.
+type GroupOutput struct {
+	output         Output
+	config         GroupConfig
+	templateEngine *template.Engine
+
+	mu     sync.Mutex
+	groups map[string]*eventGroup
+	closed bool
+}
+
+type eventGroup struct {
+	labels map[string]string
+	events []nomad.Event
+	timer  *time.Timer
+}
+
+// NewGroupOutput creates a new GroupOutput wrapper
+func NewGroupOutput(output Output, config GroupConfig) *GroupOutput {
+	// Set defaults if not specified
+	if config.GroupWait == 0 {
+		config.GroupWait = 30 * time.Second
+	}
+	if config.GroupInterval == 0 {
+		config.GroupInterval = 5 * time.Minute
+	}
+
+	return &GroupOutput{
+		output:         output,
+		config:         config,
+		templateEngine: template.NewEngine(),
+		groups:         make(map[string]*eventGroup),
+	}
+}
+
+// Send implements the Output interface by adding the event to its group
+func (g *GroupOutput) Send(event nomad.Event) error {
+	labels := g.groupLabels(event)
+	key := groupKey(labels)
+
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return sendBatch(g.output, Batch{GroupLabels: labels, Events: []nomad.Event{event}})
+	}
+
+	group, exists := g.groups[key]
+	if !exists {
+		group = &eventGroup{labels: labels}
+		group.timer = time.AfterFunc(g.config.GroupWait, func() { g.flush(key, group) })
+		g.groups[key] = group
+	}
+
+	group.events = append(group.events, event)
+
+	// A full batch is delivered straight away; the timer keeps running for anything that follows
+	if g.config.MaxBatch > 0 && len(group.events) >= g.config.MaxBatch {
+		batch := Batch{GroupLabels: group.labels, Events: group.events}
+		group.events = nil
+		g.mu.Unlock()
+		return sendBatch(g.output, batch)
+	}
+
+	g.mu.Unlock()
+	return nil
+}
+
+// flush delivers the pending events of a group and schedules the next
+// delivery, or forgets the group if nothing arrived since the last one
+func (g *GroupOutput) flush(key string, group *eventGroup) {
+	g.mu.Lock()
+	if g.closed || g.groups[key] != group {
+		g.mu.Unlock()
+		return
+	}
+
+	if len(group.events) == 0 {
+		delete(g.groups, key)
+		g.mu.Unlock()
+		return
+	}
+
+	batch := Batch{GroupLabels: group.labels, Events: group.events}
+	group.events = nil
+	group.timer = time.AfterFunc(g.config.GroupInterval, func() { g.flush(key, group) })
+	g.mu.Unlock()
+
+	g.deliver(batch)
+}
+
+func (g *GroupOutput) deliver(batch Batch) {
+	if err := sendBatch(g.output, batch); err != nil {
+		slog.Error("Failed to send event batch",
+			"error", err,
+			"group_labels", batch.GroupLabels,
+			"events", len(batch.Events))
+	}
+}
+
+// Close delivers all pending groups and closes the wrapped output
+func (g *GroupOutput) Close() error {
+	g.mu.Lock()
+	g.closed = true
+	var pending []Batch
+	for key, group := range g.groups {
+		group.timer.Stop()
+		if len(group.events) > 0 {
+			pending = append(pending, Batch{GroupLabels: group.labels, Events: group.events})
+		}
+		delete(g.groups, key)
+	}
+	g.mu.Unlock()
+
+	for _, batch := range pending {
+		g.deliver(batch)
+	}
+
+	return closeOutput(g.output)
+}
+
+// groupLabels resolves the configured group_by fields against the event
+func (g *GroupOutput) groupLabels(event nomad.Event) map[string]string {
+	labels := make(map[string]string, len(g.config.GroupBy))
+	if len(g.config.GroupBy) == 0 {
+		return labels
+	}
+
+	data := g.templateEngine.CreateTemplateData(event)
+	for _, field := range g.config.GroupBy {
+		if value, ok := nomad.LookupField(data, field); ok && value != nil {
+			labels[field] = fmt.Sprint(value)
+		} else {
+			labels[field] = ""
+		}
+	}
+
+	return labels
+}
+
+// groupKey builds a stable identifier for a set of group labels
+func groupKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, labels[name])
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package outputs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBatchOutput records batches delivered by GroupOutput
+type MockBatchOutput struct {
+	mu      sync.Mutex
+	batches []Batch
+	closed  bool
+}
+
+func (m *MockBatchOutput) Send(event nomad.Event) error {
+	return m.SendBatch(Batch{Events: []nomad.Event{event}})
+}
+
+func (m *MockBatchOutput) SendBatch(batch Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func (m *MockBatchOutput) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *MockBatchOutput) Batches() []Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Batch(nil), m.batches...)
+}
+
+func jobEvent(jobID, eventType string) nomad.Event {
+	return nomad.Event{
+		Topic: "Job",
+		Type:  eventType,
+		Key:   jobID,
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": jobID},
+		},
+	}
+}
+
+func TestGroupOutput(t *testing.T) {
+	t.Run("groups events by labels after group_wait", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewGroupOutput(mock, GroupConfig{
+			GroupBy:       []string{"Topic", "Payload.Job.ID"},
+			GroupWait:     20 * time.Millisecond,
+			GroupInterval: time.Hour,
+		})
+		defer output.Close()
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		require.NoError(t, output.Send(jobEvent("web", "JobDeregistered")))
+		require.NoError(t, output.Send(jobEvent("api", "JobRegistered")))
+		assert.Empty(t, mock.Batches(), "nothing is delivered before group_wait")
+
+		assert.Eventually(t, func() bool { return len(mock.Batches()) == 2 }, time.Second, 5*time.Millisecond)
+
+		byJob := map[string]Batch{}
+		for _, batch := range mock.Batches() {
+			byJob[batch.GroupLabels["Payload.Job.ID"]] = batch
+		}
+		assert.Len(t, byJob["web"].Events, 2)
+		assert.Len(t, byJob["api"].Events, 1)
+		assert.Equal(t, "Job", byJob["web"].GroupLabels["Topic"])
+	})
+
+	t.Run("later events wait for group_interval", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewGroupOutput(mock, GroupConfig{
+			GroupWait:     10 * time.Millisecond,
+			GroupInterval: 50 * time.Millisecond,
+		})
+		defer output.Close()
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		assert.Eventually(t, func() bool { return len(mock.Batches()) == 1 }, time.Second, 5*time.Millisecond)
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		require.NoError(t, output.Send(jobEvent("api", "JobRegistered")))
+		time.Sleep(20 * time.Millisecond)
+		assert.Len(t, mock.Batches(), 1, "existing group waits for group_interval")
+
+		assert.Eventually(t, func() bool { return len(mock.Batches()) == 2 }, time.Second, 5*time.Millisecond)
+		assert.Len(t, mock.Batches()[1].Events, 2)
+	})
+
+	t.Run("max_batch delivers immediately", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewGroupOutput(mock, GroupConfig{
+			GroupWait: time.Hour,
+			MaxBatch:  2,
+		})
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		assert.Empty(t, mock.Batches())
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		assert.Len(t, mock.Batches(), 1)
+		assert.Len(t, mock.Batches()[0].Events, 2)
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		require.NoError(t, output.Close())
+		assert.Len(t, mock.Batches(), 2, "close flushes pending events")
+		assert.True(t, mock.closed)
+	})
+
+	t.Run("falls back to individual sends", func(t *testing.T) {
+		mock := &MockOutput{}
+		output := NewGroupOutput(mock, GroupConfig{GroupWait: time.Hour})
+
+		require.NoError(t, output.Send(jobEvent("web", "JobRegistered")))
+		require.NoError(t, output.Send(jobEvent("api", "JobRegistered")))
+		require.NoError(t, output.Close())
+		assert.Equal(t, 2, mock.sendCalls)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		output := NewGroupOutput(&MockOutput{}, GroupConfig{})
+		assert.Equal(t, 30*time.Second, output.config.GroupWait)
+		assert.Equal(t, 5*time.Minute, output.config.GroupInterval)
+	})
+}
+
+func TestCreateOutputWithGrouping(t *testing.T) {
+	output, err := createOutput(config.Output{
+		Type:      "stdout",
+		GroupBy:   []string{"Topic"},
+		GroupWait: "10s",
+		Retry:     &config.RetryConfig{MaxRetries: 2},
+	}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &GroupOutput{}, output)
+	assert.Equal(t, 10*time.Second, output.(*GroupOutput).config.GroupWait)
+	assert.IsType(t, &RetryOutput{}, output.(*GroupOutput).output)
+
+	_, err = createOutput(config.Output{Type: "stdout", GroupInterval: "often"}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid group_interval format")
+}
+
+func TestHTTPOutputSendBatch(t *testing.T) {
+	var received Batch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	output, err := NewHTTPOutput(map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+
+	err = output.SendBatch(Batch{
+		GroupLabels: map[string]string{"Topic": "Job"},
+		Events:      []nomad.Event{jobEvent("web", "JobRegistered"), jobEvent("api", "JobRegistered")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Job", received.GroupLabels["Topic"])
+	assert.Len(t, received.Events, 2)
+}
+
+func TestSlackOutputFormatBatch(t *testing.T) {
+	output, err := NewSlackOutput(map[string]interface{}{
+		"webhook_url": "https://hooks.slack.com/services/test",
+		"text":        "{{ .Count }} events for {{ .GroupLabels.Topic }}:{{ range .Events }} {{ .Key }}{{ end }}",
+		"blocks": []interface{}{
+			map[string]interface{}{
+				"type": "section",
+				"text": "Jobs changed",
+				"fields": []interface{}{
+					map[string]interface{}{
+						"range": ".Events",
+						"type":  "mrkdwn",
+						"text":  "{{ .Type }} {{ .Payload.Job.ID }}",
+					},
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	message, err := output.formatBatch(Batch{
+		GroupLabels: map[string]string{"Topic": "Job"},
+		Events:      []nomad.Event{jobEvent("web", "JobRegistered"), jobEvent("api", "JobDeregistered")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2 events for Job: web api", message.Text)
+	blocks := message.Blocks.([]slack.Block)
+	require.Len(t, blocks, 1)
+	section := blocks[0].(*slack.SectionBlock)
+	require.Len(t, section.Fields, 2)
+	assert.Equal(t, "JobRegistered web", section.Fields[0].Text)
+	assert.Equal(t, "JobDeregistered api", section.Fields[1].Text)
+}
@@ -2,19 +2,21 @@ package outputs
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
 )
 
 type HTTPOutput struct {
-	url        string
-	method     string
-	headers    map[string]string
-	httpClient *http.Client
+	url            string
+	method         string
+	headers        map[string]string
+	httpClient     *http.Client
+	templateEngine *template.Engine
+	body           *outputBody
 }
 
 func NewHTTPOutput(config map[string]interface{}) (*HTTPOutput, error) {
@@ -42,26 +44,58 @@ func NewHTTPOutput(config map[string]interface{}) (*HTTPOutput, error) {
 		timeout = time.Duration(t) * time.Second
 	}
 
+	templateEngine := template.NewEngine()
+	onError, err := newTemplateErrorPolicy(config, templateEngine, DefaultFallbackTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := newOutputBody(config, templateEngine, onError)
+	if err != nil {
+		return nil, err
+	}
+
 	return &HTTPOutput{
-		url:        url,
-		method:     method,
-		headers:    headers,
-		httpClient: &http.Client{Timeout: timeout},
+		url:            url,
+		method:         method,
+		headers:        headers,
+		httpClient:     &http.Client{Timeout: timeout},
+		templateEngine: templateEngine,
+		body:           body,
 	}, nil
 }
 
 func (o *HTTPOutput) Send(event nomad.Event) error {
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event to JSON: %w", err)
+	eventJSON, deliver, err := o.body.encode(func() map[string]interface{} {
+		return o.templateEngine.CreateTemplateData(event)
+	}, event)
+	if err != nil || !deliver {
+		return err
 	}
 
-	req, err := http.NewRequest(o.method, o.url, bytes.NewBuffer(eventJSON))
+	return o.send(eventJSON)
+}
+
+// SendBatch implements the BatchSender interface, sending a single request
+// whose body is {"GroupLabels": {...}, "Events": [...]}, or the body template
+// rendered with .Events and .GroupLabels
+func (o *HTTPOutput) SendBatch(batch Batch) error {
+	batchJSON, deliver, err := o.body.encode(func() map[string]interface{} {
+		return o.templateEngine.CreateBatchTemplateData(batch.Events, batch.GroupLabels)
+	}, batch)
+	if err != nil || !deliver {
+		return err
+	}
+
+	return o.send(batchJSON)
+}
+
+func (o *HTTPOutput) send(body []byte) error {
+	req, err := http.NewRequest(o.method, o.url, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", o.body.mediaType())
 	for k, v := range o.headers {
 		req.Header.Set(k, v)
 	}
@@ -1,10 +1,13 @@
 package outputs
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"nomad-events/internal/config"
+	"nomad-events/internal/dedupe"
 	"nomad-events/internal/nomad"
 
 	"github.com/hashicorp/nomad/api"
@@ -14,9 +17,60 @@ type Output interface {
 	Send(event nomad.Event) error
 }
 
+// Batch is a group of events delivered together, see GroupOutput
+type Batch struct {
+	GroupLabels map[string]string
+	Events      []nomad.Event
+}
+
+// BatchSender is implemented by outputs that can deliver a Batch as a single message
+type BatchSender interface {
+	SendBatch(batch Batch) error
+}
+
+// sendBatch delivers a batch as one message when the output supports it,
+// otherwise it falls back to sending each event individually
+func sendBatch(output Output, batch Batch) error {
+	if sender, ok := output.(BatchSender); ok {
+		return sender.SendBatch(batch)
+	}
+
+	var errs []error
+	for _, event := range batch.Events {
+		if err := output.Send(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeOutput closes an output if it holds resources
+func closeOutput(output Output) error {
+	if closer, ok := output.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// unwrap returns the output a wrapper such as RetryOutput delivers to, or nil
+func unwrap(output Output) Output {
+	switch o := output.(type) {
+	case *DedupeOutput:
+		return o.output
+	case *GroupOutput:
+		return o.output
+	case *RateLimitOutput:
+		return o.output
+	case *RetryOutput:
+		return o.output
+	}
+	return nil
+}
+
 type Manager struct {
 	outputs     map[string]Output
 	nomadClient *api.Client
+	inherited   map[string]Stats // Stats of the outputs this manager replaced
 }
 
 func NewManager(outputConfigs map[string]config.Output, nomadClient *api.Client) (*Manager, error) {
@@ -30,7 +84,7 @@ func NewManager(outputConfigs map[string]config.Output, nomadClient *api.Client)
 		outputs[name] = output
 	}
 
-	return &Manager{outputs: outputs, nomadClient: nomadClient}, nil
+	return &Manager{outputs: outputs, nomadClient: nomadClient, inherited: make(map[string]Stats)}, nil
 }
 
 func createOutput(cfg config.Output, nomadClient *api.Client) (Output, error) {
@@ -70,7 +124,67 @@ func createOutput(cfg config.Output, nomadClient *api.Client) (Output, error) {
 			retryConfig.BaseDelay = delay
 		}
 
-		return NewRetryOutput(baseOutput, retryConfig), nil
+		if cfg.Retry.MaxRetryAfter != "" {
+			maxRetryAfter, err := time.ParseDuration(cfg.Retry.MaxRetryAfter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max_retry_after format: %w. use a duration like \"30s\", \"1m\"", err)
+			}
+			retryConfig.MaxRetryAfter = maxRetryAfter
+		}
+
+		baseOutput = NewRetryOutput(baseOutput, retryConfig)
+	}
+
+	// Rate limit deliveries (single events or batches) if configured. The
+	// settings were checked when the configuration was loaded.
+	if cfg.RateLimit != nil {
+		rateLimitConfig := RateLimitConfig{
+			Limit:    cfg.RateLimit.Limit,
+			Burst:    cfg.RateLimit.Burst,
+			Action:   cfg.RateLimit.Action,
+			MaxQueue: cfg.RateLimit.MaxQueue,
+		}
+		if cfg.RateLimit.Per != "" {
+			rateLimitConfig.Per, _ = time.ParseDuration(cfg.RateLimit.Per)
+		}
+
+		baseOutput = NewRateLimitOutput(baseOutput, rateLimitConfig)
+	}
+
+	// Group events into batches if configured
+	if cfg.Grouped() {
+		groupConfig := GroupConfig{
+			GroupBy:  cfg.GroupBy,
+			MaxBatch: cfg.MaxBatch,
+		}
+
+		if cfg.GroupWait != "" {
+			wait, err := time.ParseDuration(cfg.GroupWait)
+			if err != nil {
+				return nil, fmt.Errorf("invalid group_wait format: %w. use a duration like \"30s\", \"1m\"", err)
+			}
+			groupConfig.GroupWait = wait
+		}
+
+		if cfg.GroupInterval != "" {
+			interval, err := time.ParseDuration(cfg.GroupInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid group_interval format: %w. use a duration like \"1m\", \"5m\"", err)
+			}
+			groupConfig.GroupInterval = interval
+		}
+
+		baseOutput = NewGroupOutput(baseOutput, groupConfig)
+	}
+
+	// Dedupe before anything else so suppressed events never reach retries
+	if cfg.Dedupe != nil {
+		deduplicator, err := dedupe.New(*cfg.Dedupe)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dedupe configuration: %w", err)
+		}
+
+		baseOutput = NewDedupeOutput(baseOutput, deduplicator)
 	}
 
 	return baseOutput, nil
@@ -85,6 +199,52 @@ func (m *Manager) Send(outputName string, event nomad.Event) error {
 	return output.Send(event)
 }
 
+// Inherit passes the rate limit state and stats of the previous manager's
+// outputs to the outputs with the same name, before the previous manager is
+// closed
+func (m *Manager) Inherit(previous *Manager) {
+	previousStats := previous.Stats()
+	for name, output := range m.outputs {
+		previousOutput, exists := previous.outputs[name]
+		if !exists {
+			continue
+		}
+		m.inherited[name] = previousStats[name]
+
+		current, old := rateLimitOf(output), rateLimitOf(previousOutput)
+		if current != nil && old != nil {
+			current.inherit(old)
+		}
+	}
+}
+
+// Stats returns the stats of each output, including those of the outputs it
+// replaced on reload
+func (m *Manager) Stats() map[string]Stats {
+	stats := make(map[string]Stats, len(m.outputs))
+	for name, output := range m.outputs {
+		s := m.inherited[name]
+		for ; output != nil; output = unwrap(output) {
+			if counter, ok := output.(droppedCounter); ok {
+				s.Dropped += counter.Dropped()
+			}
+		}
+		stats[name] = s
+	}
+	return stats
+}
+
+// Close flushes and releases every output, returning all errors encountered
+func (m *Manager) Close() error {
+	var errs []error
+	for name, output := range m.outputs {
+		if err := closeOutput(output); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close output %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (m *Manager) GetOutput(name string) (Output, bool) {
 	output, exists := m.outputs[name]
 	return output, exists
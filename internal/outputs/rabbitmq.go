@@ -1,7 +1,6 @@
 package outputs
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -20,8 +19,14 @@ type RabbitMQOutput struct {
 	durable            bool
 	autoDelete         bool
 	templateEngine     *template.Engine
+	onError            templateErrorPolicy
+	body               *outputBody
 }
 
+// defaultRoutingKey is the routing key template used when none is configured,
+// and the fallback when the configured one fails to render
+const defaultRoutingKey = "nomad.{{ .Topic }}.{{ .Type }}"
+
 func NewRabbitMQOutput(config map[string]interface{}) (*RabbitMQOutput, error) {
 	url, ok := config["url"].(string)
 	if !ok || url == "" {
@@ -34,7 +39,20 @@ func NewRabbitMQOutput(config map[string]interface{}) (*RabbitMQOutput, error) {
 
 	// Set default routing key template
 	if routingKeyTemplate == "" {
-		routingKeyTemplate = "nomad.{{ .Topic }}.{{ .Type }}"
+		routingKeyTemplate = defaultRoutingKey
+	}
+
+	templateEngine := template.NewEngine()
+	if err := templateEngine.Compile(routingKeyTemplate); err != nil {
+		return nil, fmt.Errorf("invalid routing_key template: %w", err)
+	}
+	onError, err := newTemplateErrorPolicy(config, templateEngine, defaultRoutingKey)
+	if err != nil {
+		return nil, err
+	}
+	body, err := newOutputBody(config, templateEngine, onError)
+	if err != nil {
+		return nil, err
 	}
 
 	durable := true
@@ -66,7 +84,9 @@ func NewRabbitMQOutput(config map[string]interface{}) (*RabbitMQOutput, error) {
 		queue:              queue,
 		durable:            durable,
 		autoDelete:         autoDelete,
-		templateEngine:     template.NewEngine(),
+		templateEngine:     templateEngine,
+		onError:            onError,
+		body:               body,
 	}
 
 	// Setup exchange and queue since names are now static
@@ -127,16 +147,24 @@ func (o *RabbitMQOutput) Send(event nomad.Event) error {
 	// Process routing key template only
 	routingKey, err := o.processTemplate(o.routingKeyTemplate, event)
 	if err != nil {
-		return fmt.Errorf("failed to process routing key template: %w", err)
+		var deliver bool
+		routingKey, deliver, err = o.onError.handle(err, o.templateEngine.CreateTemplateData(event))
+		if err != nil {
+			return fmt.Errorf("failed to process routing key template: %w", err)
+		}
+		if !deliver {
+			return nil
+		}
 	}
 
 	// Trim whitespace from routing key name
 	routingKey = strings.TrimSpace(routingKey)
 
-	// Marshal event to JSON
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event to JSON: %w", err)
+	eventJSON, deliver, err := o.body.encode(func() map[string]interface{} {
+		return o.templateEngine.CreateTemplateData(event)
+	}, event)
+	if err != nil || !deliver {
+		return err
 	}
 
 	// Publish message
@@ -146,7 +174,7 @@ func (o *RabbitMQOutput) Send(event nomad.Event) error {
 		false,
 		false,
 		amqp.Publishing{
-			ContentType: "application/json",
+			ContentType: o.body.mediaType(),
 			Timestamp:   time.Now(),
 			Body:        eventJSON,
 		},
@@ -163,12 +191,7 @@ func (o *RabbitMQOutput) processTemplate(templateStr string, event nomad.Event)
 		return "", nil
 	}
 
-	result, err := o.templateEngine.ProcessText(templateStr, event)
-	if err != nil {
-		return templateStr, err // Return original template on error
-	}
-
-	return result, nil
+	return o.templateEngine.ProcessText(templateStr, event)
 }
 
 func (o *RabbitMQOutput) Close() error {
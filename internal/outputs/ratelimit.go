@@ -0,0 +1,416 @@
+package outputs
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nomad-events/internal/nomad"
+)
+
+// Rate limit actions for deliveries that exceed the token bucket
+const (
+	RateLimitDrop     = "drop"
+	RateLimitQueue    = "queue"
+	RateLimitCollapse = "collapse"
+)
+
+// RateLimitConfig holds configuration for rate limiting behavior
+type RateLimitConfig struct {
+	Limit    int
+	Per      time.Duration
+	Burst    int
+	Action   string
+	MaxQueue int
+}
+
+// RateLimitOutput wraps another Output with a token bucket. Deliveries over
+// the limit are dropped, queued until tokens are available, or collapsed into
+// a single "N events suppressed" summary sent once the window ends. Dropped
+// deliveries are counted and logged once per window.
+type RateLimitOutput struct {
+	output Output
+	config RateLimitConfig
+	rate   float64 // tokens per second
+	now    func() time.Time
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+
+	// queue action
+	queue  []delivery
+	wakeup chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	// Deliveries collapsed into a summary, or dropped, in the current window
+	suppressed      int
+	suppressedTypes map[string]int
+	windowStart     time.Time
+	windowTimer     *time.Timer
+
+	// successor took over the queue and bucket on reload, see inherit
+	successor *RateLimitOutput
+	closed    bool
+
+	dropped atomic.Uint64
+}
+
+// delivery is a single event or a batch waiting to be sent
+type delivery struct {
+	event *nomad.Event
+	batch *Batch
+}
+
+func (d delivery) size() int {
+	if d.batch != nil {
+		return len(d.batch.Events)
+	}
+	return 1
+}
+
+func (d delivery) eventType() string {
+	if d.event != nil {
+		return d.event.Type
+	}
+	return "Batch"
+}
+
+// NewRateLimitOutput creates a new RateLimitOutput wrapper
+func NewRateLimitOutput(output Output, config RateLimitConfig) *RateLimitOutput {
+	// Set defaults if not specified
+	if config.Per == 0 {
+		config.Per = time.Minute
+	}
+	if config.Burst == 0 {
+		config.Burst = config.Limit
+	}
+	if config.Action == "" {
+		config.Action = RateLimitDrop
+	}
+	if config.MaxQueue == 0 {
+		config.MaxQueue = 1000
+	}
+
+	r := &RateLimitOutput{
+		output:          output,
+		config:          config,
+		rate:            float64(config.Limit) / config.Per.Seconds(),
+		now:             time.Now,
+		tokens:          float64(config.Burst),
+		suppressedTypes: make(map[string]int),
+	}
+	r.lastRefill = r.now()
+
+	if config.Action == RateLimitQueue {
+		r.wakeup = make(chan struct{}, 1)
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.drainQueue()
+	}
+
+	return r
+}
+
+// Send implements the Output interface with rate limiting
+func (r *RateLimitOutput) Send(event nomad.Event) error {
+	return r.deliver(delivery{event: &event})
+}
+
+// SendBatch implements the BatchSender interface; a batch consumes a single token
+func (r *RateLimitOutput) SendBatch(batch Batch) error {
+	return r.deliver(delivery{batch: &batch})
+}
+
+func (r *RateLimitOutput) deliver(d delivery) error {
+	r.mu.Lock()
+
+	if r.successor != nil {
+		successor := r.successor
+		r.mu.Unlock()
+		return successor.deliver(d)
+	}
+
+	// Preserve ordering: nothing overtakes events already waiting in the queue
+	if r.closed || (len(r.queue) == 0 && r.take()) {
+		r.mu.Unlock()
+		return r.send(d)
+	}
+
+	if r.config.Action == RateLimitQueue && len(r.queue) < r.config.MaxQueue {
+		r.queue = append(r.queue, d)
+		r.mu.Unlock()
+
+		select {
+		case r.wakeup <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	// Collapsed deliveries are summarized when the window ends, and dropped
+	// ones, including those that do not fit in a full queue, are logged then
+	r.suppress(d)
+	r.mu.Unlock()
+	return nil
+}
+
+// suppress counts a delivery in the current window, starting one if needed.
+// Callers must hold r.mu.
+func (r *RateLimitOutput) suppress(d delivery) {
+	if r.suppressed == 0 {
+		r.windowStart = r.now()
+		r.windowTimer = time.AfterFunc(r.config.Per, r.flushSummary)
+	}
+	r.suppressed += d.size()
+	r.suppressedTypes[d.eventType()] += d.size()
+	if r.config.Action != RateLimitCollapse {
+		r.dropped.Add(uint64(d.size()))
+	}
+}
+
+// Dropped returns the number of events dropped over the limit, including
+// queued events dropped on close. Collapsed events are not counted.
+func (r *RateLimitOutput) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+func (r *RateLimitOutput) send(d delivery) error {
+	if d.batch != nil {
+		return sendBatch(r.output, *d.batch)
+	}
+	return r.output.Send(*d.event)
+}
+
+// take refills the bucket and consumes a token if one is available.
+// Callers must hold r.mu.
+func (r *RateLimitOutput) take() bool {
+	now := r.now()
+	r.tokens += now.Sub(r.lastRefill).Seconds() * r.rate
+	if r.tokens > float64(r.config.Burst) {
+		r.tokens = float64(r.config.Burst)
+	}
+	r.lastRefill = now
+
+	if r.tokens >= 1 {
+		r.tokens--
+		return true
+	}
+	return false
+}
+
+// nextToken returns how long until a token becomes available. Callers must hold r.mu.
+func (r *RateLimitOutput) nextToken() time.Duration {
+	missing := 1 - r.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / r.rate * float64(time.Second))
+}
+
+// drainQueue sends queued deliveries as tokens become available
+func (r *RateLimitOutput) drainQueue() {
+	defer close(r.done)
+
+	for {
+		r.mu.Lock()
+		if len(r.queue) == 0 {
+			r.mu.Unlock()
+			select {
+			case <-r.wakeup:
+				continue
+			case <-r.stop:
+				return
+			}
+		}
+
+		if !r.take() {
+			wait := r.nextToken()
+			r.mu.Unlock()
+			select {
+			case <-time.After(wait):
+				continue
+			case <-r.stop:
+				return
+			}
+		}
+
+		d := r.queue[0]
+		r.queue = r.queue[1:]
+		r.mu.Unlock()
+
+		if err := r.send(d); err != nil {
+			slog.Error("Failed to send queued event", "error", err, "type", d.eventType())
+		}
+	}
+}
+
+// flushSummary ends the window, sending a single summary event for everything
+// collapsed in it, or logging how many deliveries were dropped
+func (r *RateLimitOutput) flushSummary() {
+	r.mu.Lock()
+	if r.suppressed == 0 {
+		r.mu.Unlock()
+		return
+	}
+
+	summary := r.summaryEvent()
+	r.suppressed = 0
+	r.suppressedTypes = make(map[string]int)
+	r.windowTimer = nil
+	r.mu.Unlock()
+
+	if r.config.Action != RateLimitCollapse {
+		payload := summary.Payload.(map[string]interface{})
+		slog.Warn("Rate limit exceeded, dropped events",
+			"count", payload["Count"],
+			"types", payload["Types"],
+			"action", r.config.Action,
+			"limit", r.config.Limit,
+			"per", r.config.Per)
+		return
+	}
+
+	if err := r.output.Send(summary); err != nil {
+		slog.Error("Failed to send rate limit summary", "error", err)
+	}
+}
+
+// summaryEvent builds the "N events suppressed" event. Callers must hold r.mu.
+func (r *RateLimitOutput) summaryEvent() nomad.Event {
+	types := make(map[string]interface{}, len(r.suppressedTypes))
+	for eventType, count := range r.suppressedTypes {
+		types[eventType] = count
+	}
+
+	now := r.now()
+	return nomad.Event{
+		Topic: "RateLimit",
+		Type:  "EventsSuppressed",
+		Payload: map[string]interface{}{
+			"Message":     fmt.Sprintf("%d events suppressed by rate limit", r.suppressed),
+			"Count":       r.suppressed,
+			"Types":       types,
+			"WindowStart": r.windowStart.Format(time.RFC3339),
+			"WindowEnd":   now.Format(time.RFC3339),
+		},
+	}
+}
+
+// inherit takes over the bucket, queue and current window of the output this
+// one replaces on reload, so a reload neither resets the limit nor flushes the
+// queue at once. The previous output passes later deliveries on to this one.
+func (r *RateLimitOutput) inherit(previous *RateLimitOutput) {
+	if previous == r {
+		return
+	}
+
+	previous.mu.Lock()
+	if previous.closed || previous.successor != nil {
+		previous.mu.Unlock()
+		return
+	}
+	previous.take()
+	tokens, lastRefill := previous.tokens, previous.lastRefill
+	queued := previous.queue
+	suppressed, suppressedTypes, windowStart := previous.suppressed, previous.suppressedTypes, previous.windowStart
+	if previous.windowTimer != nil {
+		previous.windowTimer.Stop()
+	}
+	previous.queue = nil
+	previous.suppressed = 0
+	previous.suppressedTypes = make(map[string]int)
+	previous.windowTimer = nil
+	previous.successor = r
+	previous.mu.Unlock()
+
+	r.mu.Lock()
+	r.tokens = min(tokens, float64(r.config.Burst))
+	r.lastRefill = lastRefill
+	if suppressed > 0 {
+		if r.suppressed == 0 {
+			r.windowStart = windowStart
+			r.windowTimer = time.AfterFunc(max(r.config.Per-r.now().Sub(windowStart), 0), r.flushSummary)
+		}
+		r.suppressed += suppressed
+		for eventType, count := range suppressedTypes {
+			r.suppressedTypes[eventType] += count
+		}
+	}
+	r.mu.Unlock()
+
+	// Queued deliveries wait for this output's tokens, or are dropped or
+	// collapsed if it no longer queues
+	for _, d := range queued {
+		r.deliver(d)
+	}
+}
+
+// Close stops the rate limiter and closes the wrapped output. Queued events
+// are passed to the output that replaced it on reload; otherwise those the
+// bucket still has tokens for are sent and the rest are dropped. A pending
+// summary is sent immediately.
+func (r *RateLimitOutput) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	if r.windowTimer != nil {
+		r.windowTimer.Stop()
+	}
+	r.mu.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+	}
+
+	r.mu.Lock()
+	queued := r.queue
+	r.queue = nil
+	successor := r.successor
+	var sendable []delivery
+	dropped := 0
+	for _, d := range queued {
+		if successor == nil && r.take() {
+			sendable = append(sendable, d)
+		} else if successor == nil {
+			dropped += d.size()
+		}
+	}
+	r.mu.Unlock()
+
+	if successor != nil {
+		for _, d := range queued {
+			successor.deliver(d)
+		}
+	}
+	for _, d := range sendable {
+		if err := r.send(d); err != nil {
+			slog.Error("Failed to send queued event", "error", err, "type", d.eventType())
+		}
+	}
+	if dropped > 0 {
+		r.dropped.Add(uint64(dropped))
+		slog.Warn("Rate limit output closed, dropped queued events", "count", dropped)
+	}
+
+	r.flushSummary()
+
+	return closeOutput(r.output)
+}
+
+// rateLimitOf returns the rate limiter in an output's chain of wrappers
+func rateLimitOf(output Output) *RateLimitOutput {
+	for ; output != nil; output = unwrap(output) {
+		if r, ok := output.(*RateLimitOutput); ok {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitOutput(t *testing.T) {
+	event := nomad.Event{Topic: "Node", Type: "NodeEvent", Key: "node-1"}
+
+	t.Run("drop", func(t *testing.T) {
+		mock := &MockOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 2, Per: time.Hour})
+
+		now := time.Now()
+		output.now = func() time.Time { return now }
+		output.lastRefill = now
+
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, output.Send(event))
+		}
+		assert.Equal(t, 2, mock.sendCalls)
+		assert.Equal(t, uint64(3), output.Dropped())
+
+		// One token is refilled every 30 minutes
+		now = now.Add(30 * time.Minute)
+		assert.NoError(t, output.Send(event))
+		assert.NoError(t, output.Send(event))
+		assert.Equal(t, 3, mock.sendCalls)
+	})
+
+	t.Run("burst caps the bucket", func(t *testing.T) {
+		mock := &MockOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 60, Per: time.Minute, Burst: 1})
+
+		now := time.Now()
+		output.now = func() time.Time { return now }
+		output.lastRefill = now
+
+		now = now.Add(time.Hour)
+		output.Send(event)
+		output.Send(event)
+		assert.Equal(t, 1, mock.sendCalls)
+	})
+
+	t.Run("queue delivers excess events in order", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 1, Per: 20 * time.Millisecond, Action: RateLimitQueue})
+		defer output.Close()
+
+		for _, key := range []string{"a", "b", "c"} {
+			require.NoError(t, output.Send(nomad.Event{Topic: "Node", Key: key}))
+		}
+		assert.Len(t, mock.Batches(), 1)
+
+		assert.Eventually(t, func() bool { return len(mock.Batches()) == 3 }, time.Second, 5*time.Millisecond)
+		for i, key := range []string{"a", "b", "c"} {
+			assert.Equal(t, key, mock.Batches()[i].Events[0].Key)
+		}
+	})
+
+	t.Run("queue drops when full and keeps the limit on close", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 1, Per: time.Hour, Action: RateLimitQueue, MaxQueue: 2})
+
+		for i := 0; i < 4; i++ {
+			require.NoError(t, output.Send(event))
+		}
+		assert.Len(t, mock.Batches(), 1)
+		assert.Equal(t, 1, output.suppressed, "drops are counted and logged once per window")
+
+		// Queued events the bucket has no tokens for are dropped, not burst out
+		require.NoError(t, output.Close())
+		assert.Len(t, mock.Batches(), 1)
+		assert.Equal(t, uint64(3), output.Dropped())
+		assert.True(t, mock.closed)
+	})
+
+	t.Run("drops are counted per window", func(t *testing.T) {
+		output := NewRateLimitOutput(&MockOutput{}, RateLimitConfig{Limit: 1, Per: 20 * time.Millisecond})
+		defer output.Close()
+
+		for i := 0; i < 4; i++ {
+			require.NoError(t, output.Send(event))
+		}
+		output.mu.Lock()
+		assert.Equal(t, 3, output.suppressed)
+		assert.Equal(t, map[string]int{"NodeEvent": 3}, output.suppressedTypes)
+		output.mu.Unlock()
+
+		assert.Eventually(t, func() bool {
+			output.mu.Lock()
+			defer output.mu.Unlock()
+			return output.suppressed == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("collapse sends a summary when the window ends", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 1, Per: 30 * time.Millisecond, Action: RateLimitCollapse})
+		defer output.Close()
+
+		for i := 0; i < 4; i++ {
+			require.NoError(t, output.Send(event))
+		}
+		require.NoError(t, output.SendBatch(Batch{Events: []nomad.Event{event, event}}))
+		assert.Len(t, mock.Batches(), 1)
+
+		assert.Eventually(t, func() bool { return len(mock.Batches()) == 2 }, time.Second, 5*time.Millisecond)
+		summary := mock.Batches()[1].Events[0]
+		assert.Equal(t, "RateLimit", summary.Topic)
+		assert.Equal(t, "EventsSuppressed", summary.Type)
+
+		payload := summary.Payload.(map[string]interface{})
+		assert.Equal(t, 5, payload["Count"])
+		assert.Zero(t, output.Dropped(), "collapsed events are not dropped")
+		assert.Equal(t, "5 events suppressed by rate limit", payload["Message"])
+		assert.Equal(t, map[string]interface{}{"NodeEvent": 3, "Batch": 2}, payload["Types"])
+	})
+
+	t.Run("batch consumes a single token", func(t *testing.T) {
+		mock := &MockBatchOutput{}
+		output := NewRateLimitOutput(mock, RateLimitConfig{Limit: 1, Per: time.Hour})
+
+		require.NoError(t, output.SendBatch(Batch{Events: []nomad.Event{event, event, event}}))
+		require.NoError(t, output.SendBatch(Batch{Events: []nomad.Event{event}}))
+		assert.Len(t, mock.Batches(), 1)
+		assert.Len(t, mock.Batches()[0].Events, 3)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		output := NewRateLimitOutput(&MockOutput{}, RateLimitConfig{Limit: 5})
+		assert.Equal(t, time.Minute, output.config.Per)
+		assert.Equal(t, 5, output.config.Burst)
+		assert.Equal(t, RateLimitDrop, output.config.Action)
+	})
+}
+
+func TestRateLimitOutputInherit(t *testing.T) {
+	event := nomad.Event{Topic: "Node", Type: "NodeEvent", Key: "node-1"}
+
+	t.Run("reload keeps the queue and bucket", func(t *testing.T) {
+		cfg := RateLimitConfig{Limit: 1, Per: time.Hour, Action: RateLimitQueue}
+		oldMock, newMock := &MockBatchOutput{}, &MockBatchOutput{}
+		previous := NewRateLimitOutput(oldMock, cfg)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, previous.Send(event))
+		}
+		assert.Len(t, oldMock.Batches(), 1)
+
+		current := NewRateLimitOutput(newMock, cfg)
+		defer current.Close()
+		current.inherit(previous)
+		require.NoError(t, previous.Close())
+
+		// Neither output sends the queued events before the next token
+		assert.Len(t, oldMock.Batches(), 1)
+		assert.Empty(t, newMock.Batches())
+		current.mu.Lock()
+		assert.Len(t, current.queue, 2)
+		current.mu.Unlock()
+
+		// Late deliveries to the previous output join the queue behind them
+		require.NoError(t, previous.Send(nomad.Event{Topic: "Node", Key: "late"}))
+		current.mu.Lock()
+		assert.Equal(t, "late", current.queue[2].event.Key)
+		current.mu.Unlock()
+	})
+
+	t.Run("reload keeps the collapse window", func(t *testing.T) {
+		cfg := RateLimitConfig{Limit: 1, Per: 30 * time.Millisecond, Action: RateLimitCollapse}
+		oldMock, newMock := &MockBatchOutput{}, &MockBatchOutput{}
+		previous := NewRateLimitOutput(oldMock, cfg)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, previous.Send(event))
+		}
+
+		current := NewRateLimitOutput(newMock, cfg)
+		defer current.Close()
+		current.inherit(previous)
+		require.NoError(t, previous.Close())
+		assert.Len(t, oldMock.Batches(), 1, "the summary is not sent by the previous output")
+
+		assert.Eventually(t, func() bool { return len(newMock.Batches()) == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 2, newMock.Batches()[0].Events[0].Payload.(map[string]interface{})["Count"])
+	})
+
+	t.Run("manager matches outputs by name", func(t *testing.T) {
+		outputs := map[string]config.Output{
+			"limited": {Type: "stdout", GroupBy: []string{"Topic"}, RateLimit: &config.RateLimitConfig{Limit: 1, Per: "1h", Action: "queue"}},
+		}
+		previous, err := NewManager(outputs, nil)
+		require.NoError(t, err)
+		current, err := NewManager(outputs, nil)
+		require.NoError(t, err)
+		defer current.Close()
+
+		current.Inherit(previous)
+		oldLimiter := rateLimitOf(previous.outputs["limited"])
+		require.NotNil(t, oldLimiter)
+		assert.Same(t, rateLimitOf(current.outputs["limited"]), oldLimiter.successor)
+		require.NoError(t, previous.Close())
+	})
+}
+
+func TestCreateOutputWithRateLimit(t *testing.T) {
+	t.Run("wraps between grouping and retry", func(t *testing.T) {
+		output, err := createOutput(config.Output{
+			Type:      "stdout",
+			GroupBy:   []string{"Topic"},
+			Retry:     &config.RetryConfig{MaxRetries: 2},
+			RateLimit: &config.RateLimitConfig{Limit: 10, Per: "1m", Action: "collapse"},
+		}, nil)
+		require.NoError(t, err)
+		require.IsType(t, &GroupOutput{}, output)
+		rateLimited := output.(*GroupOutput).output
+		require.IsType(t, &RateLimitOutput{}, rateLimited)
+		assert.IsType(t, &RetryOutput{}, rateLimited.(*RateLimitOutput).output)
+	})
+}
@@ -1,8 +1,10 @@
 package outputs
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"nomad-events/internal/nomad"
@@ -10,15 +12,18 @@ import (
 
 // RetryOutput wraps another Output with retry logic
 type RetryOutput struct {
-	output     Output
-	maxRetries int
-	baseDelay  time.Duration
+	output        Output
+	maxRetries    int
+	baseDelay     time.Duration
+	maxRetryAfter time.Duration
+	dropped       atomic.Uint64
 }
 
 // RetryConfig holds configuration for retry behavior
 type RetryConfig struct {
-	MaxRetries int           `yaml:"max_retries"`
-	BaseDelay  time.Duration `yaml:"base_delay"`
+	MaxRetries    int           `yaml:"max_retries"`
+	BaseDelay     time.Duration `yaml:"base_delay"`
+	MaxRetryAfter time.Duration `yaml:"max_retry_after"`
 }
 
 // NewRetryOutput creates a new RetryOutput wrapper
@@ -30,48 +35,133 @@ func NewRetryOutput(output Output, config RetryConfig) *RetryOutput {
 	if config.BaseDelay == 0 {
 		config.BaseDelay = 1 * time.Second
 	}
+	if config.MaxRetryAfter == 0 {
+		config.MaxRetryAfter = time.Minute
+	}
 
 	return &RetryOutput{
-		output:     output,
-		maxRetries: config.MaxRetries,
-		baseDelay:  config.BaseDelay,
+		output:        output,
+		maxRetries:    config.MaxRetries,
+		baseDelay:     config.BaseDelay,
+		maxRetryAfter: config.MaxRetryAfter,
 	}
 }
 
+// permanentError is implemented by errors that retrying cannot fix, such as
+// a message Slack rejected as invalid
+type permanentError interface {
+	Permanent() bool
+}
+
+// retryAfterError is implemented by errors that say how long to wait before
+// retrying, such as rate limit responses with a Retry-After header
+type retryAfterError interface {
+	RetryAfter() time.Duration
+}
+
 // Send implements the Output interface with retry logic
 func (r *RetryOutput) Send(event nomad.Event) error {
+	attempts, err := r.retry(func() error { return r.output.Send(event) },
+		"topic", event.Topic,
+		"type", event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to send event after %d attempts: %w", attempts, err)
+	}
+	return nil
+}
+
+// SendBatch implements the BatchSender interface, retrying the whole batch
+// when the wrapped output supports batches and each event otherwise
+func (r *RetryOutput) SendBatch(batch Batch) error {
+	sender, ok := r.output.(BatchSender)
+	if !ok {
+		return sendBatch(outputFunc(r.Send), batch)
+	}
+
+	attempts, err := r.retry(func() error { return sender.SendBatch(batch) },
+		"group_labels", batch.GroupLabels,
+		"events", len(batch.Events))
+	if err != nil {
+		return fmt.Errorf("failed to send batch after %d attempts: %w", attempts, err)
+	}
+	return nil
+}
+
+// Dropped returns the number of deliveries given up on because the output
+// asked to retry later than the maximum Retry-After
+func (r *RetryOutput) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close closes the wrapped output
+func (r *RetryOutput) Close() error {
+	return closeOutput(r.output)
+}
+
+// retry calls send until it succeeds, fails permanently or maxRetries is
+// reached, returning the number of attempts and the last error
+func (r *RetryOutput) retry(send func() error, logAttrs ...any) (int, error) {
 	var lastErr error
-	
+
 	for attempt := 1; attempt <= r.maxRetries; attempt++ {
-		err := r.output.Send(event)
+		err := send()
 		if err == nil {
 			// Success
 			if attempt > 1 {
 				slog.Info("Event sent successfully after retry",
-					"topic", event.Topic,
-					"type", event.Type,
-					"attempt", attempt)
+					append(logAttrs, "attempt", attempt)...)
 			}
-			return nil
+			return attempt, nil
 		}
 
 		lastErr = err
-		
+
+		var permanent permanentError
+		if errors.As(err, &permanent) && permanent.Permanent() {
+			return attempt, err
+		}
+
 		if attempt < r.maxRetries {
-			// Calculate exponential backoff delay
-			delay := r.baseDelay * time.Duration(1<<(attempt-1)) // 1s, 2s, 4s, 8s, etc.
-			
+			delay, tooLate := r.delay(attempt, err)
+			if tooLate != nil {
+				r.dropped.Add(1)
+				slog.Error("Output asked to retry too late, dropping delivery",
+					append(logAttrs, "attempt", attempt, "error", tooLate)...)
+				return attempt, tooLate
+			}
+
 			slog.Warn("Output send failed, retrying",
-				"topic", event.Topic,
-				"type", event.Type,
-				"attempt", attempt,
-				"max_retries", r.maxRetries,
-				"error", err,
-				"retry_delay", delay)
-			
+				append(logAttrs,
+					"attempt", attempt,
+					"max_retries", r.maxRetries,
+					"error", err,
+					"retry_delay", delay)...)
+
 			time.Sleep(delay)
 		}
 	}
 
-	return fmt.Errorf("failed to send event after %d attempts: %w", r.maxRetries, lastErr)
-}
\ No newline at end of file
+	return r.maxRetries, lastErr
+}
+
+// delay is how long to wait after a failed attempt: an exponential backoff,
+// unless the output was told how long to wait. Sends block the pipeline while
+// they wait, so a Retry-After longer than maxRetryAfter fails the delivery
+// instead.
+func (r *RetryOutput) delay(attempt int, err error) (time.Duration, error) {
+	var retryAfter retryAfterError
+	if errors.As(err, &retryAfter) && retryAfter.RetryAfter() > 0 {
+		if wait := retryAfter.RetryAfter(); wait > r.maxRetryAfter {
+			return 0, fmt.Errorf("retry after %s exceeds max_retry_after of %s: %w", wait, r.maxRetryAfter, err)
+		}
+		return retryAfter.RetryAfter(), nil
+	}
+	return r.baseDelay * time.Duration(1<<(attempt-1)), nil // 1s, 2s, 4s, 8s, etc.
+}
+
+// outputFunc adapts a function to the Output interface
+type outputFunc func(event nomad.Event) error
+
+func (f outputFunc) Send(event nomad.Event) error {
+	return f(event)
+}
@@ -6,21 +6,23 @@ import (
 	"time"
 
 	"nomad-events/internal/nomad"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockOutput implements Output interface for testing
 type MockOutput struct {
-	sendCalls   int
-	shouldFail  bool
-	failTimes   int
-	lastEvent   nomad.Event
+	sendCalls  int
+	shouldFail bool
+	failTimes  int
+	lastEvent  nomad.Event
 }
 
 func (m *MockOutput) Send(event nomad.Event) error {
 	m.sendCalls++
 	m.lastEvent = event
-	
+
 	if m.shouldFail && m.sendCalls <= m.failTimes {
 		return errors.New("mock error")
 	}
@@ -85,4 +87,91 @@ func TestRetryOutput(t *testing.T) {
 		assert.Equal(t, 3, retry.maxRetries)
 		assert.Equal(t, 1*time.Second, retry.baseDelay)
 	})
-}
\ No newline at end of file
+}
+
+// sendErrorOutput fails every send with err
+type sendErrorOutput struct {
+	err   error
+	calls int
+}
+
+func (o *sendErrorOutput) Send(event nomad.Event) error {
+	o.calls++
+	return o.err
+}
+
+func TestRetryOutputSlackErrors(t *testing.T) {
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		output := &sendErrorOutput{err: &SlackError{Status: 400, Code: "invalid_blocks"}}
+		retry := NewRetryOutput(output, RetryConfig{MaxRetries: 3, BaseDelay: 10 * time.Millisecond})
+
+		err := retry.Send(nomad.Event{Topic: "Test"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to send event after 1 attempts")
+		assert.Contains(t, err.Error(), "invalid_blocks")
+		assert.Equal(t, 1, output.calls)
+	})
+
+	t.Run("failed templates are not retried", func(t *testing.T) {
+		output := &sendErrorOutput{err: &templateFailure{err: errors.New("template: index of untyped nil")}}
+		retry := NewRetryOutput(output, RetryConfig{MaxRetries: 3, BaseDelay: 10 * time.Millisecond})
+
+		err := retry.Send(nomad.Event{Topic: "Test"})
+		assert.Error(t, err)
+		assert.Equal(t, 1, output.calls)
+	})
+
+	t.Run("Retry-After replaces the backoff", func(t *testing.T) {
+		output := &sendErrorOutput{err: &SlackError{Status: 429, Code: "rate_limited", retryAfter: 50 * time.Millisecond}}
+		retry := NewRetryOutput(output, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond})
+
+		start := time.Now()
+		err := retry.Send(nomad.Event{Topic: "Test"})
+		assert.Error(t, err)
+		assert.Equal(t, 2, output.calls)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("Retry-After past the maximum drops the delivery", func(t *testing.T) {
+		output := &sendErrorOutput{err: &SlackError{Status: 429, Code: "rate_limited", retryAfter: time.Hour}}
+		retry := NewRetryOutput(output, RetryConfig{MaxRetries: 3, MaxRetryAfter: time.Minute})
+
+		err := retry.Send(nomad.Event{Topic: "Test"})
+		assert.ErrorContains(t, err, "exceeds max_retry_after")
+		assert.Equal(t, 1, output.calls)
+		assert.Equal(t, uint64(1), retry.Dropped())
+	})
+}
+
+func TestRetryOutputDelay(t *testing.T) {
+	retry := NewRetryOutput(&MockOutput{}, RetryConfig{MaxRetries: 4, BaseDelay: time.Second})
+	rateLimited := func(wait time.Duration) error {
+		return &SlackError{Status: 429, Code: "rate_limited", retryAfter: wait}
+	}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		expected time.Duration
+		tooLate  bool
+	}{
+		{name: "backoff", attempt: 1, err: errors.New("timeout"), expected: time.Second},
+		{name: "backoff doubles", attempt: 3, err: errors.New("timeout"), expected: 4 * time.Second},
+		{name: "Retry-After", attempt: 1, err: rateLimited(3 * time.Second), expected: 3 * time.Second},
+		{name: "Retry-After longer than the backoff", attempt: 1, err: rateLimited(30 * time.Second), expected: 30 * time.Second},
+		{name: "Retry-After past the maximum", attempt: 1, err: rateLimited(time.Hour), tooLate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, err := retry.delay(tt.attempt, tt.err)
+			if tt.tooLate {
+				assert.ErrorContains(t, err, "exceeds max_retry_after of 1m0s")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, delay)
+		})
+	}
+}
@@ -3,8 +3,13 @@ package outputs
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"nomad-events/internal/nomad"
@@ -19,13 +24,22 @@ type SlackOutput struct {
 	textTemplate   string
 	httpClient     *http.Client
 	blockConfigs   []BlockConfig
+	attachments    []AttachmentConfig
 	templateEngine *SlackTemplateEngine
+	onError        templateErrorPolicy
+	api            *slackAPI // Set in api mode
+	threadKey      string    // Thread key template, in api mode
+	dropped        atomic.Uint64
 }
 
 type SlackMessage struct {
 	Channel string      `json:"channel,omitempty"`
 	Text    string      `json:"text,omitempty"`
 	Blocks  interface{} `json:"blocks,omitempty"`
+
+	Attachments []slack.Attachment `json:"attachments,omitempty"`
+
+	threadKey string // Rendered thread key, in api mode
 }
 
 // mapBlockConfig maps a configuration map to a BlockConfig struct
@@ -72,18 +86,47 @@ func mapBlockConfig(config map[string]interface{}) BlockConfig {
 	if v, ok := config["block_id"].(string); ok {
 		block.BlockID = v
 	}
+	if v, ok := config["accessory"]; ok {
+		block.Accessory = v
+	}
+	if v, ok := config["external_id"].(string); ok {
+		block.ExternalID = v
+	}
+	if v, ok := config["source"].(string); ok {
+		block.Source = v
+	}
 
 	return block
 }
 
 func NewSlackOutput(config map[string]interface{}, nomadClient *api.Client) (*SlackOutput, error) {
-	webhookURL, ok := config["webhook_url"].(string)
-	if !ok || webhookURL == "" {
-		return nil, fmt.Errorf("webhook_url is required for Slack output")
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	var webhookURL string
+	var apiClient *slackAPI
+	mode, _ := config["mode"].(string)
+	switch mode {
+	case "", SlackModeWebhook:
+		webhookURL, _ = config["webhook_url"].(string)
+		if webhookURL == "" {
+			return nil, fmt.Errorf("webhook_url is required for Slack output")
+		}
+	case SlackModeAPI:
+		var err error
+		apiClient, err = newSlackAPI(config, httpClient)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid mode %q: must be one of webhook, api", mode)
 	}
 
 	channel, _ := config["channel"].(string)
 	textTemplate, _ := config["text"].(string)
+	threadKey, _ := config["thread_key"].(string)
+	if threadKey != "" && apiClient == nil {
+		return nil, fmt.Errorf("thread_key requires mode: api")
+	}
 
 	var blockConfigs []BlockConfig
 	if blocksConfig, ok := config["blocks"].([]interface{}); ok {
@@ -95,35 +138,113 @@ func NewSlackOutput(config map[string]interface{}, nomadClient *api.Client) (*Sl
 		}
 	}
 
+	var attachments []AttachmentConfig
+	if attachmentsConfig, ok := config["attachments"].([]interface{}); ok {
+		for _, attachmentConfig := range attachmentsConfig {
+			if attachmentMap, ok := attachmentConfig.(map[string]interface{}); ok {
+				attachments = append(attachments, mapAttachmentConfig(attachmentMap))
+			}
+		}
+	}
+
 	var templateEngine *SlackTemplateEngine
-	if len(blockConfigs) > 0 || textTemplate != "" {
+	var onError templateErrorPolicy
+	if len(blockConfigs) > 0 || len(attachments) > 0 || textTemplate != "" || threadKey != "" {
 		templateEngine = NewSlackTemplateEngine(nomadClient)
+
+		// Compile templates up front so syntax errors fail configuration loading
+		if err := templateEngine.engine.Compile(textTemplate); err != nil {
+			return nil, fmt.Errorf("invalid text template: %w", err)
+		}
+		if err := templateEngine.engine.Compile(threadKey); err != nil {
+			return nil, fmt.Errorf("invalid thread_key template: %w", err)
+		}
+		if err := compileTemplates(templateEngine.engine, "blocks", config["blocks"]); err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		if err := compileTemplates(templateEngine.engine, "attachments", config["attachments"]); err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+
+		var err error
+		onError, err = newTemplateErrorPolicy(config, templateEngine.engine, DefaultFallbackTemplate)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &SlackOutput{
 		webhookURL:     webhookURL,
 		channel:        channel,
 		textTemplate:   textTemplate,
-		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		httpClient:     httpClient,
 		blockConfigs:   blockConfigs,
+		attachments:    attachments,
 		templateEngine: templateEngine,
+		onError:        onError,
+		api:            apiClient,
+		threadKey:      threadKey,
 	}, nil
 }
 
 func (o *SlackOutput) Send(event nomad.Event) error {
-	var message SlackMessage
-	var err error
-
-	message, err = o.formatEvent(event)
+	message, err := o.formatEvent(event)
 	if err != nil {
 		return fmt.Errorf("failed to format event: %w", err)
 	}
+	if message == nil {
+		return nil
+	}
+
+	return o.post(*message)
+}
+
+// SendBatch implements the BatchSender interface, posting one message for the
+// whole batch. Templates receive .Events and .GroupLabels instead of a single event.
+func (o *SlackOutput) SendBatch(batch Batch) error {
+	message, err := o.formatBatch(batch)
+	if err != nil {
+		return fmt.Errorf("failed to format event batch: %w", err)
+	}
+	if message == nil {
+		return nil
+	}
+
+	return o.post(*message)
+}
+
+// Dropped returns the number of messages Slack rejected permanently, which
+// are not retried
+func (o *SlackOutput) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// post sends a message, recording it as dropped when Slack rejects it for
+// good, such as for invalid_blocks
+func (o *SlackOutput) post(message SlackMessage) error {
+	err := o.deliver(message)
+
+	var slackErr *SlackError
+	if errors.As(err, &slackErr) && slackErr.Permanent() {
+		o.dropped.Add(1)
+		slog.Error("Slack rejected message, dropping it",
+			"slack_error", slackErr.Code,
+			"status", slackErr.Status,
+			"channel", message.Channel)
+	}
+	return err
+}
 
+func (o *SlackOutput) deliver(message SlackMessage) error {
 	// Check if we should skip sending the message
 	if shouldSkipMessage(message, o.blockConfigs, o.textTemplate) {
 		return nil // Skip sending empty message
 	}
 
+	if o.api != nil {
+		return o.api.send(message)
+	}
+
 	payload, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal Slack message: %w", err)
@@ -136,33 +257,87 @@ func (o *SlackOutput) Send(event nomad.Event) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Slack API returned status %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSlackErrorBody))
+		return fmt.Errorf("failed to send Slack message: %w", webhookError(resp, body))
 	}
 
 	return nil
 }
 
-func (o *SlackOutput) formatEvent(event nomad.Event) (SlackMessage, error) {
-	var blocks []slack.Block
-	var text string
-	var err error
+// formatEvent renders the message for an event, returning nil when the
+// on_template_error policy skips it
+func (o *SlackOutput) formatEvent(event nomad.Event) (*SlackMessage, error) {
+	if o.templateEngine == nil {
+		return &SlackMessage{Channel: o.channel}, nil
+	}
 
-	if o.templateEngine != nil {
-		blocks, err = o.templateEngine.ProcessBlocks(o.blockConfigs, event)
-		if err != nil {
-			return SlackMessage{}, fmt.Errorf("failed to process blocks: %w", err)
-		}
+	return o.formatData(o.templateEngine.CreateTemplateData(event))
+}
 
-		text, err = o.templateEngine.ProcessText(o.textTemplate, event)
-		if err != nil {
-			return SlackMessage{}, fmt.Errorf("failed to process text: %w", err)
+func (o *SlackOutput) formatBatch(batch Batch) (*SlackMessage, error) {
+	if o.templateEngine == nil {
+		return &SlackMessage{Channel: o.channel}, nil
+	}
+
+	return o.formatData(o.templateEngine.CreateBatchTemplateData(batch.Events, batch.GroupLabels))
+}
+
+func (o *SlackOutput) formatData(data map[string]interface{}) (*SlackMessage, error) {
+	message, err := o.renderData(data)
+	if err != nil {
+		// A fallback is sent as plain text without blocks
+		text, deliver, err := o.onError.handle(err, data)
+		if err != nil || !deliver {
+			return nil, err
 		}
+		message = &SlackMessage{Text: text, Channel: o.channel}
+	}
+
+	message.threadKey = o.renderThreadKey(data)
+	return message, nil
+}
+
+// renderThreadKey renders the thread key of a message. A key that fails to
+// render posts a new message rather than dropping it.
+func (o *SlackOutput) renderThreadKey(data map[string]interface{}) string {
+	if o.threadKey == "" {
+		return ""
+	}
+
+	key, err := o.templateEngine.ProcessTextWithData(o.threadKey, data)
+	if err != nil {
+		slog.Warn("Thread key template failed to render, posting a new message", "error", err)
+		return ""
+	}
+
+	// Missing fields render as <no value>, which would thread unrelated events together
+	if strings.Contains(key, "<no value>") {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+func (o *SlackOutput) renderData(data map[string]interface{}) (*SlackMessage, error) {
+	blocks, err := o.templateEngine.ProcessBlocksWithData(o.blockConfigs, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process blocks: %w", err)
 	}
 
-	message := SlackMessage{
-		Text:    text,
-		Blocks:  blocks,
-		Channel: o.channel,
+	attachments, err := o.templateEngine.ProcessAttachmentsWithData(o.attachments, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process attachments: %w", err)
+	}
+
+	text, err := o.templateEngine.ProcessTextWithData(o.textTemplate, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process text: %w", err)
+	}
+
+	message := &SlackMessage{
+		Text:        text,
+		Blocks:      blocks,
+		Attachments: attachments,
+		Channel:     o.channel,
 	}
 
 	return message, nil
@@ -170,6 +345,11 @@ func (o *SlackOutput) formatEvent(event nomad.Event) (SlackMessage, error) {
 
 // shouldSkipMessage determines if a Slack message should be skipped
 func shouldSkipMessage(message SlackMessage, blockConfigs []BlockConfig, textTemplate string) bool {
+	// Attachments are sent even when no blocks or text were generated
+	if len(message.Attachments) > 0 {
+		return false
+	}
+
 	// If we have block configurations but no blocks were generated, skip the message
 	if len(blockConfigs) > 0 {
 		if blocks, ok := message.Blocks.([]slack.Block); ok {
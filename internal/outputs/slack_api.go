@@ -0,0 +1,182 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack output modes, set with mode
+const (
+	SlackModeWebhook = "webhook" // Incoming webhook (default)
+	SlackModeAPI     = "api"     // Web API with a bot token
+)
+
+// How later messages with the same thread key are posted, set with thread_mode
+const (
+	SlackThreadReply  = "reply"  // Reply in the thread of the first message (default)
+	SlackThreadUpdate = "update" // Replace the first message in place
+)
+
+// DefaultSlackThreadTTL is how long a thread key is remembered after its last message
+const DefaultSlackThreadTTL = 24 * time.Hour
+
+// slackAPI posts messages through the Slack Web API with a bot token. Messages
+// with a thread key are threaded under, or replace, the first message posted
+// for that key.
+type slackAPI struct {
+	client *slack.Client
+	mode   string
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	threads map[string]slackThread // thread key -> first message
+}
+
+// slackThread is the first message posted for a thread key
+type slackThread struct {
+	channel string // Channel ID, which chat.update requires
+	ts      string
+	expires time.Time
+}
+
+// newSlackAPI reads the api mode settings of a Slack output
+func newSlackAPI(config map[string]interface{}, httpClient *http.Client) (*slackAPI, error) {
+	token, _ := config["token"].(string)
+	if token == "" {
+		return nil, fmt.Errorf("token is required for Slack output in api mode")
+	}
+
+	if channel, _ := config["channel"].(string); channel == "" {
+		return nil, fmt.Errorf("channel is required for Slack output in api mode")
+	}
+
+	mode, _ := config["thread_mode"].(string)
+	switch mode {
+	case "":
+		mode = SlackThreadReply
+	case SlackThreadReply, SlackThreadUpdate:
+	default:
+		return nil, fmt.Errorf("invalid thread_mode %q: must be one of reply, update", mode)
+	}
+
+	ttl := DefaultSlackThreadTTL
+	if value, ok := config["thread_ttl"].(string); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid thread_ttl %q: use a positive duration like \"24h\"", value)
+		}
+		ttl = d
+	}
+
+	options := []slack.Option{slack.OptionHTTPClient(httpClient)}
+	if apiURL, _ := config["api_url"].(string); apiURL != "" {
+		if !strings.HasSuffix(apiURL, "/") {
+			apiURL += "/"
+		}
+		options = append(options, slack.OptionAPIURL(apiURL))
+	}
+
+	return &slackAPI{
+		client:  slack.New(token, options...),
+		mode:    mode,
+		ttl:     ttl,
+		now:     time.Now,
+		threads: make(map[string]slackThread),
+	}, nil
+}
+
+// send posts a message, threading it under or replacing the first message
+// with the same thread key
+func (a *slackAPI) send(message SlackMessage) error {
+	options := messageOptions(message)
+	if message.threadKey == "" {
+		_, _, err := a.client.PostMessage(message.Channel, options...)
+		return slackAPIError(err)
+	}
+
+	// Held across the API calls, so the first two messages for a key cannot
+	// both start a thread
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	thread, ok := a.threads[message.threadKey]
+	if ok && now.After(thread.expires) {
+		delete(a.threads, message.threadKey)
+		ok = false
+	}
+
+	if ok && a.mode == SlackThreadUpdate {
+		_, _, _, err := a.client.UpdateMessage(thread.channel, thread.ts, options...)
+		if err == nil {
+			thread.expires = now.Add(a.ttl)
+			a.threads[message.threadKey] = thread
+			return nil
+		}
+
+		// The first message was deleted, so a new one takes its place
+		var slackErr slack.SlackErrorResponse
+		if !errors.As(err, &slackErr) || slackErr.Err != "message_not_found" {
+			return slackAPIError(err)
+		}
+		ok = false
+	}
+
+	channel := message.Channel
+	if ok {
+		channel = thread.channel
+		options = append(options, slack.MsgOptionTS(thread.ts))
+	}
+
+	channelID, ts, err := a.client.PostMessage(channel, options...)
+	if err != nil {
+		return slackAPIError(err)
+	}
+
+	if ok {
+		thread.expires = now.Add(a.ttl)
+	} else {
+		a.prune(now)
+		thread = slackThread{channel: channelID, ts: ts, expires: now.Add(a.ttl)}
+	}
+	a.threads[message.threadKey] = thread
+	return nil
+}
+
+// prune forgets thread keys whose last message is older than the TTL
+func (a *slackAPI) prune(now time.Time) {
+	for key, thread := range a.threads {
+		if now.After(thread.expires) {
+			delete(a.threads, key)
+		}
+	}
+}
+
+// messageOptions converts a rendered message to chat.postMessage options
+func messageOptions(message SlackMessage) []slack.MsgOption {
+	var options []slack.MsgOption
+	if message.Text != "" {
+		options = append(options, slack.MsgOptionText(message.Text, false))
+	}
+	if blocks, ok := message.Blocks.([]slack.Block); ok && len(blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(blocks...))
+	}
+	if len(message.Attachments) > 0 {
+		options = append(options, slack.MsgOptionAttachments(message.Attachments...))
+	}
+	return options
+}
+
+func slackAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to send Slack message: %w", apiError(err))
+}
@@ -0,0 +1,250 @@
+package outputs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slackCall is a Web API request received by fakeSlackAPI
+type slackCall struct {
+	method   string
+	channel  string
+	ts       string
+	threadTS string
+	text     string
+	blocks   string
+}
+
+// fakeSlackAPI serves chat.postMessage and chat.update, answering each
+// post with an increasing ts
+type fakeSlackAPI struct {
+	mu     sync.Mutex
+	calls  []slackCall
+	posts  int
+	errors map[string]string // method -> Slack error to return
+}
+
+func (f *fakeSlackAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := slackCall{
+		method:   r.URL.Path[1:],
+		channel:  r.Form.Get("channel"),
+		ts:       r.Form.Get("ts"),
+		threadTS: r.Form.Get("thread_ts"),
+		text:     r.Form.Get("text"),
+		blocks:   r.Form.Get("blocks"),
+	}
+	f.calls = append(f.calls, call)
+
+	w.Header().Set("Content-Type", "application/json")
+	if slackErr := f.errors[call.method]; slackErr != "" {
+		fmt.Fprintf(w, `{"ok": false, "error": %q}`, slackErr)
+		return
+	}
+
+	switch call.method {
+	case "chat.postMessage":
+		f.posts++
+		fmt.Fprintf(w, `{"ok": true, "channel": "C123", "ts": "1700000000.%06d"}`, f.posts)
+	case "chat.update":
+		fmt.Fprintf(w, `{"ok": true, "channel": %q, "ts": %q}`, call.channel, call.ts)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeSlackOutput(t *testing.T, fake *fakeSlackAPI, config map[string]interface{}) *SlackOutput {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	settings := map[string]interface{}{
+		"mode":       "api",
+		"token":      "xoxb-test",
+		"channel":    "#deploys",
+		"api_url":    server.URL,
+		"thread_key": "{{ .Payload.Deployment.ID }}",
+		"text":       "{{ .Payload.Deployment.JobID }} is {{ .Payload.Deployment.Status }}",
+	}
+	for key, value := range config {
+		settings[key] = value
+	}
+
+	output, err := NewSlackOutput(settings, nil)
+	require.NoError(t, err)
+	return output
+}
+
+func deploymentEvent(id, status string) nomad.Event {
+	return nomad.Event{
+		Topic: "Deployment",
+		Type:  "DeploymentStatusUpdate",
+		Payload: map[string]interface{}{
+			"Deployment": map[string]interface{}{"ID": id, "JobID": "web", "Status": status},
+		},
+	}
+}
+
+func TestNewSlackOutputAPIMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		errorMsg string
+	}{
+		{
+			name:   "valid",
+			config: map[string]interface{}{"mode": "api", "token": "xoxb-test", "channel": "#deploys", "thread_key": "{{ .Key }}", "thread_mode": "update", "thread_ttl": "1h"},
+		},
+		{
+			name:     "missing token",
+			config:   map[string]interface{}{"mode": "api", "channel": "#deploys"},
+			errorMsg: "token is required for Slack output in api mode",
+		},
+		{
+			name:     "missing channel",
+			config:   map[string]interface{}{"mode": "api", "token": "xoxb-test"},
+			errorMsg: "channel is required for Slack output in api mode",
+		},
+		{
+			name:     "invalid mode",
+			config:   map[string]interface{}{"mode": "rtm"},
+			errorMsg: `invalid mode "rtm"`,
+		},
+		{
+			name:     "invalid thread mode",
+			config:   map[string]interface{}{"mode": "api", "token": "xoxb-test", "channel": "#deploys", "thread_mode": "edit"},
+			errorMsg: `invalid thread_mode "edit"`,
+		},
+		{
+			name:     "invalid thread ttl",
+			config:   map[string]interface{}{"mode": "api", "token": "xoxb-test", "channel": "#deploys", "thread_ttl": "forever"},
+			errorMsg: `invalid thread_ttl "forever"`,
+		},
+		{
+			name:     "invalid thread key",
+			config:   map[string]interface{}{"mode": "api", "token": "xoxb-test", "channel": "#deploys", "thread_key": "{{ .Key"},
+			errorMsg: "invalid thread_key template",
+		},
+		{
+			name:     "thread key with a webhook",
+			config:   map[string]interface{}{"webhook_url": "https://hooks.slack.com/services/test", "thread_key": "{{ .Key }}"},
+			errorMsg: "thread_key requires mode: api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSlackOutput(tt.config, nil)
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSlackOutputAPIThreadReplies(t *testing.T) {
+	fake := &fakeSlackAPI{}
+	output := newFakeSlackOutput(t, fake, map[string]interface{}{
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "section", "text": "*{{ .Payload.Deployment.Status }}*"},
+		},
+	})
+
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	require.NoError(t, output.Send(deploymentEvent("d2", "running")))
+	require.NoError(t, output.Send(deploymentEvent("d1", "successful")))
+
+	require.Len(t, fake.calls, 3)
+	assert.Equal(t, "chat.postMessage", fake.calls[0].method)
+	assert.Equal(t, "#deploys", fake.calls[0].channel)
+	assert.Empty(t, fake.calls[0].threadTS)
+	assert.Equal(t, "web is running", fake.calls[0].text)
+	assert.Contains(t, fake.calls[0].blocks, "*running*")
+
+	assert.Empty(t, fake.calls[1].threadTS)
+
+	// Replies go to the channel ID of the first message
+	assert.Equal(t, "chat.postMessage", fake.calls[2].method)
+	assert.Equal(t, "C123", fake.calls[2].channel)
+	assert.Equal(t, "1700000000.000001", fake.calls[2].threadTS)
+	assert.Equal(t, "web is successful", fake.calls[2].text)
+}
+
+func TestSlackOutputAPIThreadUpdates(t *testing.T) {
+	fake := &fakeSlackAPI{}
+	output := newFakeSlackOutput(t, fake, map[string]interface{}{"thread_mode": "update"})
+
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	require.NoError(t, output.Send(deploymentEvent("d1", "successful")))
+
+	require.Len(t, fake.calls, 2)
+	assert.Equal(t, "chat.update", fake.calls[1].method)
+	assert.Equal(t, "C123", fake.calls[1].channel)
+	assert.Equal(t, "1700000000.000001", fake.calls[1].ts)
+	assert.Equal(t, "web is successful", fake.calls[1].text)
+
+	// A deleted message is replaced by a new one, which later updates replace
+	fake.errors = map[string]string{"chat.update": "message_not_found"}
+	require.NoError(t, output.Send(deploymentEvent("d1", "failed")))
+	require.Len(t, fake.calls, 4)
+	assert.Equal(t, "chat.postMessage", fake.calls[3].method)
+
+	fake.errors = nil
+	require.NoError(t, output.Send(deploymentEvent("d1", "failed")))
+	assert.Equal(t, "1700000000.000002", fake.calls[4].ts)
+}
+
+func TestSlackOutputAPIThreadExpiry(t *testing.T) {
+	fake := &fakeSlackAPI{}
+	output := newFakeSlackOutput(t, fake, map[string]interface{}{"thread_ttl": "1h"})
+
+	now := time.Now()
+	output.api.now = func() time.Time { return now }
+
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	assert.NotEmpty(t, fake.calls[1].threadTS)
+
+	// The TTL runs from the last message
+	now = now.Add(61 * time.Minute)
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	assert.Empty(t, fake.calls[2].threadTS)
+}
+
+func TestSlackOutputAPIErrors(t *testing.T) {
+	fake := &fakeSlackAPI{errors: map[string]string{"chat.postMessage": "channel_not_found"}}
+	output := newFakeSlackOutput(t, fake, nil)
+
+	err := output.Send(deploymentEvent("d1", "running"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel_not_found")
+
+	// Failed posts do not start a thread
+	fake.errors = nil
+	require.NoError(t, output.Send(deploymentEvent("d1", "running")))
+	assert.Empty(t, fake.calls[1].threadTS)
+
+	// Messages whose key fails to render, or is missing, are posted without a thread
+	require.NoError(t, output.Send(nomad.Event{Topic: "Deployment"}))
+	require.NoError(t, output.Send(nomad.Event{Topic: "Deployment", Payload: map[string]interface{}{"Deployment": map[string]interface{}{}}}))
+	require.NoError(t, output.Send(nomad.Event{Topic: "Deployment", Payload: map[string]interface{}{"Deployment": map[string]interface{}{}}}))
+	require.Len(t, fake.calls, 5)
+	for _, call := range fake.calls[2:] {
+		assert.Empty(t, call.threadTS)
+	}
+}
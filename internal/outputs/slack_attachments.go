@@ -0,0 +1,136 @@
+package outputs
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// AttachmentConfig is a legacy Slack attachment, shown with a colored bar
+// down its side
+type AttachmentConfig struct {
+	Condition string
+	Color     string // good, warning, danger or a hex color such as #2eb886
+	Fallback  string
+	Pretext   string
+	Title     string
+	TitleLink string
+	Text      string
+	Footer    string
+	Fields    []interface{} // Each with title, value and short
+	Blocks    []BlockConfig
+}
+
+// mapAttachmentConfig maps a configuration map to an AttachmentConfig struct
+func mapAttachmentConfig(config map[string]interface{}) AttachmentConfig {
+	var attachment AttachmentConfig
+
+	attachment.Condition, _ = config["condition"].(string)
+	attachment.Color, _ = config["color"].(string)
+	attachment.Fallback, _ = config["fallback"].(string)
+	attachment.Pretext, _ = config["pretext"].(string)
+	attachment.Title, _ = config["title"].(string)
+	attachment.TitleLink, _ = config["title_link"].(string)
+	attachment.Text, _ = config["text"].(string)
+	attachment.Footer, _ = config["footer"].(string)
+	attachment.Fields, _ = config["fields"].([]interface{})
+
+	if blocks, ok := config["blocks"].([]interface{}); ok {
+		for _, block := range blocks {
+			if blockMap, ok := block.(map[string]interface{}); ok {
+				attachment.Blocks = append(attachment.Blocks, mapBlockConfig(blockMap))
+			}
+		}
+	}
+
+	return attachment
+}
+
+// ProcessAttachmentsWithData renders attachments against prepared template
+// data, leaving out those whose condition is false
+func (ste *SlackTemplateEngine) ProcessAttachmentsWithData(attachmentConfigs []AttachmentConfig, eventData map[string]interface{}) ([]slack.Attachment, error) {
+	var attachments []slack.Attachment
+
+	for _, attachmentConfig := range attachmentConfigs {
+		if !ste.evaluateCondition(attachmentConfig.Condition, eventData) {
+			continue
+		}
+
+		attachment, err := ste.processAttachment(attachmentConfig, eventData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+func (ste *SlackTemplateEngine) processAttachment(attachmentConfig AttachmentConfig, eventData map[string]interface{}) (slack.Attachment, error) {
+	attachment := slack.Attachment{
+		MarkdownIn: []string{"pretext", "text", "fields"},
+	}
+
+	texts := []struct {
+		template string
+		target   *string
+	}{
+		{attachmentConfig.Color, &attachment.Color},
+		{attachmentConfig.Fallback, &attachment.Fallback},
+		{attachmentConfig.Pretext, &attachment.Pretext},
+		{attachmentConfig.Title, &attachment.Title},
+		{attachmentConfig.TitleLink, &attachment.TitleLink},
+		{attachmentConfig.Text, &attachment.Text},
+		{attachmentConfig.Footer, &attachment.Footer},
+	}
+	for _, text := range texts {
+		if text.template == "" {
+			continue
+		}
+		processed, err := ste.processText(text.template, eventData)
+		if err != nil {
+			return slack.Attachment{}, err
+		}
+		*text.target = processed
+	}
+
+	fields, err := ste.processItems(attachmentConfig.Fields, eventData, ste.processAttachmentField)
+	if err != nil {
+		return slack.Attachment{}, err
+	}
+	for _, field := range fields {
+		if attachmentField, ok := field.(slack.AttachmentField); ok {
+			attachment.Fields = append(attachment.Fields, attachmentField)
+		}
+	}
+
+	if len(attachmentConfig.Blocks) > 0 {
+		blocks, err := ste.ProcessBlocksWithData(attachmentConfig.Blocks, eventData)
+		if err != nil {
+			return slack.Attachment{}, err
+		}
+		attachment.Blocks = slack.Blocks{BlockSet: blocks}
+	}
+
+	return attachment, nil
+}
+
+func (ste *SlackTemplateEngine) processAttachmentField(fieldConfig interface{}, eventData map[string]interface{}) (interface{}, error) {
+	fieldMap, ok := fieldConfig.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid attachment field configuration")
+	}
+
+	title, err := ste.elementText(fieldMap, "title", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ste.elementText(fieldMap, "value", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	short, _ := fieldMap["short"].(bool)
+	return slack.AttachmentField{Title: title, Value: value, Short: short}, nil
+}
@@ -0,0 +1,96 @@
+package outputs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackTemplateEngineProcessAttachments(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+	attachments := []AttachmentConfig{
+		{
+			Color: `{{ if eq .Payload.Deployment.Status "failed" }}danger{{ else }}good{{ end }}`,
+			Title: "{{ .Payload.Deployment.JobID }} deployment",
+			Text:  "Status: *{{ .Payload.Deployment.Status }}*",
+			Fields: []interface{}{
+				map[string]interface{}{"title": "ID", "value": "{{ .Payload.Deployment.ID }}", "short": true},
+				map[string]interface{}{"condition": "event.Payload.Deployment.Status == 'failed'", "title": "Action", "value": "Investigate"},
+			},
+			Blocks: []BlockConfig{{Type: "context", Elements: []interface{}{"{{ .Topic }}"}}},
+		},
+		{
+			Condition: "event.Payload.Deployment.Status == 'failed'",
+			Color:     "#e01e5a",
+			Text:      "Rolling back",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		status   string
+		color    string
+		fields   int
+		attached int
+	}{
+		{name: "successful", status: "successful", color: "good", fields: 1, attached: 1},
+		{name: "failed", status: "failed", color: "danger", fields: 2, attached: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := engine.CreateTemplateData(deploymentEvent("d1", tt.status))
+			result, err := engine.ProcessAttachmentsWithData(attachments, data)
+			require.NoError(t, err)
+			require.Len(t, result, tt.attached)
+
+			assert.Equal(t, tt.color, result[0].Color)
+			assert.Equal(t, "web deployment", result[0].Title)
+			assert.Equal(t, "Status: *"+tt.status+"*", result[0].Text)
+			require.Len(t, result[0].Fields, tt.fields)
+			assert.Equal(t, "d1", result[0].Fields[0].Value)
+			assert.True(t, result[0].Fields[0].Short)
+			assert.Len(t, result[0].Blocks.BlockSet, 1)
+		})
+	}
+}
+
+func TestSlackOutputSendsAttachments(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	output, err := NewSlackOutput(map[string]interface{}{
+		"webhook_url": server.URL,
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"color": `{{ if eq .Payload.Deployment.Status "failed" }}danger{{ else }}good{{ end }}`,
+				"text":  "{{ .Payload.Deployment.JobID }} is {{ .Payload.Deployment.Status }}",
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, output.Send(deploymentEvent("d1", "failed")))
+	attachments, ok := received["attachments"].([]interface{})
+	require.True(t, ok, "expected attachments in %v", received)
+	require.Len(t, attachments, 1)
+	attachment := attachments[0].(map[string]interface{})
+	assert.Equal(t, "danger", attachment["color"])
+	assert.Equal(t, "web is failed", attachment["text"])
+
+	_, err = NewSlackOutput(map[string]interface{}{
+		"webhook_url": server.URL,
+		"attachments": []interface{}{map[string]interface{}{"color": "{{ .Payload"}},
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "attachments[0].color")
+}
@@ -196,7 +196,7 @@ func TestSlackOutputFallbackToBehavior(t *testing.T) {
 
 	message, err := output.formatEvent(event)
 	assert.Error(t, err)
-	assert.Empty(t, message.Blocks)
+	assert.Nil(t, message)
 }
 
 func TestNewSlackOutputWithTextTemplate(t *testing.T) {
@@ -0,0 +1,450 @@
+package outputs
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// markdownBlock is Slack's markdown block, which renders standard Markdown.
+// slack-go does not have it yet.
+type markdownBlock struct {
+	Type    slack.MessageBlockType `json:"type"`
+	BlockID string                 `json:"block_id,omitempty"`
+	Text    string                 `json:"text"`
+}
+
+func (b markdownBlock) BlockType() slack.MessageBlockType {
+	return b.Type
+}
+
+// richTextList is a bulleted or numbered list in a rich_text block, which
+// slack-go does not have yet
+type richTextList struct {
+	Type     slack.RichTextElementType `json:"type"`
+	Style    string                    `json:"style"`
+	Indent   int                       `json:"indent,omitempty"`
+	Elements []slack.RichTextElement   `json:"elements"`
+}
+
+func (l richTextList) RichTextElementType() slack.RichTextElementType {
+	return l.Type
+}
+
+// richTextEmoji is an emoji in a rich text section. slack-go's always sends a
+// skin_tone, which Slack only accepts from 1 to 6.
+type richTextEmoji struct {
+	Type slack.RichTextSectionElementType `json:"type"`
+	Name string                           `json:"name"`
+}
+
+func (e richTextEmoji) RichTextSectionElementType() slack.RichTextSectionElementType {
+	return e.Type
+}
+
+// processItems renders a list of item configurations, skipping items whose
+// condition is false and expanding range items, as fields and elements are
+func (ste *SlackTemplateEngine) processItems(
+	itemsConfig []interface{},
+	eventData map[string]interface{},
+	itemProcessor func(interface{}, map[string]interface{}) (interface{}, error),
+) ([]interface{}, error) {
+	var items []interface{}
+
+	for _, itemConfig := range itemsConfig {
+		if hasCondition, condition := ste.isConditionItem(itemConfig); hasCondition {
+			if !ste.evaluateCondition(condition, eventData) {
+				continue
+			}
+		}
+
+		if isRange, rangePath := ste.isRangeItem(itemConfig); isRange {
+			expandedItems, err := ste.expandRangeItem(itemConfig, rangePath, eventData, func(templateItem interface{}, itemData map[string]interface{}) (interface{}, error) {
+				if hasCondition, condition := ste.isConditionItem(templateItem); hasCondition {
+					if !ste.evaluateCondition(condition, itemData) {
+						return nil, nil
+					}
+				}
+				return itemProcessor(templateItem, itemData)
+			})
+			if err != nil {
+				if skippable(err) {
+					continue
+				}
+				return nil, err
+			}
+			items = append(items, expandedItems...)
+		} else {
+			item, err := itemProcessor(itemConfig, eventData)
+			if err != nil && !skippable(err) {
+				return nil, err
+			}
+			if err == nil && item != nil {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+func (ste *SlackTemplateEngine) createMarkdownBlock(blockConfig BlockConfig, eventData map[string]interface{}) (slack.Block, error) {
+	text, err := ste.processText(blockConfig.Text, eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &markdownBlock{
+		Type:    "markdown",
+		BlockID: blockConfig.BlockID,
+		Text:    truncateSlackText(text, MaxSlackMarkdownText),
+	}, nil
+}
+
+func (ste *SlackTemplateEngine) createFileBlock(blockConfig BlockConfig, eventData map[string]interface{}) (slack.Block, error) {
+	externalID, err := ste.processText(blockConfig.ExternalID, eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	source := blockConfig.Source
+	if source == "" {
+		source = "remote"
+	}
+
+	return slack.NewFileBlock(blockConfig.BlockID, externalID, source), nil
+}
+
+func (ste *SlackTemplateEngine) createRichTextBlock(blockConfig BlockConfig, eventData map[string]interface{}) (slack.Block, error) {
+	items, err := ste.processItems(blockConfig.Elements, eventData, ste.processRichTextElement)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []slack.RichTextElement
+	for _, item := range items {
+		if element, ok := item.(slack.RichTextElement); ok {
+			elements = append(elements, element)
+		}
+	}
+
+	return slack.NewRichTextBlock(blockConfig.BlockID, elements...), nil
+}
+
+// processRichTextElement renders a rich_text_section, rich_text_preformatted,
+// rich_text_quote or rich_text_list. A string is a section of plain text.
+func (ste *SlackTemplateEngine) processRichTextElement(elemConfig interface{}, eventData map[string]interface{}) (interface{}, error) {
+	elemMap, ok := elemConfig.(map[string]interface{})
+	if !ok {
+		return ste.processRichTextSection(map[string]interface{}{"text": elemConfig}, slack.RTESection, eventData)
+	}
+
+	elemType, _ := elemMap["type"].(string)
+	switch slack.RichTextElementType(elemType) {
+	case "", slack.RTESection, slack.RTEPreformatted, slack.RTEQuote:
+		if elemType == "" {
+			elemType = string(slack.RTESection)
+		}
+		return ste.processRichTextSection(elemMap, slack.RichTextElementType(elemType), eventData)
+	case slack.RTEList:
+		return ste.processRichTextList(elemMap, eventData)
+	default:
+		return nil, fmt.Errorf("unsupported rich text element type: %s", elemType)
+	}
+}
+
+// processRichTextSection renders a section's elements, or its text as a
+// single text element
+func (ste *SlackTemplateEngine) processRichTextSection(elemMap map[string]interface{}, elemType slack.RichTextElementType, eventData map[string]interface{}) (*slack.RichTextSection, error) {
+	elementsConfig, _ := elemMap["elements"].([]interface{})
+	if text, ok := elemMap["text"]; ok && elementsConfig == nil {
+		elementsConfig = []interface{}{text}
+	}
+
+	items, err := ste.processItems(elementsConfig, eventData, ste.processRichTextSectionElement)
+	if err != nil {
+		return nil, err
+	}
+
+	section := &slack.RichTextSection{Type: elemType}
+	for _, item := range items {
+		if element, ok := item.(slack.RichTextSectionElement); ok {
+			section.Elements = append(section.Elements, element)
+		}
+	}
+	return section, nil
+}
+
+func (ste *SlackTemplateEngine) processRichTextList(elemMap map[string]interface{}, eventData map[string]interface{}) (*richTextList, error) {
+	style, _ := elemMap["style"].(string)
+	switch style {
+	case "":
+		style = "bullet"
+	case "bullet", "ordered":
+	default:
+		return nil, fmt.Errorf("invalid rich_text_list style %q: must be one of bullet, ordered", style)
+	}
+
+	elementsConfig, _ := elemMap["elements"].([]interface{})
+	items, err := ste.processItems(elementsConfig, eventData, func(itemConfig interface{}, itemData map[string]interface{}) (interface{}, error) {
+		// List items are always sections
+		itemMap, ok := itemConfig.(map[string]interface{})
+		if !ok {
+			itemMap = map[string]interface{}{"text": itemConfig}
+		}
+		return ste.processRichTextSection(itemMap, slack.RTESection, itemData)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &richTextList{Type: slack.RTEList, Style: style, Indent: intValue(elemMap["indent"])}
+	for _, item := range items {
+		if element, ok := item.(slack.RichTextElement); ok {
+			list.Elements = append(list.Elements, element)
+		}
+	}
+	return list, nil
+}
+
+// processRichTextSectionElement renders a text, link, emoji, user or channel
+// element. A string is plain text. Empty text is left out, as Slack rejects it.
+func (ste *SlackTemplateEngine) processRichTextSectionElement(elemConfig interface{}, eventData map[string]interface{}) (interface{}, error) {
+	elemMap, ok := elemConfig.(map[string]interface{})
+	if !ok {
+		elemMap = map[string]interface{}{"text": elemConfig}
+	}
+
+	elemType, _ := elemMap["type"].(string)
+	switch slack.RichTextSectionElementType(elemType) {
+	case "", slack.RTSEText:
+		text, err := ste.processText(elemMap["text"], eventData)
+		if err != nil || text == "" {
+			return nil, err
+		}
+		return slack.NewRichTextSectionTextElement(text, richTextStyle(elemMap["style"])), nil
+	case slack.RTSELink:
+		url, err := ste.elementText(elemMap, "url", eventData)
+		if err != nil {
+			return nil, err
+		}
+		text, err := ste.elementText(elemMap, "text", eventData)
+		if err != nil {
+			return nil, err
+		}
+		return slack.NewRichTextSectionLinkElement(url, text, richTextStyle(elemMap["style"])), nil
+	case slack.RTSEEmoji:
+		name, err := ste.elementText(elemMap, "name", eventData)
+		if err != nil {
+			return nil, err
+		}
+		return &richTextEmoji{Type: slack.RTSEEmoji, Name: name}, nil
+	case slack.RTSEUser:
+		userID, err := ste.elementText(elemMap, "user_id", eventData)
+		if err != nil {
+			return nil, err
+		}
+		return slack.NewRichTextSectionUserElement(userID, richTextStyle(elemMap["style"])), nil
+	case slack.RTSEChannel:
+		channelID, err := ste.elementText(elemMap, "channel_id", eventData)
+		if err != nil {
+			return nil, err
+		}
+		return slack.NewRichTextSectionChannelElement(channelID, richTextStyle(elemMap["style"])), nil
+	default:
+		return nil, fmt.Errorf("unsupported rich text section element type: %s", elemType)
+	}
+}
+
+// richTextStyle reads a style map such as {bold: true, code: true}
+func richTextStyle(value interface{}) *slack.RichTextSectionTextStyle {
+	styleMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	style := &slack.RichTextSectionTextStyle{}
+	style.Bold, _ = styleMap["bold"].(bool)
+	style.Italic, _ = styleMap["italic"].(bool)
+	style.Strike, _ = styleMap["strike"].(bool)
+	style.Code, _ = styleMap["code"].(bool)
+	return style
+}
+
+// elementText renders an optional string setting of an element, returning
+// an empty string when it is not set
+func (ste *SlackTemplateEngine) elementText(elemMap map[string]interface{}, key string, eventData map[string]interface{}) (string, error) {
+	value, _ := elemMap[key].(string)
+	if value == "" {
+		return "", nil
+	}
+	return ste.processText(value, eventData)
+}
+
+// elementPlaceholder renders the optional placeholder of a menu or picker
+func (ste *SlackTemplateEngine) elementPlaceholder(elemMap map[string]interface{}, eventData map[string]interface{}) (*slack.TextBlockObject, error) {
+	placeholderConfig, ok := elemMap["placeholder"]
+	if !ok {
+		return nil, nil
+	}
+
+	textConfig, err := ste.parseTextConfig(placeholderConfig, eventData)
+	if err != nil {
+		if skippable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Placeholders are always plain text
+	return slack.NewTextBlockObject(slack.PlainTextType, textConfig.Text, textConfig.Emoji, false), nil
+}
+
+// elementOptions renders the options of an element, limited to max
+func (ste *SlackTemplateEngine) elementOptions(elemMap map[string]interface{}, eventData map[string]interface{}, max int) ([]*slack.OptionBlockObject, error) {
+	optionsConfig, _ := elemMap["options"].([]interface{})
+	options, err := ste.processSelectOptions(optionsConfig, eventData)
+	if err != nil {
+		return nil, err
+	}
+	return limitSlackOptions(options, max), nil
+}
+
+// plainTextOptions converts option text to plain text, which overflow and
+// multi-select menus require
+func plainTextOptions(options []*slack.OptionBlockObject) []*slack.OptionBlockObject {
+	for _, option := range options {
+		if option.Text != nil {
+			option.Text.Type = slack.PlainTextType
+		}
+	}
+	return options
+}
+
+func (ste *SlackTemplateEngine) createOverflowElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	actionID, err := ste.elementText(elemMap, "action_id", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := ste.elementOptions(elemMap, eventData, MaxSlackOverflowOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.NewOverflowBlockElement(actionID, plainTextOptions(options)...), nil
+}
+
+func (ste *SlackTemplateEngine) createDatePickerElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	actionID, err := ste.elementText(elemMap, "action_id", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholder, err := ste.elementPlaceholder(elemMap, eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	initialDate, err := ste.elementText(elemMap, "initial_date", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	picker := slack.NewDatePickerBlockElement(actionID)
+	picker.Placeholder = placeholder
+	picker.InitialDate = initialDate
+	return picker, nil
+}
+
+func (ste *SlackTemplateEngine) createCheckboxesElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	actionID, err := ste.elementText(elemMap, "action_id", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := ste.elementOptions(elemMap, eventData, MaxSlackChoiceOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.NewCheckboxGroupsBlockElement(actionID, options...), nil
+}
+
+func (ste *SlackTemplateEngine) createRadioButtonsElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	actionID, err := ste.elementText(elemMap, "action_id", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := ste.elementOptions(elemMap, eventData, MaxSlackChoiceOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.NewRadioButtonsBlockElement(actionID, options...), nil
+}
+
+func (ste *SlackTemplateEngine) createMultiStaticSelectElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	actionID, err := ste.elementText(elemMap, "action_id", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholder, err := ste.elementPlaceholder(elemMap, eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := ste.elementOptions(elemMap, eventData, MaxSlackSelectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	multiSelect := slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeStatic, placeholder, actionID, plainTextOptions(options)...)
+	if maxSelected := intValue(elemMap["max_selected_items"]); maxSelected > 0 {
+		multiSelect.MaxSelectedItems = &maxSelected
+	}
+	return multiSelect, nil
+}
+
+func (ste *SlackTemplateEngine) createImageElement(elemMap map[string]interface{}, eventData map[string]interface{}) (slack.BlockElement, error) {
+	imageURL, err := ste.elementText(elemMap, "image_url", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	altText, err := ste.elementText(elemMap, "alt_text", eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.NewImageBlockElement(imageURL, altText), nil
+}
+
+// processAccessory renders a section's accessory, returning nil when its
+// condition is false
+func (ste *SlackTemplateEngine) processAccessory(accessoryConfig interface{}, eventData map[string]interface{}) (*slack.Accessory, error) {
+	if hasCondition, condition := ste.isConditionItem(accessoryConfig); hasCondition {
+		if !ste.evaluateCondition(condition, eventData) {
+			return nil, nil
+		}
+	}
+
+	element, err := ste.processElement(accessoryConfig, eventData)
+	if err != nil {
+		return nil, err
+	}
+	return slack.NewAccessory(element), nil
+}
+
+// intValue reads an integer setting, which YAML decodes as int and JSON as float64
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,223 @@
+package outputs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockJSON renders a block the way it is sent to Slack
+func blockJSON(t *testing.T, block interface{}) string {
+	encoded, err := json.Marshal(block)
+	require.NoError(t, err)
+	return string(encoded)
+}
+
+func allocationData() map[string]interface{} {
+	return map[string]interface{}{
+		"Topic": "Allocation",
+		"Payload": map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":    "8ba85cef",
+				"JobID": "web",
+				"Tasks": []interface{}{
+					map[string]interface{}{"Name": "nginx", "State": "dead"},
+					map[string]interface{}{"Name": "envoy", "State": "running"},
+				},
+			},
+		},
+	}
+}
+
+func TestSlackTemplateEngineRichTextBlock(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+
+	block, err := engine.processBlock(BlockConfig{
+		Type: "rich_text",
+		Elements: []interface{}{
+			map[string]interface{}{
+				"elements": []interface{}{
+					map[string]interface{}{"text": "{{ .Payload.Allocation.JobID }}", "style": map[string]interface{}{"bold": true}},
+					" failed on ",
+					map[string]interface{}{"type": "link", "url": "https://nomad.example.com/ui/allocations/{{ .Payload.Allocation.ID }}", "text": "{{ .Payload.Allocation.ID }}"},
+					map[string]interface{}{"type": "emoji", "name": "fire"},
+				},
+			},
+			map[string]interface{}{
+				"type": "rich_text_list",
+				"elements": []interface{}{
+					map[string]interface{}{"range": ".Payload.Allocation.Tasks", "text": "{{ .Name }}: {{ .State }}"},
+				},
+			},
+			map[string]interface{}{"type": "rich_text_preformatted", "text": "exit code 137"},
+			map[string]interface{}{"type": "rich_text_quote", "text": "{{ .Missing }}", "condition": "event.Topic == 'Node'"},
+		},
+	}, allocationData())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"type": "rich_text",
+		"elements": [
+			{"type": "rich_text_section", "elements": [
+				{"type": "text", "text": "web", "style": {"bold": true}},
+				{"type": "text", "text": " failed on "},
+				{"type": "link", "url": "https://nomad.example.com/ui/allocations/8ba85cef", "text": "8ba85cef"},
+				{"type": "emoji", "name": "fire"}
+			]},
+			{"type": "rich_text_list", "style": "bullet", "elements": [
+				{"type": "rich_text_section", "elements": [{"type": "text", "text": "nginx: dead"}]},
+				{"type": "rich_text_section", "elements": [{"type": "text", "text": "envoy: running"}]}
+			]},
+			{"type": "rich_text_preformatted", "elements": [{"type": "text", "text": "exit code 137"}]}
+		]
+	}`, blockJSON(t, block))
+}
+
+func TestSlackTemplateEngineMarkdownAndFileBlocks(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+
+	block, err := engine.processBlock(BlockConfig{Type: "markdown", Text: "**{{ .Payload.Allocation.JobID }}** failed"}, allocationData())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "markdown", "text": "**web** failed"}`, blockJSON(t, block))
+
+	block, err = engine.processBlock(BlockConfig{Type: "file", ExternalID: "logs-{{ .Payload.Allocation.ID }}"}, allocationData())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "file", "external_id": "logs-8ba85cef", "source": "remote"}`, blockJSON(t, block))
+}
+
+func TestSlackTemplateEngineElements(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+	taskOptions := []interface{}{
+		map[string]interface{}{"range": ".Payload.Allocation.Tasks", "text": "{{ .Name }}", "value": "{{ .Name }}"},
+	}
+
+	tests := []struct {
+		name     string
+		element  map[string]interface{}
+		expected string
+	}{
+		{
+			name:    "overflow",
+			element: map[string]interface{}{"type": "overflow", "action_id": "restart-{{ .Payload.Allocation.ID }}", "options": taskOptions},
+			expected: `{"type": "overflow", "action_id": "restart-8ba85cef", "options": [
+				{"text": {"type": "plain_text", "text": "nginx"}, "value": "nginx"},
+				{"text": {"type": "plain_text", "text": "envoy"}, "value": "envoy"}
+			]}`,
+		},
+		{
+			name:     "datepicker",
+			element:  map[string]interface{}{"type": "datepicker", "action_id": "silence_until", "placeholder": "Silence until", "initial_date": "2024-01-02"},
+			expected: `{"type": "datepicker", "action_id": "silence_until", "placeholder": {"type": "plain_text", "text": "Silence until"}, "initial_date": "2024-01-02"}`,
+		},
+		{
+			name:    "checkboxes",
+			element: map[string]interface{}{"type": "checkboxes", "action_id": "tasks", "options": taskOptions},
+			expected: `{"type": "checkboxes", "action_id": "tasks", "options": [
+				{"text": {"type": "mrkdwn", "text": "nginx"}, "value": "nginx"},
+				{"text": {"type": "mrkdwn", "text": "envoy"}, "value": "envoy"}
+			]}`,
+		},
+		{
+			name:    "radio buttons",
+			element: map[string]interface{}{"type": "radio_buttons", "action_id": "task", "options": taskOptions},
+			expected: `{"type": "radio_buttons", "action_id": "task", "options": [
+				{"text": {"type": "mrkdwn", "text": "nginx"}, "value": "nginx"},
+				{"text": {"type": "mrkdwn", "text": "envoy"}, "value": "envoy"}
+			]}`,
+		},
+		{
+			name:    "multi-select",
+			element: map[string]interface{}{"type": "multi_static_select", "action_id": "tasks", "placeholder": "Tasks", "max_selected_items": 2, "options": taskOptions},
+			expected: `{"type": "multi_static_select", "action_id": "tasks", "placeholder": {"type": "plain_text", "text": "Tasks"}, "max_selected_items": 2, "options": [
+				{"text": {"type": "plain_text", "text": "nginx"}, "value": "nginx"},
+				{"text": {"type": "plain_text", "text": "envoy"}, "value": "envoy"}
+			]}`,
+		},
+		{
+			name:     "image",
+			element:  map[string]interface{}{"type": "image", "image_url": "https://example.com/{{ .Payload.Allocation.JobID }}.png", "alt_text": "{{ .Payload.Allocation.JobID }}"},
+			expected: `{"type": "image", "image_url": "https://example.com/web.png", "alt_text": "web"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			element, err := engine.processElement(tt.element, allocationData())
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, blockJSON(t, element))
+		})
+	}
+}
+
+func TestSlackTemplateEngineSectionAccessory(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+
+	block, err := engine.createSectionBlock(BlockConfig{
+		Type: "section",
+		Text: "*{{ .Payload.Allocation.JobID }}* failed",
+		Accessory: map[string]interface{}{
+			"type":      "button",
+			"text":      map[string]interface{}{"type": "plain_text", "text": "Restart"},
+			"action_id": "restart_alloc",
+			"value":     "{{ .Payload.Allocation.ID }}",
+		},
+	}, allocationData())
+	require.NoError(t, err)
+
+	section := block.(*slack.SectionBlock)
+	require.NotNil(t, section.Accessory)
+	require.NotNil(t, section.Accessory.ButtonElement)
+	assert.Equal(t, "8ba85cef", section.Accessory.ButtonElement.Value)
+
+	// Accessories whose condition is false are left out
+	block, err = engine.createSectionBlock(BlockConfig{
+		Type: "section",
+		Text: "*{{ .Payload.Allocation.JobID }}* failed",
+		Accessory: map[string]interface{}{
+			"condition": "event.Topic == 'Node'",
+			"type":      "button",
+			"text":      "Drain",
+		},
+	}, allocationData())
+	require.NoError(t, err)
+	assert.Nil(t, block.(*slack.SectionBlock).Accessory)
+}
+
+func TestNewSlackOutputWithNewBlockTypes(t *testing.T) {
+	output, err := NewSlackOutput(map[string]interface{}{
+		"webhook_url": "https://hooks.slack.com/services/test",
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "markdown", "text": "**{{ .Topic }}**"},
+			map[string]interface{}{"type": "file", "external_id": "{{ .Key }}", "source": "remote"},
+			map[string]interface{}{
+				"type": "section",
+				"text": "{{ .Topic }}",
+				"accessory": map[string]interface{}{
+					"type":      "overflow",
+					"action_id": "alloc_actions",
+					"options": []interface{}{
+						map[string]interface{}{"text": "Restart", "value": "restart"},
+						map[string]interface{}{"text": "Stop", "value": "stop"},
+					},
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, output.blockConfigs, 3)
+	assert.Equal(t, "{{ .Key }}", output.blockConfigs[1].ExternalID)
+	assert.Equal(t, "remote", output.blockConfigs[1].Source)
+	assert.NotNil(t, output.blockConfigs[2].Accessory)
+
+	_, err = NewSlackOutput(map[string]interface{}{
+		"webhook_url": "https://hooks.slack.com/services/test",
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "section", "text": "ok", "accessory": map[string]interface{}{"type": "button", "text": "{{ .Topic"}},
+		},
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "blocks[0].accessory.text")
+}
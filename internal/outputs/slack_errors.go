@@ -0,0 +1,91 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// SlackError is a message Slack did not accept, from a webhook or the Web API
+type SlackError struct {
+	Status     int           // HTTP status, when Slack answered with one other than 200
+	Code       string        // Slack's error string, such as invalid_blocks or rate_limited
+	retryAfter time.Duration // From the Retry-After header of rate limited requests
+}
+
+func (e *SlackError) Error() string {
+	code := e.Code
+	if code == "" {
+		code = "an error"
+	}
+	if e.Status != 0 {
+		return fmt.Sprintf("Slack returned %s (status %d)", code, e.Status)
+	}
+	return fmt.Sprintf("Slack returned %s", code)
+}
+
+// RetryAfter is how long Slack asked for before the message is retried, or
+// zero when it did not say
+func (e *SlackError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// Permanent reports whether sending the same message again cannot succeed,
+// such as for invalid_blocks or channel_not_found. Rate limits, server errors
+// and Slack's own internal errors are retryable.
+func (e *SlackError) Permanent() bool {
+	if e.Status == http.StatusTooManyRequests || e.Status >= 500 {
+		return false
+	}
+
+	switch e.Code {
+	case "rate_limited", "ratelimited", "internal_error", "fatal_error", "service_unavailable", "request_timeout":
+		return false
+	}
+	return true
+}
+
+// maxSlackErrorBody bounds how much of a webhook's error response is read
+const maxSlackErrorBody = 1024
+
+// webhookError reads the error of a webhook response other than 200. Webhooks
+// answer with the error string as plain text.
+func webhookError(resp *http.Response, body []byte) *SlackError {
+	code := strings.TrimSpace(string(body))
+	if len(code) > maxSlackErrorBody {
+		code = code[:maxSlackErrorBody]
+	}
+
+	err := &SlackError{Status: resp.StatusCode, Code: code}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+		err.retryAfter = time.Duration(seconds) * time.Second
+	}
+	if err.Code == "" && resp.StatusCode == http.StatusTooManyRequests {
+		err.Code = "rate_limited"
+	}
+	return err
+}
+
+// apiError converts the errors of the Slack Web API client to a SlackError.
+// Other errors, such as failed connections, are returned unchanged.
+func apiError(err error) error {
+	var rateLimited *slack.RateLimitedError
+	var response slack.SlackErrorResponse
+	var status slack.StatusCodeError
+
+	switch {
+	case errors.As(err, &rateLimited):
+		return &SlackError{Status: http.StatusTooManyRequests, Code: "rate_limited", retryAfter: rateLimited.RetryAfter}
+	case errors.As(err, &response):
+		return &SlackError{Code: response.Err}
+	case errors.As(err, &status):
+		return &SlackError{Status: status.Code}
+	default:
+		return err
+	}
+}
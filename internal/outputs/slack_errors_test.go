@@ -0,0 +1,130 @@
+package outputs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackErrorPermanent(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       SlackError
+		permanent bool
+	}{
+		{name: "invalid blocks", err: SlackError{Status: 400, Code: "invalid_blocks"}, permanent: true},
+		{name: "channel not found", err: SlackError{Code: "channel_not_found"}, permanent: true},
+		{name: "no text", err: SlackError{Status: 400, Code: "no_text"}, permanent: true},
+		{name: "rate limited status", err: SlackError{Status: 429}, permanent: false},
+		{name: "rate limited code", err: SlackError{Code: "ratelimited"}, permanent: false},
+		{name: "server error", err: SlackError{Status: 503}, permanent: false},
+		{name: "internal error", err: SlackError{Code: "internal_error"}, permanent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.permanent, tt.err.Permanent())
+		})
+	}
+}
+
+func TestSlackOutputWebhookErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		code       string
+		wait       time.Duration
+		permanent  bool
+	}{
+		{name: "invalid blocks", status: 400, body: "invalid_blocks", code: "invalid_blocks", permanent: true},
+		{name: "rate limited", status: 429, retryAfter: "30", code: "rate_limited", wait: 30 * time.Second},
+		{name: "server error", status: 500, body: "internal_error\n", code: "internal_error"},
+		{name: "invalid Retry-After", status: 429, retryAfter: "soon", body: "rate_limited", code: "rate_limited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			output, err := NewSlackOutput(map[string]interface{}{
+				"webhook_url": server.URL,
+				"text":        "{{ .Payload.Deployment.JobID }} is {{ .Payload.Deployment.Status }}",
+			}, nil)
+			require.NoError(t, err)
+
+			err = output.Send(deploymentEvent("d1", "running"))
+			var slackErr *SlackError
+			require.True(t, errors.As(err, &slackErr), "expected a SlackError, got %v", err)
+			assert.Equal(t, tt.status, slackErr.Status)
+			assert.Equal(t, tt.code, slackErr.Code)
+			assert.Equal(t, tt.wait, slackErr.RetryAfter())
+			assert.Equal(t, tt.permanent, slackErr.Permanent())
+			assert.Contains(t, err.Error(), tt.code)
+
+			// Only messages Slack rejects for good are dropped
+			expected := uint64(0)
+			if tt.permanent {
+				expected = 1
+			}
+			assert.Equal(t, expected, output.Dropped())
+		})
+	}
+}
+
+func TestSlackOutputAPIErrorTypes(t *testing.T) {
+	t.Run("rejected message", func(t *testing.T) {
+		fake := &fakeSlackAPI{errors: map[string]string{"chat.postMessage": "invalid_blocks"}}
+		output := newFakeSlackOutput(t, fake, nil)
+
+		err := output.Send(deploymentEvent("d1", "running"))
+		var slackErr *SlackError
+		require.True(t, errors.As(err, &slackErr))
+		assert.Equal(t, "invalid_blocks", slackErr.Code)
+		assert.True(t, slackErr.Permanent())
+		assert.Equal(t, uint64(1), output.Dropped())
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		output, err := NewSlackOutput(map[string]interface{}{
+			"mode":    "api",
+			"token":   "xoxb-test",
+			"channel": "#deploys",
+			"api_url": server.URL,
+			"text":    "{{ .Payload.Deployment.JobID }} is {{ .Payload.Deployment.Status }}",
+		}, nil)
+		require.NoError(t, err)
+
+		err = output.Send(deploymentEvent("d1", "running"))
+		var slackErr *SlackError
+		require.True(t, errors.As(err, &slackErr), "expected a SlackError, got %v", err)
+		assert.Equal(t, "rate_limited", slackErr.Code)
+		assert.Equal(t, 7*time.Second, slackErr.RetryAfter())
+		assert.False(t, slackErr.Permanent())
+		assert.Zero(t, output.Dropped())
+	})
+
+	t.Run("connection errors are unchanged", func(t *testing.T) {
+		err := errors.New("connection refused")
+		assert.Equal(t, err, apiError(err))
+	})
+}
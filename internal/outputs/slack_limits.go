@@ -0,0 +1,119 @@
+package outputs
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack's Block Kit limits. Messages over them are rejected with
+// invalid_blocks, so rendered blocks are truncated to fit.
+const (
+	MaxSlackBlocks          = 50    // Blocks in a message
+	MaxSlackHeaderText      = 150   // Characters in a header
+	MaxSlackSectionText     = 3000  // Characters in a section's text
+	MaxSlackMarkdownText    = 12000 // Characters in a markdown block
+	MaxSlackSectionFields   = 10    // Fields in a section
+	MaxSlackFieldText       = 2000  // Characters in a section field
+	MaxSlackContextElements = 10    // Elements in a context block
+	MaxSlackActionElements  = 25    // Elements in an actions block
+	MaxSlackSelectOptions   = 100   // Options in a select menu
+	MaxSlackOverflowOptions = 5     // Options in an overflow menu
+	MaxSlackChoiceOptions   = 10    // Options in a checkbox group or radio buttons
+)
+
+// moreText is the line that stands in for truncated items
+func moreText(n int) string {
+	return fmt.Sprintf("…and %d more", n)
+}
+
+// truncateSlackText shortens text to at most max characters. Whole lines are
+// kept where possible, followed by how many lines were left out, since long
+// text is usually a list rendered with range; otherwise the text is cut
+// short with an ellipsis.
+func truncateSlackText(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	slog.Debug("Slack text exceeds limit, truncating", "limit", max, "length", utf8.RuneCountInString(text))
+
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	length := 0
+	for keep := 0; keep < len(lines); keep++ {
+		// Length of the kept lines plus the newline before the suffix
+		next := length + utf8.RuneCountInString(lines[keep]) + 1
+		suffix := utf8.RuneCountInString(moreText(len(lines) - keep - 1))
+		if next+suffix > max {
+			if keep == 0 {
+				break
+			}
+			return strings.Join(lines[:keep], "\n") + "\n" + moreText(len(lines)-keep)
+		}
+		length = next
+	}
+
+	runes := []rune(text)
+	return string(runes[:max-1]) + "…"
+}
+
+// limitSlackBlocks keeps a message within MaxSlackBlocks, replacing the blocks
+// left out with a context block saying how many there were
+func limitSlackBlocks(blocks []slack.Block) []slack.Block {
+	if len(blocks) <= MaxSlackBlocks {
+		return blocks
+	}
+	slog.Debug("Slack message exceeds block limit, truncating", "limit", MaxSlackBlocks, "blocks", len(blocks))
+
+	more := slack.NewTextBlockObject(slack.MarkdownType, moreText(len(blocks)-MaxSlackBlocks+1), false, false)
+	return append(blocks[:MaxSlackBlocks-1:MaxSlackBlocks-1], slack.NewContextBlock("", more))
+}
+
+// limitSlackFields keeps a section within MaxSlackSectionFields and each
+// field within MaxSlackFieldText
+func limitSlackFields(fields []*slack.TextBlockObject) []*slack.TextBlockObject {
+	if len(fields) > MaxSlackSectionFields {
+		slog.Debug("Slack section exceeds field limit, truncating", "limit", MaxSlackSectionFields, "fields", len(fields))
+		more := slack.NewTextBlockObject(fields[MaxSlackSectionFields-1].Type, moreText(len(fields)-MaxSlackSectionFields+1), false, false)
+		fields = append(fields[:MaxSlackSectionFields-1:MaxSlackSectionFields-1], more)
+	}
+
+	for _, field := range fields {
+		field.Text = truncateSlackText(field.Text, MaxSlackFieldText)
+	}
+	return fields
+}
+
+// limitSlackContextElements keeps a context block within MaxSlackContextElements
+func limitSlackContextElements(elements []slack.MixedElement) []slack.MixedElement {
+	if len(elements) <= MaxSlackContextElements {
+		return elements
+	}
+	slog.Debug("Slack context block exceeds element limit, truncating", "limit", MaxSlackContextElements, "elements", len(elements))
+
+	more := slack.NewTextBlockObject(slack.MarkdownType, moreText(len(elements)-MaxSlackContextElements+1), false, false)
+	return append(elements[:MaxSlackContextElements-1:MaxSlackContextElements-1], more)
+}
+
+// limitSlackActionElements keeps an actions block within
+// MaxSlackActionElements. Actions blocks cannot hold text, so the rest are
+// left out without a note.
+func limitSlackActionElements(elements []slack.BlockElement) []slack.BlockElement {
+	if len(elements) <= MaxSlackActionElements {
+		return elements
+	}
+	slog.Debug("Slack actions block exceeds element limit, truncating", "limit", MaxSlackActionElements, "elements", len(elements))
+	return elements[:MaxSlackActionElements]
+}
+
+// limitSlackOptions keeps a menu or choice element within max options. Every
+// option can be chosen, so the rest are left out without a note.
+func limitSlackOptions(options []*slack.OptionBlockObject, max int) []*slack.OptionBlockObject {
+	if len(options) <= max {
+		return options
+	}
+	slog.Debug("Slack element exceeds option limit, truncating", "limit", max, "options", len(options))
+	return options[:max]
+}
@@ -0,0 +1,137 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateSlackText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		max      int
+		expected string
+	}{
+		{name: "within limit", text: "web is running", max: 20, expected: "web is running"},
+		{name: "at limit", text: "12345", max: 5, expected: "12345"},
+		{name: "whole lines", text: "• web\n• api\n• worker\n• cron\n• batch", max: 30, expected: "• web\n• api\n…and 3 more"},
+		{name: "trailing newline", text: "• web\n• api\n• worker\n• cron\n• batch\n", max: 30, expected: "• web\n• api\n…and 3 more"},
+		{name: "single line", text: "allocation failed to start", max: 10, expected: "allocatio…"},
+		{name: "first line too long", text: "allocation failed to start\nretrying", max: 10, expected: "allocatio…"},
+		{name: "multibyte", text: "🟢🟢🟢🟢🟢🟢", max: 4, expected: "🟢🟢🟢…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := truncateSlackText(tt.text, tt.max)
+			assert.Equal(t, tt.expected, result)
+			assert.LessOrEqual(t, utf8.RuneCountInString(result), tt.max)
+		})
+	}
+}
+
+func TestSlackTemplateEngineLimits(t *testing.T) {
+	engine := NewSlackTemplateEngine(nil)
+
+	var services []interface{}
+	for i := 0; i < 120; i++ {
+		services = append(services, map[string]interface{}{"Name": fmt.Sprintf("service-%d", i)})
+	}
+	eventData := map[string]interface{}{
+		"Payload": map[string]interface{}{"Services": services},
+	}
+
+	t.Run("header", func(t *testing.T) {
+		block, err := engine.createHeaderBlock(BlockConfig{Type: "header", Text: strings.Repeat("a", 200)}, eventData)
+		require.NoError(t, err)
+
+		header := block.(*slack.HeaderBlock)
+		assert.Equal(t, MaxSlackHeaderText, utf8.RuneCountInString(header.Text.Text))
+		assert.True(t, strings.HasSuffix(header.Text.Text, "…"))
+	})
+
+	t.Run("section text and fields", func(t *testing.T) {
+		block, err := engine.createSectionBlock(BlockConfig{
+			Type: "section",
+			Text: `{{ range .Payload.Services }}• {{ .Name }} is running and healthy on its node{{ "\n" }}{{ end }}`,
+			Fields: []interface{}{
+				map[string]interface{}{"range": ".Payload.Services", "text": "*{{ .Name }}*"},
+			},
+		}, eventData)
+		require.NoError(t, err)
+
+		section := block.(*slack.SectionBlock)
+		assert.LessOrEqual(t, utf8.RuneCountInString(section.Text.Text), MaxSlackSectionText)
+		assert.Regexp(t, `\n…and \d+ more$`, section.Text.Text)
+
+		require.Len(t, section.Fields, MaxSlackSectionFields)
+		assert.Equal(t, "*service-8*", section.Fields[8].Text)
+		assert.Equal(t, "…and 111 more", section.Fields[9].Text)
+	})
+
+	t.Run("context elements", func(t *testing.T) {
+		block, err := engine.createContextBlock(BlockConfig{
+			Type: "context",
+			Elements: []interface{}{
+				map[string]interface{}{"range": ".Payload.Services", "text": "{{ .Name }}"},
+			},
+		}, eventData)
+		require.NoError(t, err)
+
+		context := block.(*slack.ContextBlock)
+		require.Len(t, context.ContextElements.Elements, MaxSlackContextElements)
+		assert.Equal(t, "…and 111 more", context.ContextElements.Elements[9].(*slack.TextBlockObject).Text)
+	})
+
+	t.Run("actions and options", func(t *testing.T) {
+		block, err := engine.createActionBlock(BlockConfig{
+			Type: "actions",
+			Elements: []interface{}{
+				map[string]interface{}{"range": ".Payload.Services", "type": "button", "text": "{{ .Name }}", "value": "{{ .Name }}"},
+				map[string]interface{}{
+					"type":    "static_select",
+					"options": []interface{}{map[string]interface{}{"range": ".Payload.Services", "text": "{{ .Name }}", "value": "{{ .Name }}"}},
+				},
+			},
+		}, eventData)
+		require.NoError(t, err)
+
+		actions := block.(*slack.ActionBlock)
+		assert.Len(t, actions.Elements.ElementSet, MaxSlackActionElements)
+
+		block, err = engine.createActionBlock(BlockConfig{
+			Type: "actions",
+			Elements: []interface{}{
+				map[string]interface{}{
+					"type":    "static_select",
+					"options": []interface{}{map[string]interface{}{"range": ".Payload.Services", "text": "{{ .Name }}", "value": "{{ .Name }}"}},
+				},
+			},
+		}, eventData)
+		require.NoError(t, err)
+
+		menu := block.(*slack.ActionBlock).Elements.ElementSet[0].(*slack.SelectBlockElement)
+		assert.Len(t, menu.Options, MaxSlackSelectOptions)
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		var configs []BlockConfig
+		for i := 0; i < 60; i++ {
+			configs = append(configs, BlockConfig{Type: "section", Text: fmt.Sprintf("block %d", i)})
+		}
+
+		blocks, err := engine.ProcessBlocksWithData(configs, eventData)
+		require.NoError(t, err)
+		require.Len(t, blocks, MaxSlackBlocks)
+		assert.Equal(t, "block 48", blocks[48].(*slack.SectionBlock).Text.Text)
+
+		more := blocks[49].(*slack.ContextBlock)
+		assert.Equal(t, "…and 11 more", more.ContextElements.Elements[0].(*slack.TextBlockObject).Text)
+	})
+}
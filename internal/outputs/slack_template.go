@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -33,6 +34,10 @@ type BlockConfig struct {
 	Hint      interface{}   `yaml:"hint,omitempty"`
 	Optional  bool          `yaml:"optional,omitempty"`
 	BlockID   string        `yaml:"block_id,omitempty"`
+	Accessory interface{}   `yaml:"accessory,omitempty"`
+	// File blocks
+	ExternalID string `yaml:"external_id,omitempty"`
+	Source     string `yaml:"source,omitempty"`
 }
 
 type TextConfig struct {
@@ -73,9 +78,12 @@ func NewSlackTemplateEngine(nomadClient *api.Client) *SlackTemplateEngine {
 }
 
 func (ste *SlackTemplateEngine) ProcessBlocks(blockConfigs []BlockConfig, event nomad.Event) ([]slack.Block, error) {
-	var blocks []slack.Block
+	return ste.ProcessBlocksWithData(blockConfigs, ste.engine.CreateTemplateData(event))
+}
 
-	eventData := ste.engine.CreateTemplateData(event)
+// ProcessBlocksWithData renders blocks against prepared template data, such as batch data
+func (ste *SlackTemplateEngine) ProcessBlocksWithData(blockConfigs []BlockConfig, eventData map[string]interface{}) ([]slack.Block, error) {
+	var blocks []slack.Block
 
 	for _, blockConfig := range blockConfigs {
 		// Check condition before processing block
@@ -113,13 +121,25 @@ func (ste *SlackTemplateEngine) ProcessBlocks(blockConfigs []BlockConfig, event
 		}
 	}
 
-	return blocks, nil
+	return limitSlackBlocks(blocks), nil
 }
 
 func (ste *SlackTemplateEngine) ProcessText(text string, event nomad.Event) (string, error) {
 	return ste.engine.ProcessText(text, event)
 }
 
+func (ste *SlackTemplateEngine) ProcessTextWithData(text string, eventData map[string]interface{}) (string, error) {
+	return ste.engine.ProcessTextWithData(text, eventData)
+}
+
+func (ste *SlackTemplateEngine) CreateTemplateData(event nomad.Event) map[string]interface{} {
+	return ste.engine.CreateTemplateData(event)
+}
+
+func (ste *SlackTemplateEngine) CreateBatchTemplateData(events []nomad.Event, groupLabels map[string]string) map[string]interface{} {
+	return ste.engine.CreateBatchTemplateData(events, groupLabels)
+}
+
 func (ste *SlackTemplateEngine) processBlock(blockConfig BlockConfig, eventData map[string]interface{}) (slack.Block, error) {
 	switch blockConfig.Type {
 	case "header":
@@ -136,6 +156,12 @@ func (ste *SlackTemplateEngine) processBlock(blockConfig BlockConfig, eventData
 		return ste.createImageBlock(blockConfig, eventData)
 	case "input":
 		return ste.createInputBlock(blockConfig, eventData)
+	case "rich_text":
+		return ste.createRichTextBlock(blockConfig, eventData)
+	case "markdown":
+		return ste.createMarkdownBlock(blockConfig, eventData)
+	case "file":
+		return ste.createFileBlock(blockConfig, eventData)
 	default:
 		return nil, fmt.Errorf("unsupported block type: %s", blockConfig.Type)
 	}
@@ -147,7 +173,7 @@ func (ste *SlackTemplateEngine) createHeaderBlock(blockConfig BlockConfig, event
 		return nil, err
 	}
 
-	textObj := slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+	textObj := slack.NewTextBlockObject(slack.PlainTextType, truncateSlackText(text, MaxSlackHeaderText), false, false)
 	return slack.NewHeaderBlock(textObj), nil
 }
 
@@ -164,15 +190,24 @@ func (ste *SlackTemplateEngine) createSectionBlock(blockConfig BlockConfig, even
 		if err != nil {
 			return nil, err
 		}
-		textObj = slack.NewTextBlockObject(textConfig.Type, textConfig.Text, textConfig.Emoji, false)
+		textObj = slack.NewTextBlockObject(textConfig.Type, truncateSlackText(textConfig.Text, MaxSlackSectionText), textConfig.Emoji, false)
 	}
 
 	processedFields, err := ste.processFields(blockConfig.Fields, eventData)
-	if err == nil {
-		fields = processedFields
+	if err != nil {
+		return nil, err
 	}
+	fields = limitSlackFields(processedFields)
 
-	return slack.NewSectionBlock(textObj, fields, nil), nil
+	var accessory *slack.Accessory
+	if blockConfig.Accessory != nil {
+		accessory, err = ste.processAccessory(blockConfig.Accessory, eventData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return slack.NewSectionBlock(textObj, fields, accessory), nil
 }
 
 func (ste *SlackTemplateEngine) processFields(fieldsConfig []interface{}, eventData map[string]interface{}) ([]*slack.TextBlockObject, error) {
@@ -189,7 +224,10 @@ func (ste *SlackTemplateEngine) processFields(fieldsConfig []interface{}, eventD
 		if isRange, rangePath := ste.isRangeItem(field); isRange {
 			expandedFields, err := ste.expandRangeItem(field, rangePath, eventData, ste.processTextField)
 			if err != nil {
-				continue
+				if skippable(err) {
+					continue
+				}
+				return nil, err
 			}
 			for _, expandedField := range expandedFields {
 				if textObj, ok := expandedField.(*slack.TextBlockObject); ok {
@@ -198,6 +236,9 @@ func (ste *SlackTemplateEngine) processFields(fieldsConfig []interface{}, eventD
 			}
 		} else {
 			fieldObj, err := ste.processSingleTextField(field, eventData)
+			if err != nil && !skippable(err) {
+				return nil, err
+			}
 			if err == nil && fieldObj != nil {
 				fields = append(fields, fieldObj)
 			}
@@ -231,7 +272,7 @@ func (ste *SlackTemplateEngine) createContextBlock(blockConfig BlockConfig, even
 		return nil, err
 	}
 
-	return slack.NewContextBlock(blockConfig.BlockID, processedElements...), nil
+	return slack.NewContextBlock(blockConfig.BlockID, limitSlackContextElements(processedElements)...), nil
 }
 
 func (ste *SlackTemplateEngine) processContextElements(elementsConfig []interface{}, eventData map[string]interface{}) ([]slack.MixedElement, error) {
@@ -248,7 +289,10 @@ func (ste *SlackTemplateEngine) processContextElements(elementsConfig []interfac
 		if isRange, rangePath := ste.isRangeItem(elem); isRange {
 			expandedElements, err := ste.expandRangeItem(elem, rangePath, eventData, ste.processContextElement)
 			if err != nil {
-				continue
+				if skippable(err) {
+					continue
+				}
+				return nil, err
 			}
 			for _, expandedElement := range expandedElements {
 				if mixedElement, ok := expandedElement.(slack.MixedElement); ok {
@@ -257,6 +301,9 @@ func (ste *SlackTemplateEngine) processContextElements(elementsConfig []interfac
 			}
 		} else {
 			element, err := ste.processSingleContextElement(elem, eventData)
+			if err != nil && !skippable(err) {
+				return nil, err
+			}
 			if err == nil && element != nil {
 				elements = append(elements, element)
 			}
@@ -290,7 +337,7 @@ func (ste *SlackTemplateEngine) createActionBlock(blockConfig BlockConfig, event
 		return nil, err
 	}
 
-	return slack.NewActionBlock(blockConfig.BlockID, processedElements...), nil
+	return slack.NewActionBlock(blockConfig.BlockID, limitSlackActionElements(processedElements)...), nil
 }
 
 func (ste *SlackTemplateEngine) processActionElements(elementsConfig []interface{}, eventData map[string]interface{}) ([]slack.BlockElement, error) {
@@ -307,7 +354,10 @@ func (ste *SlackTemplateEngine) processActionElements(elementsConfig []interface
 		if isRange, rangePath := ste.isRangeItem(elem); isRange {
 			expandedElements, err := ste.expandRangeItem(elem, rangePath, eventData, ste.processActionElement)
 			if err != nil {
-				continue
+				if skippable(err) {
+					continue
+				}
+				return nil, err
 			}
 			for _, expandedElement := range expandedElements {
 				if blockElement, ok := expandedElement.(slack.BlockElement); ok {
@@ -316,6 +366,9 @@ func (ste *SlackTemplateEngine) processActionElements(elementsConfig []interface
 			}
 		} else {
 			element, err := ste.processSingleActionElement(elem, eventData)
+			if err != nil && !skippable(err) {
+				return nil, err
+			}
 			if err == nil && element != nil {
 				elements = append(elements, element)
 			}
@@ -353,6 +406,9 @@ func (ste *SlackTemplateEngine) createImageBlock(blockConfig BlockConfig, eventD
 	var titleObj *slack.TextBlockObject
 	if blockConfig.Title != nil {
 		titleText, err := ste.processText(blockConfig.Title, eventData)
+		if err != nil && !skippable(err) {
+			return nil, err
+		}
 		if err == nil {
 			titleObj = slack.NewTextBlockObject(slack.PlainTextType, titleText, false, false)
 		}
@@ -381,6 +437,18 @@ func (ste *SlackTemplateEngine) processElement(elemConfig interface{}, eventData
 		return ste.createButtonElement(elemMap, eventData)
 	case "static_select":
 		return ste.createStaticSelectElement(elemMap, eventData)
+	case "multi_static_select":
+		return ste.createMultiStaticSelectElement(elemMap, eventData)
+	case "overflow":
+		return ste.createOverflowElement(elemMap, eventData)
+	case "datepicker":
+		return ste.createDatePickerElement(elemMap, eventData)
+	case "checkboxes":
+		return ste.createCheckboxesElement(elemMap, eventData)
+	case "radio_buttons":
+		return ste.createRadioButtonsElement(elemMap, eventData)
+	case "image":
+		return ste.createImageElement(elemMap, eventData)
 	default:
 		return nil, fmt.Errorf("unsupported element type: %s", elemType)
 	}
@@ -400,23 +468,26 @@ func (ste *SlackTemplateEngine) createButtonElement(elemMap map[string]interface
 
 	if actionID != "" {
 		processedActionID, err := ste.processText(actionID, eventData)
-		if err == nil {
-			actionID = processedActionID
+		if err != nil {
+			return nil, err
 		}
+		actionID = processedActionID
 	}
 
 	if url != "" {
 		processedURL, err := ste.processText(url, eventData)
-		if err == nil {
-			url = processedURL
+		if err != nil {
+			return nil, err
 		}
+		url = processedURL
 	}
 
 	if value != "" {
 		processedValue, err := ste.processText(value, eventData)
-		if err == nil {
-			value = processedValue
+		if err != nil {
+			return nil, err
 		}
+		value = processedValue
 	}
 
 	btn := slack.NewButtonBlockElement(actionID, value, textObj)
@@ -431,6 +502,9 @@ func (ste *SlackTemplateEngine) createStaticSelectElement(elemMap map[string]int
 	var placeholder *slack.TextBlockObject
 	if placeholderConfig, ok := elemMap["placeholder"]; ok {
 		textConfig, err := ste.parseTextConfig(placeholderConfig, eventData)
+		if err != nil && !skippable(err) {
+			return nil, err
+		}
 		if err == nil {
 			placeholder = slack.NewTextBlockObject(textConfig.Type, textConfig.Text, textConfig.Emoji, false)
 		}
@@ -439,9 +513,10 @@ func (ste *SlackTemplateEngine) createStaticSelectElement(elemMap map[string]int
 	var options []*slack.OptionBlockObject
 	if optionsConfig, ok := elemMap["options"].([]interface{}); ok {
 		processedOptions, err := ste.processSelectOptions(optionsConfig, eventData)
-		if err == nil {
-			options = processedOptions
+		if err != nil {
+			return nil, err
 		}
+		options = limitSlackOptions(processedOptions, MaxSlackSelectOptions)
 	}
 
 	actionID, _ := elemMap["action_id"].(string)
@@ -463,7 +538,10 @@ func (ste *SlackTemplateEngine) processSelectOptions(optionsConfig []interface{}
 		if isRange, rangePath := ste.isRangeItem(option); isRange {
 			expandedOptions, err := ste.expandRangeItem(option, rangePath, eventData, ste.processSelectOption)
 			if err != nil {
-				continue
+				if skippable(err) {
+					continue
+				}
+				return nil, err
 			}
 			for _, expandedOption := range expandedOptions {
 				if optionObj, ok := expandedOption.(*slack.OptionBlockObject); ok {
@@ -472,6 +550,9 @@ func (ste *SlackTemplateEngine) processSelectOptions(optionsConfig []interface{}
 			}
 		} else {
 			optionObj, err := ste.processSingleSelectOption(option, eventData)
+			if err != nil && !skippable(err) {
+				return nil, err
+			}
 			if err == nil && optionObj != nil {
 				options = append(options, optionObj)
 			}
@@ -510,9 +591,10 @@ func (ste *SlackTemplateEngine) createOption(optionConfig interface{}, eventData
 
 	value, _ := optionMap["value"].(string)
 	processedValue, err := ste.processText(value, eventData)
-	if err == nil {
-		value = processedValue
+	if err != nil {
+		return nil, err
 	}
+	value = processedValue
 
 	return slack.NewOptionBlockObject(value, textObj, nil), nil
 }
@@ -670,6 +752,9 @@ func (ste *SlackTemplateEngine) expandRangeItem(
 		templateItem := ste.createTemplateItem(rangeItem)
 
 		processedItem, err := itemProcessor(templateItem, itemContext)
+		if err != nil && !skippable(err) {
+			return nil, err
+		}
 		if err == nil && processedItem != nil {
 			results = append(results, processedItem)
 		}
@@ -717,6 +802,14 @@ func (ste *SlackTemplateEngine) evaluateCondition(condition string, eventData ma
 	return result == types.True
 }
 
+// skippable reports whether an item that failed to render is left out of the
+// message. Template errors are returned instead, so the output's
+// on_template_error policy applies.
+func skippable(err error) bool {
+	var tmplErr *template.Error
+	return !errors.As(err, &tmplErr)
+}
+
 // isConditionItem checks if an item has a condition field
 func (ste *SlackTemplateEngine) isConditionItem(item interface{}) (bool, string) {
 	if itemMap, ok := item.(map[string]interface{}); ok {
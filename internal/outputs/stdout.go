@@ -15,6 +15,7 @@ type StdoutOutput struct {
 	format         string
 	textTemplate   string
 	templateEngine *template.Engine
+	onError        templateErrorPolicy
 }
 
 func NewStdoutOutput(config map[string]interface{}, nomadClient *api.Client) (*StdoutOutput, error) {
@@ -30,17 +31,28 @@ func NewStdoutOutput(config map[string]interface{}, nomadClient *api.Client) (*S
 	textTemplate, _ := config["text"].(string)
 
 	var templateEngine *template.Engine
+	var onError templateErrorPolicy
 	if format == "text" {
 		if textTemplate == "" {
 			return nil, fmt.Errorf("text template is required when format is 'text'")
 		}
 		templateEngine = template.NewEngineWithNomad(nomadClient)
+		if err := templateEngine.Compile(textTemplate); err != nil {
+			return nil, fmt.Errorf("invalid text template: %w", err)
+		}
+
+		var err error
+		onError, err = newTemplateErrorPolicy(config, templateEngine, DefaultFallbackTemplate)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &StdoutOutput{
 		format:         format,
 		textTemplate:   textTemplate,
 		templateEngine: templateEngine,
+		onError:        onError,
 	}, nil
 }
 
@@ -57,16 +69,62 @@ func (o *StdoutOutput) Send(event nomad.Event) error {
 		output = string(eventJSON)
 
 	case "text":
-		output, err = o.templateEngine.ProcessText(o.textTemplate, event)
+		var deliver bool
+		output, deliver, err = o.render(o.templateEngine.CreateTemplateData(event))
+		if err != nil || !deliver {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported format: %s", o.format)
+	}
+
+	return o.write(output)
+}
+
+// SendBatch implements the BatchSender interface. JSON format writes the batch
+// as one line; text templates receive .Events and .GroupLabels.
+func (o *StdoutOutput) SendBatch(batch Batch) error {
+	var output string
+
+	switch o.format {
+	case "json":
+		batchJSON, err := json.Marshal(batch)
 		if err != nil {
-			return fmt.Errorf("failed to process text template: %w", err)
+			return fmt.Errorf("failed to marshal event batch to JSON: %w", err)
 		}
+		output = string(batchJSON)
+
+	case "text":
+		text, deliver, err := o.render(o.templateEngine.CreateBatchTemplateData(batch.Events, batch.GroupLabels))
+		if err != nil || !deliver {
+			return err
+		}
+		output = text
 
 	default:
 		return fmt.Errorf("unsupported format: %s", o.format)
 	}
 
-	_, err = os.Stdout.WriteString(output + "\n")
+	return o.write(output)
+}
+
+// render renders the text template, applying the on_template_error policy
+func (o *StdoutOutput) render(data map[string]interface{}) (string, bool, error) {
+	text, err := o.templateEngine.ProcessTextWithData(o.textTemplate, data)
+	if err == nil {
+		return text, true, nil
+	}
+
+	text, deliver, err := o.onError.handle(err, data)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to process text template: %w", err)
+	}
+	return text, deliver, nil
+}
+
+func (o *StdoutOutput) write(output string) error {
+	_, err := os.Stdout.WriteString(output + "\n")
 	if err != nil {
 		return fmt.Errorf("failed to write to stdout: %w", err)
 	}
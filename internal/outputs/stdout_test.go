@@ -39,6 +39,18 @@ func TestNewStdoutOutput(t *testing.T) {
 			expectError: true,
 			errorMsg:    "text template is required when format is 'text'",
 		},
+		{
+			name:        "text format with invalid template",
+			config:      map[string]interface{}{"format": "text", "text": "{{ .Topic "},
+			expectError: true,
+			errorMsg:    "invalid text template",
+		},
+		{
+			name:        "invalid on_template_error",
+			config:      map[string]interface{}{"format": "text", "text": "{{ .Topic }}", "on_template_error": "ignore"},
+			expectError: true,
+			errorMsg:    "invalid on_template_error \"ignore\"",
+		},
 		{
 			name:        "invalid format",
 			config:      map[string]interface{}{"format": "xml"},
@@ -0,0 +1,125 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"nomad-events/internal/template"
+)
+
+// Actions for templates that fail to render, set with on_template_error
+const (
+	TemplateErrorFallback = "fallback" // Deliver the fallback template instead (default)
+	TemplateErrorSkip     = "skip"     // Drop the delivery
+	TemplateErrorFail     = "fail"     // Fail the delivery, so it is reported as a failed send
+)
+
+// DefaultFallbackTemplate is delivered in place of a template that failed to
+// render, for single events and batches
+const DefaultFallbackTemplate = `{{ if .Events }}{{ .Count }} events{{ else }}{{ .Topic }} {{ .Type }}: {{ .Key }}{{ end }}`
+
+// templateErrorPolicy decides what an output delivers when its templates fail
+// to render
+type templateErrorPolicy struct {
+	action   string
+	fallback string
+	engine   *template.Engine
+}
+
+// newTemplateErrorPolicy reads on_template_error and fallback_template from an
+// output's configuration, compiling the fallback with the output's engine.
+// defaultFallback is used when no fallback_template is set.
+func newTemplateErrorPolicy(config map[string]interface{}, engine *template.Engine, defaultFallback string) (templateErrorPolicy, error) {
+	action, _ := config["on_template_error"].(string)
+	switch action {
+	case "":
+		action = TemplateErrorFallback
+	case TemplateErrorFallback, TemplateErrorSkip, TemplateErrorFail:
+	default:
+		return templateErrorPolicy{}, fmt.Errorf("invalid on_template_error %q: must be one of fallback, skip, fail", action)
+	}
+
+	fallback, _ := config["fallback_template"].(string)
+	if fallback == "" {
+		fallback = defaultFallback
+	}
+	if err := engine.Compile(fallback); err != nil {
+		return templateErrorPolicy{}, fmt.Errorf("invalid fallback_template: %w", err)
+	}
+
+	return templateErrorPolicy{action: action, fallback: fallback, engine: engine}, nil
+}
+
+// templateFailure is a delivery failed by on_template_error: fail. Rendering
+// the same data again fails the same way, so the retry layer gives up on it.
+type templateFailure struct {
+	err error
+}
+
+func (e *templateFailure) Error() string   { return e.err.Error() }
+func (e *templateFailure) Unwrap() error   { return e.err }
+func (e *templateFailure) Permanent() bool { return true }
+
+// handle applies the policy to an error from rendering data. It returns the
+// fallback text to deliver instead, or deliver == false when the delivery is
+// skipped. Errors other than template errors are returned unchanged.
+func (p templateErrorPolicy) handle(err error, data map[string]interface{}) (fallback string, deliver bool, _ error) {
+	var tmplErr *template.Error
+	if !errors.As(err, &tmplErr) || p.engine == nil {
+		return "", false, err
+	}
+
+	if p.action == TemplateErrorFail {
+		return "", false, &templateFailure{err: err}
+	}
+
+	if p.action == TemplateErrorSkip {
+		slog.Warn("Template failed to render, skipping delivery", "error", err)
+		return "", false, nil
+	}
+
+	text, fallbackErr := p.engine.ProcessTextWithData(p.fallback, data)
+	if fallbackErr != nil {
+		return "", false, fmt.Errorf("%w (fallback template also failed: %v)", err, fallbackErr)
+	}
+
+	slog.Warn("Template failed to render, delivering fallback", "error", err)
+	return text, true, nil
+}
+
+// compileTemplates compiles every string in an output's configuration value,
+// such as a list of Slack blocks, naming the first that fails by its path.
+// CEL conditions, range paths and block types are not templates and are skipped.
+func compileTemplates(engine *template.Engine, path string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if err := engine.Compile(v); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := compileTemplates(engine, fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			switch key {
+			case "condition", "range", "type":
+				continue
+			}
+			if err := compileTemplates(engine, path+"."+key, v[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nomad-events/internal/nomad"
+	"nomad-events/internal/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplateErrorPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected string
+		errorMsg string
+	}{
+		{
+			name:     "default",
+			config:   map[string]interface{}{},
+			expected: TemplateErrorFallback,
+		},
+		{
+			name:     "skip",
+			config:   map[string]interface{}{"on_template_error": "skip"},
+			expected: TemplateErrorSkip,
+		},
+		{
+			name:     "invalid action",
+			config:   map[string]interface{}{"on_template_error": "retry"},
+			errorMsg: "invalid on_template_error \"retry\"",
+		},
+		{
+			name:     "invalid fallback",
+			config:   map[string]interface{}{"fallback_template": "{{ .Topic"},
+			errorMsg: "invalid fallback_template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newTemplateErrorPolicy(tt.config, template.NewEngine(), DefaultFallbackTemplate)
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.action)
+		})
+	}
+}
+
+func TestTemplateErrorPolicyHandle(t *testing.T) {
+	engine := template.NewEngine()
+	data := engine.CreateTemplateData(nomad.Event{Topic: "Job", Type: "JobRegistered", Key: "web"})
+	_, tmplErr := engine.ProcessTextWithData("{{ index .Payload 0 }}", data)
+	require.Error(t, tmplErr)
+
+	policy := func(config map[string]interface{}) templateErrorPolicy {
+		p, err := newTemplateErrorPolicy(config, engine, DefaultFallbackTemplate)
+		require.NoError(t, err)
+		return p
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		text, deliver, err := policy(map[string]interface{}{}).handle(tmplErr, data)
+		require.NoError(t, err)
+		assert.True(t, deliver)
+		assert.Equal(t, "Job JobRegistered: web", text)
+	})
+
+	t.Run("batch and custom fallbacks", func(t *testing.T) {
+		batchData := engine.CreateBatchTemplateData([]nomad.Event{{Topic: "Job"}, {Topic: "Job"}}, nil)
+		text, deliver, err := policy(map[string]interface{}{}).handle(tmplErr, batchData)
+		require.NoError(t, err)
+		assert.True(t, deliver)
+		assert.Equal(t, "2 events", text)
+
+		text, _, err = policy(map[string]interface{}{"fallback_template": "{{ .Topic }} changed"}).handle(tmplErr, data)
+		require.NoError(t, err)
+		assert.Equal(t, "Job changed", text)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		_, deliver, err := policy(map[string]interface{}{"on_template_error": "skip"}).handle(tmplErr, data)
+		require.NoError(t, err)
+		assert.False(t, deliver)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, deliver, err := policy(map[string]interface{}{"on_template_error": "fail"}).handle(tmplErr, data)
+		assert.ErrorIs(t, err, tmplErr)
+		assert.False(t, deliver)
+
+		var permanent permanentError
+		require.ErrorAs(t, err, &permanent)
+		assert.True(t, permanent.Permanent())
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		otherErr := errors.New("unsupported block type: table")
+		_, deliver, err := policy(map[string]interface{}{}).handle(otherErr, data)
+		assert.Equal(t, otherErr, err)
+		assert.False(t, deliver)
+	})
+}
+
+func TestCompileTemplates(t *testing.T) {
+	blocks := []interface{}{
+		map[string]interface{}{"type": "header", "text": "{{ .Topic }}"},
+		map[string]interface{}{
+			"type":      "section",
+			"condition": "event.Topic == 'Job'",
+			"fields": []interface{}{
+				map[string]interface{}{"range": ".Events", "text": "{{ .Key }}"},
+				map[string]interface{}{"type": "mrkdwn", "text": "{{ .Payload.Job.ID"},
+			},
+		},
+	}
+
+	err := compileTemplates(template.NewEngine(), "blocks", blocks)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "blocks[1].fields[1].text: template:")
+
+	assert.NoError(t, compileTemplates(template.NewEngine(), "blocks", blocks[:1]))
+	assert.NoError(t, compileTemplates(template.NewEngine(), "blocks", nil))
+}
+
+func TestNewSlackOutputInvalidTemplate(t *testing.T) {
+	_, err := NewSlackOutput(map[string]interface{}{
+		"webhook_url": "https://hooks.slack.com/services/test",
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "section", "text": "{{ if .Diff }}changed"},
+		},
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "blocks[0].text")
+}
+
+func TestSlackOutputOnTemplateError(t *testing.T) {
+	var received []SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var message SlackMessage
+		json.Unmarshal(body, &message)
+		received = append(received, message)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Deployment events have no .Payload.Job, so indexing it fails to render
+	newOutput := func(action string) *SlackOutput {
+		output, err := NewSlackOutput(map[string]interface{}{
+			"webhook_url":       server.URL,
+			"on_template_error": action,
+			"blocks": []interface{}{
+				map[string]interface{}{"type": "section", "text": "{{ index .Payload.Job.Meta \"owner\" }}"},
+			},
+		}, nil)
+		require.NoError(t, err)
+		return output
+	}
+	event := nomad.Event{Topic: "Deployment", Type: "DeploymentStatusUpdate", Key: "deploy-1", Payload: map[string]interface{}{}}
+
+	require.NoError(t, newOutput("fallback").Send(event))
+	require.Len(t, received, 1)
+	assert.Equal(t, "Deployment DeploymentStatusUpdate: deploy-1", received[0].Text)
+	assert.Nil(t, received[0].Blocks)
+
+	require.NoError(t, newOutput("skip").Send(event))
+	assert.Len(t, received, 1)
+
+	err := newOutput("fail").Send(event)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error calling index")
+	assert.Len(t, received, 1)
+}
@@ -2,9 +2,16 @@ package routing
 
 import (
 	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
 	"nomad-events/internal/config"
+	"nomad-events/internal/dedupe"
+	"nomad-events/internal/flap"
 	"nomad-events/internal/nomad"
+	"nomad-events/internal/timeinterval"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
@@ -12,16 +19,47 @@ import (
 
 type Router struct {
 	routes []routeNode
+	now    func() time.Time
+
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+// Notification is an event generated by the router itself, such as a change
+// in flapping state, with the outputs it should be delivered to
+type Notification struct {
+	Event   nomad.Event
+	Outputs []string
 }
 
 type routeNode struct {
+	id             string // identifies the route across reloads, see routeID
 	filter         cel.Program
-	output         string      // empty if no output
-	shouldContinue bool        // true by default
+	output         string               // empty if no output
+	shouldContinue bool                 // true by default
+	dedupe         *dedupe.Deduplicator // nil if dedupe is not configured
+	dedupeConfig   config.DedupeConfig
+	activeTimes    []*timeinterval.Interval // only match inside one of these, if set
+	muteTimes      []*timeinterval.Interval // never match inside any of these
+	eventTime      bool                     // evaluate intervals against the event time
+	flap           *flap.Detector           // nil if flap detection is not configured
+	flapConfig     config.FlapDetectionConfig
 	children       []routeNode // child routes
 }
 
+// NewRouter creates a router for routes that do not reference time intervals
 func NewRouter(routes []config.Route) (*Router, error) {
+	return NewRouterWithTimeIntervals(routes, nil)
+}
+
+// NewRouterWithTimeIntervals creates a router whose routes may reference the
+// given named time intervals
+func NewRouterWithTimeIntervals(routes []config.Route, timeIntervals map[string][]config.TimeInterval) (*Router, error) {
+	intervals, err := timeinterval.NewSet(timeIntervals)
+	if err != nil {
+		return nil, err
+	}
+
 	env, err := cel.NewEnv(
 		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
 		cel.Variable("diff", cel.MapType(cel.StringType, cel.DynType)),
@@ -30,19 +68,22 @@ func NewRouter(routes []config.Route) (*Router, error) {
 		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
 	}
 
-	routeNodes, err := buildRouteNodes(routes, env)
+	routeNodes, err := buildRouteNodes(routes, env, intervals, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return &Router{routes: routeNodes}, nil
+	return &Router{routes: routeNodes, now: time.Now}, nil
 }
 
 // buildRouteNodes recursively builds route nodes from config routes
-func buildRouteNodes(routes []config.Route, env *cel.Env) ([]routeNode, error) {
+func buildRouteNodes(routes []config.Route, env *cel.Env, intervals map[string]*timeinterval.Interval, parentID string) ([]routeNode, error) {
 	nodes := make([]routeNode, len(routes))
+	seen := make(map[string]int)
 
 	for i, route := range routes {
+		id := routeID(parentID, route, seen)
+
 		var program cel.Program
 		var err error
 
@@ -74,16 +115,56 @@ func buildRouteNodes(routes []config.Route, env *cel.Env) ([]routeNode, error) {
 			continueFlag = *route.Continue
 		}
 
+		var deduplicator *dedupe.Deduplicator
+		var dedupeConfig config.DedupeConfig
+		if route.Dedupe != nil {
+			dedupeConfig = *route.Dedupe
+			deduplicator, err = dedupe.New(*route.Dedupe)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dedupe configuration for route %d: %w", i, err)
+			}
+		}
+
+		var flapDetector *flap.Detector
+		var flapConfig config.FlapDetectionConfig
+		if route.FlapDetection != nil {
+			flapConfig = *route.FlapDetection
+			if route.Output == "" {
+				return nil, fmt.Errorf("flap detection for route %d requires an output", i)
+			}
+			flapDetector, err = flap.New(*route.FlapDetection)
+			if err != nil {
+				return nil, fmt.Errorf("invalid flap detection configuration for route %d: %w", i, err)
+			}
+		}
+
+		activeTimes, err := lookupIntervals(route.ActiveTimeIntervals, intervals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid active_time_intervals for route %d: %w", i, err)
+		}
+		muteTimes, err := lookupIntervals(route.MuteTimeIntervals, intervals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mute_time_intervals for route %d: %w", i, err)
+		}
+
 		// Build child routes
-		children, err := buildRouteNodes(route.Routes, env)
+		children, err := buildRouteNodes(route.Routes, env, intervals, id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to build child routes for route %d: %w", i, err)
 		}
 
 		nodes[i] = routeNode{
+			id:             id,
 			filter:         program,
 			output:         route.Output,
 			shouldContinue: continueFlag,
+			dedupe:         deduplicator,
+			dedupeConfig:   dedupeConfig,
+			activeTimes:    activeTimes,
+			muteTimes:      muteTimes,
+			eventTime:      route.TimeSource == config.TimeSourceEvent,
+			flap:           flapDetector,
+			flapConfig:     flapConfig,
 			children:       children,
 		}
 	}
@@ -91,27 +172,69 @@ func buildRouteNodes(routes []config.Route, env *cel.Env) ([]routeNode, error) {
 	return nodes, nil
 }
 
-func (r *Router) Route(event nomad.Event) ([]string, error) {
-	eventMap := map[string]interface{}{
-		"Topic":     event.Topic,
-		"Type":      event.Type,
-		"Key":       event.Key,
-		"Namespace": event.Namespace,
-		"Index":     event.Index,
-		"Payload":   event.Payload,
-		"Diff":      event.Diff,
+// routeID identifies a route by its parent, filter and output, numbering
+// siblings that share both. Routes keep their ID when unrelated routes are
+// added, removed or reordered around them.
+func routeID(parentID string, route config.Route, seen map[string]int) string {
+	id := fmt.Sprintf("%s/%q->%q", parentID, route.Filter, route.Output)
+	seen[id]++
+	return fmt.Sprintf("%s#%d", id, seen[id])
+}
+
+// lookupIntervals resolves time interval names
+func lookupIntervals(names []string, intervals map[string]*timeinterval.Interval) ([]*timeinterval.Interval, error) {
+	result := make([]*timeinterval.Interval, 0, len(names))
+	for _, name := range names {
+		interval, exists := intervals[name]
+		if !exists {
+			return nil, fmt.Errorf("time interval %q does not exist", name)
+		}
+		result = append(result, interval)
+	}
+	return result, nil
+}
+
+// inTime reports whether the route is active at the event's time
+func (r *Router) inTime(route routeNode, event nomad.Event) bool {
+	if len(route.activeTimes) == 0 && len(route.muteTimes) == 0 {
+		return true
+	}
+
+	t := r.now()
+	if route.eventTime {
+		if eventTime, ok := nomad.EventTime(event); ok {
+			t = eventTime
+		}
+	}
+
+	for _, interval := range route.muteTimes {
+		if interval.Contains(t) {
+			return false
+		}
 	}
 
+	if len(route.activeTimes) == 0 {
+		return true
+	}
+	for _, interval := range route.activeTimes {
+		if interval.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) Route(event nomad.Event) ([]string, error) {
 	evalContext := map[string]interface{}{
-		"event": eventMap,
+		"event": nomad.EventMap(event),
 		"diff":  event.Diff,
 	}
 
-	return r.processRoutes(r.routes, evalContext)
+	return r.processRoutes(r.routes, event, evalContext)
 }
 
 // processRoutes recursively processes routes and returns matched outputs
-func (r *Router) processRoutes(routes []routeNode, evalContext map[string]interface{}) ([]string, error) {
+func (r *Router) processRoutes(routes []routeNode, event nomad.Event, evalContext map[string]interface{}) ([]string, error) {
 	var matchedOutputs []string
 
 	for _, route := range routes {
@@ -123,6 +246,35 @@ func (r *Router) processRoutes(routes []routeNode, evalContext map[string]interf
 		}
 
 		if result == types.True {
+			// Outside its time intervals a route is muted: it still matches,
+			// so continue applies, but it delivers nothing
+			if !r.inTime(route, event) {
+				if !route.shouldContinue {
+					break
+				}
+				continue
+			}
+
+			// Duplicates still count as a match for continue, but deliver nothing
+			if route.dedupe != nil && route.dedupe.IsDuplicate(event) {
+				if !route.shouldContinue {
+					break
+				}
+				continue
+			}
+
+			// Flapping keys are replaced by notifications sent to the route's output
+			if route.flap != nil {
+				suppress, flapEvents := route.flap.Observe(event)
+				r.notify(route.output, flapEvents)
+				if suppress {
+					if !route.shouldContinue {
+						break
+					}
+					continue
+				}
+			}
+
 			// Route matched - add output if specified
 			if route.output != "" {
 				matchedOutputs = append(matchedOutputs, route.output)
@@ -130,7 +282,7 @@ func (r *Router) processRoutes(routes []routeNode, evalContext map[string]interf
 
 			// Process child routes
 			if len(route.children) > 0 {
-				childOutputs, err := r.processRoutes(route.children, evalContext)
+				childOutputs, err := r.processRoutes(route.children, event, evalContext)
 				if err != nil {
 					// Don't fail entire routing if child route fails
 					continue
@@ -147,3 +299,114 @@ func (r *Router) processRoutes(routes []routeNode, evalContext map[string]interf
 
 	return matchedOutputs, nil
 }
+
+// Inherit carries state over from the router being replaced. Routes with the
+// same ID keep their dedupe windows and flap history while that part of their
+// configuration is unchanged. Dedupe state no route keeps is released, and
+// keys flapping in a detector no route keeps end with a FlappingEnded event.
+func (r *Router) Inherit(previous *Router) {
+	previousNodes := make(map[string]routeNode)
+	collectRoutes(previous.routes, previousNodes)
+
+	kept := make(map[string]bool)
+	r.inheritRoutes(r.routes, previousNodes, kept)
+
+	ids := make([]string, 0, len(previousNodes))
+	for id := range previousNodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := previousNodes[id]
+		if node.dedupe != nil && !kept["dedupe"+id] {
+			closeDedupe(node.dedupe)
+		}
+		if node.flap != nil && !kept["flap"+id] {
+			r.notify(node.output, node.flap.End())
+		}
+	}
+}
+
+func collectRoutes(routes []routeNode, nodes map[string]routeNode) {
+	for _, route := range routes {
+		nodes[route.id] = route
+		collectRoutes(route.children, nodes)
+	}
+}
+
+func (r *Router) inheritRoutes(routes []routeNode, previousNodes map[string]routeNode, kept map[string]bool) {
+	for i := range routes {
+		route := &routes[i]
+		previous, exists := previousNodes[route.id]
+		if exists && route.dedupe != nil && previous.dedupe != nil && route.dedupeConfig == previous.dedupeConfig {
+			closeDedupe(route.dedupe)
+			route.dedupe = previous.dedupe
+			kept["dedupe"+route.id] = true
+		}
+		if exists && route.flap != nil && previous.flap != nil && route.flapConfig == previous.flapConfig {
+			route.flap = previous.flap
+			kept["flap"+route.id] = true
+		}
+		r.inheritRoutes(route.children, previousNodes, kept)
+	}
+}
+
+// Close releases the router's state, writing persisted dedupe keys
+func (r *Router) Close() {
+	closeRoutes(r.routes)
+}
+
+func closeRoutes(routes []routeNode) {
+	for _, route := range routes {
+		if route.dedupe != nil {
+			closeDedupe(route.dedupe)
+		}
+		closeRoutes(route.children)
+	}
+}
+
+func closeDedupe(deduplicator *dedupe.Deduplicator) {
+	if err := deduplicator.Close(); err != nil {
+		slog.Warn("Failed to persist dedupe store", "error", err)
+	}
+}
+
+// Notifications returns and clears the notifications generated while routing
+func (r *Router) Notifications() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := r.notifications
+	r.notifications = nil
+	return notifications
+}
+
+// Sweep returns notifications for time-based changes, such as keys that have
+// stopped flapping
+func (r *Router) Sweep() []Notification {
+	r.sweepRoutes(r.routes)
+	return r.Notifications()
+}
+
+func (r *Router) sweepRoutes(routes []routeNode) {
+	for _, route := range routes {
+		if route.flap != nil {
+			r.notify(route.output, route.flap.Sweep())
+		}
+		r.sweepRoutes(route.children)
+	}
+}
+
+func (r *Router) notify(output string, events []nomad.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		r.notifications = append(r.notifications, Notification{Event: event, Outputs: []string{output}})
+	}
+}
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -170,6 +171,28 @@ func TestRouterRouteNonExistentField(t *testing.T) {
 	assert.Equal(t, []string{"all_events"}, outputs)
 }
 
+func TestRouterRouteEnriched(t *testing.T) {
+	routes := []config.Route{
+		{Filter: "has(event.Enriched.Job) && event.Enriched.Job.Meta.team == 'payments'", Output: "payments"},
+		{Filter: "", Output: "all_events"},
+	}
+
+	router, err := NewRouter(routes)
+	require.NoError(t, err)
+
+	event := nomad.Event{Topic: "Allocation", Type: "AllocationUpdated", Index: 1}
+	outputs, err := router.Route(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"all_events"}, outputs)
+
+	event.Enriched = map[string]interface{}{
+		"Job": map[string]interface{}{"Meta": map[string]interface{}{"team": "payments"}},
+	}
+	outputs, err = router.Route(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"payments", "all_events"}, outputs)
+}
+
 func TestHierarchicalRouting(t *testing.T) {
 	continueFalse := false
 
@@ -270,3 +293,365 @@ func TestHierarchicalRoutingParentWithoutOutput(t *testing.T) {
 	assert.NoError(t, err)
 	assert.ElementsMatch(t, []string{"job_registered", "all_events"}, outputs)
 }
+
+func TestRouteDedupe(t *testing.T) {
+	continueFalse := false
+
+	routes := []config.Route{
+		{
+			Filter:   "event.Topic == 'Allocation'",
+			Output:   "alloc_alerts",
+			Continue: &continueFalse,
+			Dedupe: &config.DedupeConfig{
+				Key: "{{ .Key }}-{{ .Payload.Allocation.ClientStatus }}",
+				TTL: "5m",
+			},
+			Routes: []config.Route{
+				{Filter: "event.Type == 'AllocationUpdated'", Output: "alloc_updates"},
+			},
+		},
+		{
+			Filter: "",
+			Output: "all_events",
+		},
+	}
+
+	router, err := NewRouter(routes)
+	require.NoError(t, err)
+
+	event := nomad.Event{
+		Topic: "Allocation",
+		Type:  "AllocationUpdated",
+		Key:   "alloc-1",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ClientStatus": "pending",
+			},
+		},
+	}
+
+	outputs, err := router.Route(event)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alloc_alerts", "alloc_updates"}, outputs)
+
+	// Duplicate is suppressed, including child routes, and still honours continue=false
+	outputs, err = router.Route(event)
+	assert.NoError(t, err)
+	assert.Empty(t, outputs)
+
+	event.Payload = map[string]interface{}{
+		"Allocation": map[string]interface{}{
+			"ClientStatus": "running",
+		},
+	}
+	outputs, err = router.Route(event)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alloc_alerts", "alloc_updates"}, outputs)
+}
+
+func TestRouterInheritDedupe(t *testing.T) {
+	dedupeRoute := func(ttl string) config.Route {
+		return config.Route{
+			Filter: "event.Topic == 'Allocation'",
+			Output: "alloc_alerts",
+			Dedupe: &config.DedupeConfig{Key: "{{ .Key }}", TTL: ttl},
+		}
+	}
+	event := nomad.Event{Topic: "Allocation", Type: "AllocationUpdated", Key: "alloc-1"}
+
+	router, err := NewRouter([]config.Route{dedupeRoute("5m")})
+	require.NoError(t, err)
+	outputs, err := router.Route(event)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alloc_alerts"}, outputs)
+
+	tests := []struct {
+		name       string
+		routes     []config.Route
+		suppressed bool
+	}{
+		{
+			name:       "unchanged route keeps its window",
+			routes:     []config.Route{dedupeRoute("5m")},
+			suppressed: true,
+		},
+		{
+			name:       "route keeps its window when other routes are added before it",
+			routes:     []config.Route{{Filter: "event.Topic == 'Node'", Output: "nodes"}, dedupeRoute("5m")},
+			suppressed: true,
+		},
+		{
+			name:   "changed dedupe starts a new window",
+			routes: []config.Route{dedupeRoute("10m")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloaded, err := NewRouter(tt.routes)
+			require.NoError(t, err)
+			reloaded.Inherit(router)
+
+			outputs, err := reloaded.Route(event)
+			require.NoError(t, err)
+			if tt.suppressed {
+				assert.Empty(t, outputs)
+			} else {
+				assert.Equal(t, []string{"alloc_alerts"}, outputs)
+			}
+		})
+	}
+}
+
+func TestNewRouterInvalidDedupe(t *testing.T) {
+	routes := []config.Route{
+		{Filter: "", Output: "stdout", Dedupe: &config.DedupeConfig{Key: "{{ .Key }}", TTL: "never"}},
+	}
+
+	router, err := NewRouter(routes)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid dedupe configuration for route 0")
+	assert.Nil(t, router)
+}
+
+func TestRouteTimeIntervals(t *testing.T) {
+	continueFalse := false
+
+	intervals := map[string][]config.TimeInterval{
+		"business_hours": {{
+			Weekdays: []string{"monday:friday"},
+			Times:    []config.TimeRange{{StartTime: "09:00", EndTime: "17:00"}},
+		}},
+		"maintenance": {{
+			Weekdays: []string{"sunday"},
+		}},
+	}
+
+	routes := []config.Route{
+		{
+			Filter:              "",
+			Output:              "slack",
+			Continue:            &continueFalse,
+			ActiveTimeIntervals: []string{"business_hours"},
+		},
+		{
+			Filter:            "",
+			Output:            "pager",
+			MuteTimeIntervals: []string{"maintenance"},
+		},
+	}
+
+	router, err := NewRouterWithTimeIntervals(routes, intervals)
+	require.NoError(t, err)
+
+	event := nomad.Event{Topic: "Node", Type: "NodeDrain"}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected []string
+	}{
+		{"business hours", time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC), []string{"slack"}},
+		// A muted route still matches, so continue=false keeps the event from its fallback sibling
+		{"out of hours", time.Date(2024, 3, 6, 22, 0, 0, 0, time.UTC), nil},
+		{"maintenance", time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router.now = func() time.Time { return tt.now }
+			outputs, err := router.Route(event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, outputs)
+		})
+	}
+
+	t.Run("muted routes skip their children", func(t *testing.T) {
+		router, err := NewRouterWithTimeIntervals([]config.Route{
+			{
+				Filter:            "",
+				Output:            "slack",
+				MuteTimeIntervals: []string{"maintenance"},
+				Routes:            []config.Route{{Filter: "", Output: "audit"}},
+			},
+		}, intervals)
+		require.NoError(t, err)
+
+		router.now = func() time.Time { return time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC) }
+		outputs, err := router.Route(event)
+		assert.NoError(t, err)
+		assert.Empty(t, outputs)
+	})
+
+	t.Run("complementary intervals route out of hours events elsewhere", func(t *testing.T) {
+		router, err := NewRouterWithTimeIntervals([]config.Route{
+			{Filter: "", Output: "slack", ActiveTimeIntervals: []string{"business_hours"}},
+			{Filter: "", Output: "pager", MuteTimeIntervals: []string{"business_hours"}},
+		}, intervals)
+		require.NoError(t, err)
+
+		router.now = func() time.Time { return time.Date(2024, 3, 6, 22, 0, 0, 0, time.UTC) }
+		outputs, err := router.Route(event)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pager"}, outputs)
+	})
+}
+
+func TestRouteTimeIntervalsEventTime(t *testing.T) {
+	routes := []config.Route{
+		{
+			Filter:              "",
+			Output:              "slack",
+			ActiveTimeIntervals: []string{"business_hours"},
+			TimeSource:          config.TimeSourceEvent,
+		},
+	}
+	intervals := map[string][]config.TimeInterval{
+		"business_hours": {{Times: []config.TimeRange{{StartTime: "09:00", EndTime: "17:00"}}}},
+	}
+
+	router, err := NewRouterWithTimeIntervals(routes, intervals)
+	require.NoError(t, err)
+	router.now = func() time.Time { return time.Date(2024, 3, 6, 22, 0, 0, 0, time.UTC) }
+
+	modified := time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC)
+	event := nomad.Event{
+		Topic: "Allocation",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{"ModifyTime": float64(modified.UnixNano())},
+		},
+	}
+
+	outputs, err := router.Route(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"slack"}, outputs)
+
+	// Events without a timestamp fall back to the wall clock
+	outputs, err = router.Route(nomad.Event{Topic: "Node"})
+	assert.NoError(t, err)
+	assert.Empty(t, outputs)
+}
+
+func TestNewRouterInvalidTimeIntervals(t *testing.T) {
+	routes := []config.Route{
+		{Filter: "", Output: "slack", ActiveTimeIntervals: []string{"missing"}},
+	}
+
+	_, err := NewRouterWithTimeIntervals(routes, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `time interval "missing" does not exist`)
+
+	_, err = NewRouterWithTimeIntervals(nil, map[string][]config.TimeInterval{
+		"broken": {{Times: []config.TimeRange{{StartTime: "25:00", EndTime: "26:00"}}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid time")
+}
+
+func TestRouteFlapDetection(t *testing.T) {
+	routes := []config.Route{
+		{
+			Filter: "event.Topic == 'Node'",
+			Output: "node_alerts",
+			FlapDetection: &config.FlapDetectionConfig{
+				Key:       "{{ .Payload.Node.ID }}",
+				State:     "{{ .Payload.Node.Status }}",
+				Threshold: 2,
+				Window:    "10m",
+				StableFor: "1ns",
+			},
+			Routes: []config.Route{
+				{Filter: "", Output: "node_children"},
+			},
+		},
+		{Filter: "", Output: "all_events"},
+	}
+
+	router, err := NewRouter(routes)
+	require.NoError(t, err)
+
+	nodeEvent := func(status string) nomad.Event {
+		return nomad.Event{
+			Topic:   "Node",
+			Payload: map[string]interface{}{"Node": map[string]interface{}{"ID": "node-1", "Status": status}},
+		}
+	}
+
+	outputs, err := router.Route(nodeEvent("ready"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node_alerts", "node_children", "all_events"}, outputs)
+	router.Route(nodeEvent("down"))
+	assert.Empty(t, router.Notifications())
+
+	// Flapping suppresses the route and its children, and notifies the route's output
+	outputs, err = router.Route(nodeEvent("ready"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"all_events"}, outputs)
+
+	notifications := router.Notifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "FlappingStarted", notifications[0].Event.Type)
+	assert.Equal(t, []string{"node_alerts"}, notifications[0].Outputs)
+	assert.Empty(t, router.Notifications(), "notifications are drained")
+
+	notifications = router.Sweep()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "FlappingEnded", notifications[0].Event.Type)
+	assert.Equal(t, []string{"node_alerts"}, notifications[0].Outputs)
+}
+
+func TestRouterInheritFlapDetection(t *testing.T) {
+	flapRoute := func(threshold int) config.Route {
+		return config.Route{
+			Filter: "event.Topic == 'Node'",
+			Output: "node_alerts",
+			FlapDetection: &config.FlapDetectionConfig{
+				Key:       "{{ .Payload.Node.ID }}",
+				State:     "{{ .Payload.Node.Status }}",
+				Threshold: threshold,
+				Window:    "10m",
+			},
+		}
+	}
+	nodeEvent := func(status string) nomad.Event {
+		return nomad.Event{
+			Topic:   "Node",
+			Payload: map[string]interface{}{"Node": map[string]interface{}{"ID": "node-1", "Status": status}},
+		}
+	}
+	flapping := func(t *testing.T) *Router {
+		router, err := NewRouter([]config.Route{flapRoute(2)})
+		require.NoError(t, err)
+		for _, status := range []string{"ready", "down", "ready"} {
+			router.Route(nodeEvent(status))
+		}
+		require.Len(t, router.Notifications(), 1)
+		return router
+	}
+
+	t.Run("unchanged route keeps flapping", func(t *testing.T) {
+		previous := flapping(t)
+		reloaded, err := NewRouter([]config.Route{flapRoute(2)})
+		require.NoError(t, err)
+		reloaded.Inherit(previous)
+
+		assert.Empty(t, reloaded.Notifications())
+		outputs, err := reloaded.Route(nodeEvent("down"))
+		require.NoError(t, err)
+		assert.Empty(t, outputs, "the key is still flapping")
+	})
+
+	t.Run("changed or removed detectors end flapping", func(t *testing.T) {
+		for _, routes := range [][]config.Route{{flapRoute(3)}, nil} {
+			previous := flapping(t)
+			reloaded, err := NewRouter(routes)
+			require.NoError(t, err)
+			reloaded.Inherit(previous)
+
+			notifications := reloaded.Notifications()
+			require.Len(t, notifications, 1)
+			assert.Equal(t, "FlappingEnded", notifications[0].Event.Type)
+			assert.Equal(t, []string{"node_alerts"}, notifications[0].Outputs)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server is the HTTP API served by nomad-events for runtime management
+type Server struct {
+	mux        *http.ServeMux
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// New creates a server that will listen on address, e.g. "127.0.0.1:8686"
+func New(address string) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return &Server{
+		mux: mux,
+		httpServer: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Mux returns the request multiplexer so components can register endpoints
+func (s *Server) Mux() *http.ServeMux {
+	return s.mux
+}
+
+// Start binds the listen address and serves requests in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	s.listener = listener
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("API server error", "error", err)
+		}
+	}()
+
+	slog.Info("API server listening", "address", listener.Addr().String())
+	return nil
+}
+
+// Addr returns the bound address, useful when listening on port 0
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.httpServer.Addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
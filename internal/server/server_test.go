@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	s := New("127.0.0.1:0")
+	s.Mux().HandleFunc("GET /custom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	require.NoError(t, s.Start())
+	defer s.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + s.Addr() + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + s.Addr() + "/custom")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+}
+
+func TestServerStartInvalidAddress(t *testing.T) {
+	s := New("not-an-address")
+	err := s.Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to listen on not-an-address")
+}
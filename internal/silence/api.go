@@ -0,0 +1,142 @@
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AddRequest is the body accepted by POST /api/v1/silences. The end time is
+// either EndsAt or StartsAt (default: now) plus Duration.
+type AddRequest struct {
+	Matchers   []Matcher  `json:"matchers,omitempty"`
+	Expression string     `json:"expression,omitempty"`
+	Outputs    []string   `json:"outputs,omitempty"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	Duration   string     `json:"duration,omitempty"` // e.g., "2h"
+	CreatedBy  string     `json:"created_by"`
+	Comment    string     `json:"comment"`
+}
+
+// maxRequestSize bounds the body of a request creating a silence
+const maxRequestSize = 1 << 20
+
+// API serves the silence HTTP endpoints
+type API struct {
+	manager *Manager
+}
+
+// NewAPI creates the HTTP API for a silence manager
+func NewAPI(manager *Manager) *API {
+	return &API{manager: manager}
+}
+
+// Register adds the silence endpoints to a mux
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/silences", a.list)
+	mux.HandleFunc("POST /api/v1/silences", a.add)
+	mux.HandleFunc("GET /api/v1/silences/{id}", a.get)
+	mux.HandleFunc("DELETE /api/v1/silences/{id}", a.expire)
+}
+
+func (a *API) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.manager.List())
+}
+
+func (a *API) get(w http.ResponseWriter, r *http.Request) {
+	s, err := a.manager.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+func (a *API) add(w http.ResponseWriter, r *http.Request) {
+	var req AddRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	s, err := req.silence(a.manager.now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	created, err := a.manager.Add(s)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (a *API) expire(w http.ResponseWriter, r *http.Request) {
+	s, err := a.manager.Expire(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// silence converts the request into a Silence, resolving the time window
+func (req AddRequest) silence(now time.Time) (Silence, error) {
+	if req.CreatedBy == "" {
+		return Silence{}, fmt.Errorf("created_by is required")
+	}
+	if req.Comment == "" {
+		return Silence{}, fmt.Errorf("comment is required")
+	}
+
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	var endsAt time.Time
+	switch {
+	case req.EndsAt != nil && req.Duration != "":
+		return Silence{}, fmt.Errorf("specify either ends_at or duration, not both")
+	case req.EndsAt != nil:
+		endsAt = *req.EndsAt
+	case req.Duration != "":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return Silence{}, fmt.Errorf("invalid duration %q: %w", req.Duration, err)
+		}
+		endsAt = startsAt.Add(duration)
+	default:
+		return Silence{}, fmt.Errorf("ends_at or duration is required")
+	}
+
+	return Silence{
+		Matchers:   req.Matchers,
+		Expression: req.Expression,
+		Outputs:    req.Outputs,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		CreatedBy:  req.CreatedBy,
+		Comment:    req.Comment,
+	}, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,100 @@
+package silence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI(t *testing.T) {
+	m, err := NewManager("", 0)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewAPI(m).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+
+	created, err := client.Add(AddRequest{
+		Matchers:  []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Node"}},
+		Outputs:   []string{"slack"},
+		Duration:  "2h",
+		CreatedBy: "ops",
+		Comment:   "rack maintenance",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, created.Status)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), created.EndsAt, time.Minute)
+	assert.NotNil(t, m.Match(nomad.Event{Topic: "Node"}, "slack"))
+
+	t.Run("list", func(t *testing.T) {
+		silences, err := client.List()
+		require.NoError(t, err)
+		require.Len(t, silences, 1)
+		assert.Equal(t, created.ID, silences[0].ID)
+		assert.Equal(t, "rack maintenance", silences[0].Comment)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/silences/" + created.ID)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Get(server.URL + "/api/v1/silences/missing")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		expired, err := client.Expire(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusExpired, expired.Status)
+		assert.Nil(t, m.Match(nomad.Event{Topic: "Node"}, "slack"))
+
+		_, err = client.Expire("missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "silence not found")
+	})
+
+	t.Run("validation errors are returned", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			req    AddRequest
+			errMsg string
+		}{
+			{"missing author", AddRequest{Duration: "1h", Comment: "x"}, "created_by is required"},
+			{"missing comment", AddRequest{Duration: "1h", CreatedBy: "ops"}, "comment is required"},
+			{"missing end", AddRequest{CreatedBy: "ops", Comment: "x"}, "ends_at or duration is required"},
+			{"invalid duration", AddRequest{Duration: "soon", CreatedBy: "ops", Comment: "x"}, "invalid duration"},
+			{"no criteria", AddRequest{Duration: "1h", CreatedBy: "ops", Comment: "x"}, "at least one matcher"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := client.Add(tt.req)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "status 400")
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+
+	t.Run("oversized bodies are rejected", func(t *testing.T) {
+		body := `{"comment": "` + strings.Repeat("x", maxRequestSize) + `"}`
+		resp, err := http.Post(server.URL+"/api/v1/silences", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
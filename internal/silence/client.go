@@ -0,0 +1,88 @@
+package silence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to the silence HTTP API of a running nomad-events instance
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL, e.g. "http://127.0.0.1:8686"
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// List returns all silences known to the server
+func (c *Client) List() ([]*Silence, error) {
+	var silences []*Silence
+	if err := c.do(http.MethodGet, "/api/v1/silences", nil, &silences); err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// Add creates a silence
+func (c *Client) Add(req AddRequest) (*Silence, error) {
+	var s Silence
+	if err := c.do(http.MethodPost, "/api/v1/silences", req, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Expire ends a silence immediately
+func (c *Client) Expire(id string) (*Silence, error) {
+	var s Silence
+	if err := c.do(http.MethodDelete, "/api/v1/silences/"+url.PathEscape(id), nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (c *Client) do(method, path string, body interface{}, result interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, &reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach nomad-events API at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("API returned status %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package silence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"nomad-events/internal/fsutil"
+	"nomad-events/internal/nomad"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ErrNotFound is returned when a silence ID does not exist
+var ErrNotFound = errors.New("silence not found")
+
+// DefaultPath is the file silences are stored in when no path is configured
+const DefaultPath = "silences.json"
+
+// DefaultRetention is how long expired silences are kept before being deleted
+const DefaultRetention = 5 * 24 * time.Hour
+
+// Manager holds silences, persisting them to a JSON file when a path is set
+type Manager struct {
+	mu        sync.RWMutex
+	silences  map[string]*Silence
+	path      string
+	retention time.Duration
+	celEnv    *cel.Env
+	now       func() time.Time
+}
+
+// NewManager creates a silence manager, loading existing silences from path.
+// An empty path keeps silences in memory only.
+func NewManager(path string, retention time.Duration) (*Manager, error) {
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+
+	m := &Manager{
+		silences:  make(map[string]*Silence),
+		path:      path,
+		retention: retention,
+		celEnv:    env,
+		now:       time.Now,
+	}
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Add validates and stores a new silence, returning it with its assigned ID
+func (m *Manager) Add(s Silence) (*Silence, error) {
+	now := m.now()
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	s.CreatedAt = now
+	s.Status = ""
+
+	if err := s.compile(m.celEnv); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	s.ID = id
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.silences[s.ID] = &s
+	if err := m.save(); err != nil {
+		delete(m.silences, s.ID)
+		return nil, err
+	}
+
+	return m.withStatus(&s, now), nil
+}
+
+// Expire ends a silence immediately
+func (m *Manager) Expire(id string) (*Silence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.silences[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	now := m.now()
+	if s.StatusAt(now) != StatusExpired {
+		previousStart, previousEnd := s.StartsAt, s.EndsAt
+		if now.Before(s.StartsAt) {
+			s.StartsAt = now
+		}
+		s.EndsAt = now
+
+		if err := m.save(); err != nil {
+			s.StartsAt, s.EndsAt = previousStart, previousEnd
+			return nil, err
+		}
+	}
+
+	return m.withStatus(s, now), nil
+}
+
+// Get returns a silence by ID
+func (m *Manager) Get(id string) (*Silence, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.silences[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return m.withStatus(s, m.now()), nil
+}
+
+// List returns all silences, newest first
+func (m *Manager) List() []*Silence {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	result := make([]*Silence, 0, len(m.silences))
+	for _, s := range m.silences {
+		result = append(result, m.withStatus(s, now))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Match returns the first active silence muting the event for an output, or nil
+func (m *Manager) Match(event nomad.Event, output string) *Silence {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.silences) == 0 {
+		return nil
+	}
+
+	now := m.now()
+	eventMap := nomad.EventMap(event)
+	for _, s := range m.silences {
+		if s.StatusAt(now) == StatusActive && s.matches(eventMap, output) {
+			return m.withStatus(s, now)
+		}
+	}
+	return nil
+}
+
+// withStatus returns a copy of the silence with its status filled in
+func (m *Manager) withStatus(s *Silence, now time.Time) *Silence {
+	c := *s
+	c.Status = s.StatusAt(now)
+	return &c
+}
+
+func (m *Manager) load() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read silences file %s: %w", m.path, err)
+	}
+
+	var stored []*Silence
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse silences file %s: %w", m.path, err)
+	}
+
+	for _, s := range stored {
+		if err := s.compile(m.celEnv); err != nil {
+			return fmt.Errorf("invalid silence %s in %s: %w", s.ID, m.path, err)
+		}
+		s.Status = ""
+		m.silences[s.ID] = s
+	}
+
+	return nil
+}
+
+// save drops silences expired for longer than the retention and writes the
+// rest to disk. Callers must hold m.mu.
+func (m *Manager) save() error {
+	now := m.now()
+	for id, s := range m.silences {
+		if now.Sub(s.EndsAt) > m.retention {
+			delete(m.silences, id)
+		}
+	}
+
+	if m.path == "" {
+		return nil
+	}
+
+	stored := make([]*Silence, 0, len(m.silences))
+	for _, s := range m.silences {
+		stored = append(stored, s)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal silences: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(m.path, data); err != nil {
+		return fmt.Errorf("failed to write silences file %s: %w", m.path, err)
+	}
+	return nil
+}
+
+// newID returns a random UUID-formatted identifier
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate silence ID: %w", err)
+	}
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], nil
+}
@@ -0,0 +1,128 @@
+package silence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nodeSilence(start time.Time, duration time.Duration) Silence {
+	return Silence{
+		Matchers:  []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Node"}},
+		StartsAt:  start,
+		EndsAt:    start.Add(duration),
+		CreatedBy: "ops",
+		Comment:   "maintenance",
+	}
+}
+
+func TestManager(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	nodeEvent := nomad.Event{Topic: "Node", Type: "NodeDrain"}
+	jobEvent := nomad.Event{Topic: "Job", Type: "JobRegistered"}
+
+	m, err := NewManager("", 0)
+	require.NoError(t, err)
+	m.now = func() time.Time { return now }
+
+	s, err := m.Add(nodeSilence(now, time.Hour))
+	require.NoError(t, err)
+	assert.NotEmpty(t, s.ID)
+	assert.Equal(t, StatusActive, s.Status)
+	assert.Equal(t, now, s.CreatedAt)
+
+	t.Run("matches active silences", func(t *testing.T) {
+		matched := m.Match(nodeEvent, "slack")
+		require.NotNil(t, matched)
+		assert.Equal(t, s.ID, matched.ID)
+		assert.Nil(t, m.Match(jobEvent, "slack"))
+	})
+
+	t.Run("pending silences do not match", func(t *testing.T) {
+		pending, err := m.Add(Silence{
+			Matchers:  []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}},
+			StartsAt:  now.Add(time.Hour),
+			EndsAt:    now.Add(2 * time.Hour),
+			CreatedBy: "ops",
+			Comment:   "later",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, pending.Status)
+		assert.Nil(t, m.Match(jobEvent, "slack"))
+	})
+
+	t.Run("get and list", func(t *testing.T) {
+		got, err := m.Get(s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, s.ID, got.ID)
+
+		_, err = m.Get("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		assert.Len(t, m.List(), 2)
+	})
+
+	t.Run("expire stops matching", func(t *testing.T) {
+		expired, err := m.Expire(s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusExpired, expired.Status)
+		assert.Nil(t, m.Match(nodeEvent, "slack"))
+
+		_, err = m.Expire("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("invalid silences are rejected", func(t *testing.T) {
+		_, err := m.Add(Silence{StartsAt: now, EndsAt: now.Add(time.Hour)})
+		assert.Error(t, err)
+	})
+}
+
+func TestManagerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	now := time.Now()
+
+	m, err := NewManager(path, time.Hour)
+	require.NoError(t, err)
+
+	s, err := m.Add(nodeSilence(now, time.Hour))
+	require.NoError(t, err)
+
+	t.Run("silences survive a restart", func(t *testing.T) {
+		reopened, err := NewManager(path, time.Hour)
+		require.NoError(t, err)
+
+		matched := reopened.Match(nomad.Event{Topic: "Node"}, "slack")
+		require.NotNil(t, matched)
+		assert.Equal(t, s.ID, matched.ID)
+		assert.Equal(t, "maintenance", matched.Comment)
+	})
+
+	t.Run("expired silences are removed after the retention", func(t *testing.T) {
+		_, err := m.Expire(s.ID)
+		require.NoError(t, err)
+		assert.Len(t, m.List(), 1)
+
+		m.now = func() time.Time { return now.Add(2 * time.Hour) }
+		_, err = m.Add(nodeSilence(now.Add(2*time.Hour), time.Hour))
+		require.NoError(t, err)
+
+		list := m.List()
+		require.Len(t, list, 1)
+		assert.NotEqual(t, s.ID, list[0].ID)
+	})
+
+	t.Run("invalid file fails to load", func(t *testing.T) {
+		badPath := filepath.Join(t.TempDir(), "bad.json")
+		require.NoError(t, os.WriteFile(badPath, []byte("not json"), 0o644))
+
+		_, err := NewManager(badPath, 0)
+		assert.Error(t, err)
+	})
+}
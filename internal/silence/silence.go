@@ -0,0 +1,187 @@
+package silence
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Silence statuses, derived from the start and end time
+const (
+	StatusPending = "pending"
+	StatusActive  = "active"
+	StatusExpired = "expired"
+)
+
+// Matcher operators
+const (
+	OpEqual    = "="
+	OpNotEqual = "!="
+	OpRegex    = "=~"
+	OpNotRegex = "!~"
+)
+
+// Silence mutes matching events between StartsAt and EndsAt. An event is
+// silenced when every matcher matches and the expression (if any) is true.
+type Silence struct {
+	ID         string    `json:"id"`
+	Matchers   []Matcher `json:"matchers,omitempty"`
+	Expression string    `json:"expression,omitempty"` // CEL expression over `event`
+	Outputs    []string  `json:"outputs,omitempty"`    // Only mute these outputs; empty mutes all
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	CreatedBy  string    `json:"created_by"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+	Status     string    `json:"status,omitempty"` // Computed when listing
+
+	matchers []compiledMatcher
+	program  cel.Program
+}
+
+// Matcher compares an event field, addressed by a dotted path such as
+// "Topic" or "Payload.Job.ID", against a value
+type Matcher struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type compiledMatcher struct {
+	Matcher
+	regex *regexp.Regexp
+}
+
+// ParseMatcher parses a matcher written as field=value, field!=value,
+// field=~regex or field!~regex
+func ParseMatcher(s string) (Matcher, error) {
+	for _, op := range []string{OpRegex, OpNotRegex, OpNotEqual, OpEqual} {
+		if i := strings.Index(s, op); i > 0 {
+			return Matcher{
+				Field:    strings.TrimSpace(s[:i]),
+				Operator: op,
+				Value:    strings.TrimSpace(s[i+len(op):]),
+			}, nil
+		}
+	}
+	return Matcher{}, fmt.Errorf("invalid matcher %q: expected field=value, field!=value, field=~regex or field!~regex", s)
+}
+
+func (m Matcher) String() string {
+	return m.Field + m.Operator + m.Value
+}
+
+// StatusAt returns the silence status at the given time
+func (s *Silence) StatusAt(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return StatusPending
+	case now.Before(s.EndsAt):
+		return StatusActive
+	default:
+		return StatusExpired
+	}
+}
+
+// compile validates the silence and prepares its matchers and expression
+func (s *Silence) compile(env *cel.Env) error {
+	if len(s.Matchers) == 0 && s.Expression == "" {
+		return fmt.Errorf("silence must have at least one matcher or an expression")
+	}
+
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+
+	s.matchers = make([]compiledMatcher, len(s.Matchers))
+	for i, m := range s.Matchers {
+		if m.Field == "" {
+			return fmt.Errorf("matcher %d: field is required", i)
+		}
+
+		compiled := compiledMatcher{Matcher: m}
+		switch m.Operator {
+		case OpEqual, OpNotEqual:
+		case OpRegex, OpNotRegex:
+			regex, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return fmt.Errorf("matcher %q: invalid regex: %w", m.String(), err)
+			}
+			compiled.regex = regex
+		default:
+			return fmt.Errorf("matcher %q: unsupported operator %q", m.String(), m.Operator)
+		}
+		s.matchers[i] = compiled
+	}
+
+	s.program = nil
+	if s.Expression != "" {
+		ast, issues := env.Compile(s.Expression)
+		if issues.Err() != nil {
+			return fmt.Errorf("failed to compile expression: %w", issues.Err())
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			return fmt.Errorf("failed to create program for expression: %w", err)
+		}
+		s.program = program
+	}
+
+	return nil
+}
+
+// matches reports whether the silence applies to an event delivered to an output
+func (s *Silence) matches(eventMap map[string]interface{}, output string) bool {
+	if len(s.Outputs) > 0 && !slices.Contains(s.Outputs, output) {
+		return false
+	}
+
+	for _, m := range s.matchers {
+		if !m.matches(eventMap) {
+			return false
+		}
+	}
+
+	if s.program != nil {
+		result, _, err := s.program.Eval(map[string]interface{}{"event": eventMap})
+		if err != nil || result != types.True {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m compiledMatcher) matches(eventMap map[string]interface{}) bool {
+	value := ""
+	if v, ok := nomad.LookupField(eventMap, m.Field); ok && v != nil {
+		value = fmt.Sprint(v)
+	}
+
+	switch m.Operator {
+	case OpEqual:
+		return value == m.Value
+	case OpNotEqual:
+		return value != m.Value
+	case OpRegex:
+		return m.regex.MatchString(value)
+	case OpNotRegex:
+		return !m.regex.MatchString(value)
+	}
+	return false
+}
+
+// newCELEnv creates the CEL environment used for silence expressions
+func newCELEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+	)
+}
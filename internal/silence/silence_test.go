@@ -0,0 +1,220 @@
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatcher(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Matcher
+		wantErr  bool
+	}{
+		{"Topic=Node", Matcher{Field: "Topic", Operator: OpEqual, Value: "Node"}, false},
+		{"Topic!=Node", Matcher{Field: "Topic", Operator: OpNotEqual, Value: "Node"}, false},
+		{"Payload.Job.ID=~web-.*", Matcher{Field: "Payload.Job.ID", Operator: OpRegex, Value: "web-.*"}, false},
+		{"Type!~Job.*", Matcher{Field: "Type", Operator: OpNotRegex, Value: "Job.*"}, false},
+		{"Key = abc", Matcher{Field: "Key", Operator: OpEqual, Value: "abc"}, false},
+		{"Topic", Matcher{}, true},
+		{"=Node", Matcher{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := ParseMatcher(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
+
+func TestSilenceStatusAt(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := Silence{StartsAt: start, EndsAt: start.Add(time.Hour)}
+
+	assert.Equal(t, StatusPending, s.StatusAt(start.Add(-time.Second)))
+	assert.Equal(t, StatusActive, s.StatusAt(start))
+	assert.Equal(t, StatusActive, s.StatusAt(start.Add(59*time.Minute)))
+	assert.Equal(t, StatusExpired, s.StatusAt(start.Add(time.Hour)))
+}
+
+func TestSilenceMatches(t *testing.T) {
+	env, err := newCELEnv()
+	require.NoError(t, err)
+
+	event := nomad.Event{
+		Topic:     "Job",
+		Type:      "JobRegistered",
+		Namespace: "default",
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web-api", "Priority": 50},
+		},
+	}
+	eventMap := nomad.EventMap(event)
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		silence  Silence
+		output   string
+		expected bool
+	}{
+		{
+			name:     "equal matcher",
+			silence:  Silence{Matchers: []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}}},
+			expected: true,
+		},
+		{
+			name:     "not equal matcher",
+			silence:  Silence{Matchers: []Matcher{{Field: "Topic", Operator: OpNotEqual, Value: "Job"}}},
+			expected: false,
+		},
+		{
+			name:     "regex matcher on nested field",
+			silence:  Silence{Matchers: []Matcher{{Field: "Payload.Job.ID", Operator: OpRegex, Value: "web-.*"}}},
+			expected: true,
+		},
+		{
+			name:     "regex is anchored",
+			silence:  Silence{Matchers: []Matcher{{Field: "Payload.Job.ID", Operator: OpRegex, Value: "web"}}},
+			expected: false,
+		},
+		{
+			name:     "negative regex matcher",
+			silence:  Silence{Matchers: []Matcher{{Field: "Type", Operator: OpNotRegex, Value: "Node.*"}}},
+			expected: true,
+		},
+		{
+			name:     "non-string field",
+			silence:  Silence{Matchers: []Matcher{{Field: "Payload.Job.Priority", Operator: OpEqual, Value: "50"}}},
+			expected: true,
+		},
+		{
+			name:     "missing field compares as empty",
+			silence:  Silence{Matchers: []Matcher{{Field: "Payload.Node.ID", Operator: OpEqual, Value: ""}}},
+			expected: true,
+		},
+		{
+			name: "all matchers must match",
+			silence: Silence{Matchers: []Matcher{
+				{Field: "Topic", Operator: OpEqual, Value: "Job"},
+				{Field: "Namespace", Operator: OpEqual, Value: "prod"},
+			}},
+			expected: false,
+		},
+		{
+			name:     "expression",
+			silence:  Silence{Expression: `event.Payload.Job.ID.startsWith("web")`},
+			expected: true,
+		},
+		{
+			name: "matchers and expression",
+			silence: Silence{
+				Matchers:   []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}},
+				Expression: `event.Type == "JobDeregistered"`,
+			},
+			expected: false,
+		},
+		{
+			name: "expression error does not match",
+			silence: Silence{
+				Expression: `event.Payload.Node.ID == "x"`,
+			},
+			expected: false,
+		},
+		{
+			name: "limited to other outputs",
+			silence: Silence{
+				Matchers: []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}},
+				Outputs:  []string{"slack"},
+			},
+			output:   "webhook",
+			expected: false,
+		},
+		{
+			name: "limited to this output",
+			silence: Silence{
+				Matchers: []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}},
+				Outputs:  []string{"slack"},
+			},
+			output:   "slack",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.silence
+			s.StartsAt = start
+			s.EndsAt = start.Add(time.Hour)
+			require.NoError(t, s.compile(env))
+
+			output := tt.output
+			if output == "" {
+				output = "stdout"
+			}
+			assert.Equal(t, tt.expected, s.matches(eventMap, output))
+		})
+	}
+}
+
+func TestSilenceCompileErrors(t *testing.T) {
+	env, err := newCELEnv()
+	require.NoError(t, err)
+
+	start := time.Now()
+	tests := []struct {
+		name    string
+		silence Silence
+		errMsg  string
+	}{
+		{
+			name:    "no criteria",
+			silence: Silence{},
+			errMsg:  "at least one matcher or an expression",
+		},
+		{
+			name:    "ends before start",
+			silence: Silence{Matchers: []Matcher{{Field: "Topic", Operator: OpEqual, Value: "Job"}}, EndsAt: start.Add(-time.Hour)},
+			errMsg:  "must end after it starts",
+		},
+		{
+			name:    "invalid regex",
+			silence: Silence{Matchers: []Matcher{{Field: "Topic", Operator: OpRegex, Value: "("}}},
+			errMsg:  "invalid regex",
+		},
+		{
+			name:    "unsupported operator",
+			silence: Silence{Matchers: []Matcher{{Field: "Topic", Operator: "~", Value: "Job"}}},
+			errMsg:  "unsupported operator",
+		},
+		{
+			name:    "invalid expression",
+			silence: Silence{Expression: "event.Topic =="},
+			errMsg:  "failed to compile expression",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.silence
+			s.StartsAt = start
+			if s.EndsAt.IsZero() {
+				s.EndsAt = start.Add(time.Hour)
+			}
+			err := s.compile(env)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
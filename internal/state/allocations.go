@@ -0,0 +1,347 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+)
+
+// Derived allocation event types
+const (
+	TypeAllocationFailed       = "AllocationFailed"
+	TypeTaskRestartLoop        = "TaskRestartLoop"
+	TypeAllocationRecovered    = "AllocationRecovered"
+	TypeAllocationStuckPending = "AllocationStuckPending"
+)
+
+const (
+	defaultRestartThreshold = 3
+	defaultRestartWindow    = 10 * time.Minute
+	defaultPendingTimeout   = 5 * time.Minute
+	defaultAllocRetention   = time.Hour
+
+	// staleAllocationAge is how long an allocation without updates is
+	// remembered, for allocations whose terminal event was missed
+	staleAllocationAge = 24 * time.Hour
+)
+
+// AllocationTracker keeps the last known status of every allocation and its
+// tasks, and emits derived events when they fail, restart repeatedly, stay
+// pending too long, or recover from one of those problems.
+type AllocationTracker struct {
+	restartThreshold int
+	restartWindow    time.Duration
+	pendingTimeout   time.Duration
+	retention        time.Duration
+	now              func() time.Time
+
+	mu     sync.Mutex
+	allocs map[string]*allocState
+}
+
+type allocState struct {
+	id           string
+	namespace    string
+	index        uint64
+	clientStatus string
+	previousID   string // allocation this one replaced
+	payload      map[string]interface{}
+	tasks        map[string]*taskState
+
+	pendingSince time.Time
+	stuckPending bool
+	failed       bool
+	finishedAt   time.Time // zero while the allocation is not terminal
+	updatedAt    time.Time
+}
+
+type taskState struct {
+	state        string
+	restarts     int
+	restartTimes []time.Time
+	looping      bool
+}
+
+// NewAllocationTracker creates an allocation tracker from its configuration
+func NewAllocationTracker(cfg config.AllocationTrackingConfig) (*AllocationTracker, error) {
+	t := &AllocationTracker{
+		restartThreshold: cfg.RestartThreshold,
+		restartWindow:    defaultRestartWindow,
+		pendingTimeout:   defaultPendingTimeout,
+		retention:        defaultAllocRetention,
+		now:              time.Now,
+		allocs:           make(map[string]*allocState),
+	}
+
+	if t.restartThreshold == 0 {
+		t.restartThreshold = defaultRestartThreshold
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"restart_window", cfg.RestartWindow, &t.restartWindow},
+		{"pending_timeout", cfg.PendingTimeout, &t.pendingTimeout},
+		{"retention", cfg.Retention, &t.retention},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", d.name, d.value, err)
+		}
+		*d.dest = parsed
+	}
+
+	return t, nil
+}
+
+// Observe updates the tracked state from an Allocation event and returns any derived events
+func (t *AllocationTracker) Observe(event nomad.Event) []nomad.Event {
+	if event.Topic != "Allocation" {
+		return nil
+	}
+
+	alloc, ok := payloadObject(event, "Allocation")
+	if !ok {
+		return nil
+	}
+	id := stringField(alloc, "ID")
+	if id == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	st, exists := t.allocs[id]
+	if !exists {
+		st = &allocState{id: id, tasks: make(map[string]*taskState)}
+		t.allocs[id] = st
+	}
+
+	previousStatus := st.clientStatus
+	st.namespace = stringField(alloc, "Namespace")
+	st.index = event.Index
+	st.clientStatus = stringField(alloc, "ClientStatus")
+	st.previousID = stringField(alloc, "PreviousAllocation")
+	st.payload = alloc
+	st.updatedAt = now
+
+	var derived []nomad.Event
+	derived = append(derived, t.observeTasks(st, alloc, now)...)
+
+	switch st.clientStatus {
+	case "pending":
+		if st.pendingSince.IsZero() {
+			st.pendingSince = now
+		}
+	case "running":
+		st.pendingSince = time.Time{}
+		if st.stuckPending {
+			st.stuckPending = false
+			derived = append(derived, t.recovered(st, "started after being stuck pending", nil))
+		}
+		if previous, exists := t.allocs[st.previousID]; exists && previous.failed {
+			previous.failed = false
+			derived = append(derived, t.recovered(st, fmt.Sprintf("replaced failed allocation %s", shortID(previous.id)), map[string]interface{}{
+				"PreviousAllocation": previous.id,
+			}))
+		}
+	case "failed":
+		st.pendingSince = time.Time{}
+		if previousStatus != "failed" {
+			st.failed = true
+			derived = append(derived, t.allocationFailed(st, previousStatus))
+		}
+	}
+
+	if isTerminal(st.clientStatus) {
+		if st.finishedAt.IsZero() {
+			st.finishedAt = now
+		}
+	} else {
+		st.finishedAt = time.Time{}
+	}
+
+	return derived
+}
+
+// observeTasks records task restarts and returns TaskRestartLoop events. Callers must hold t.mu.
+func (t *AllocationTracker) observeTasks(st *allocState, alloc map[string]interface{}, now time.Time) []nomad.Event {
+	taskStates, _ := alloc["TaskStates"].(map[string]interface{})
+
+	var derived []nomad.Event
+	for _, name := range sortedKeys(taskStates) {
+		fields, ok := taskStates[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		restarts := intField(fields, "Restarts")
+		task, exists := st.tasks[name]
+		if !exists {
+			// Restarts before we started watching have no timestamps to window on
+			task = &taskState{restarts: restarts}
+			st.tasks[name] = task
+		}
+		task.state = stringField(fields, "State")
+
+		for ; task.restarts < restarts; task.restarts++ {
+			task.restartTimes = append(task.restartTimes, now)
+		}
+		task.restartTimes = pruneBefore(task.restartTimes, now.Add(-t.restartWindow))
+
+		if !task.looping && len(task.restartTimes) >= t.restartThreshold {
+			task.looping = true
+			derived = append(derived, t.derived(TypeTaskRestartLoop, st, fmt.Sprintf(
+				"Task %s of allocation %s restarted %d times in %s", name, t.describe(st), len(task.restartTimes), t.restartWindow),
+				map[string]interface{}{
+					"Task":          name,
+					"Restarts":      len(task.restartTimes),
+					"TotalRestarts": restarts,
+					"Window":        t.restartWindow.String(),
+				}))
+		}
+	}
+
+	return derived
+}
+
+// Sweep emits events for allocations stuck pending or whose restart loop has
+// ended, and forgets allocations that finished longer ago than the retention
+// or have not been updated for a day, e.g. because they were garbage collected
+// or lost while disconnected and their terminal event was missed
+func (t *AllocationTracker) Sweep() []nomad.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var derived []nomad.Event
+
+	for _, id := range sortedKeys(t.allocs) {
+		st := t.allocs[id]
+
+		if (!st.finishedAt.IsZero() && now.Sub(st.finishedAt) > t.retention) || now.Sub(st.updatedAt) > staleAllocationAge {
+			delete(t.allocs, id)
+			continue
+		}
+
+		if st.clientStatus == "pending" && !st.stuckPending && now.Sub(st.pendingSince) >= t.pendingTimeout {
+			st.stuckPending = true
+			pendingFor := now.Sub(st.pendingSince).Truncate(time.Second)
+			derived = append(derived, t.derived(TypeAllocationStuckPending, st, fmt.Sprintf(
+				"Allocation %s has been pending for %s", t.describe(st), pendingFor),
+				map[string]interface{}{
+					"PendingFor":     pendingFor.String(),
+					"PendingSeconds": int(pendingFor.Seconds()),
+				}))
+		}
+
+		for _, name := range sortedKeys(st.tasks) {
+			task := st.tasks[name]
+			task.restartTimes = pruneBefore(task.restartTimes, now.Add(-t.restartWindow))
+			if !task.looping || len(task.restartTimes) > 0 {
+				continue
+			}
+
+			task.looping = false
+			if st.clientStatus == "running" && task.state == "running" {
+				derived = append(derived, t.recovered(st, fmt.Sprintf("task %s stopped restarting", name), map[string]interface{}{
+					"Task": name,
+				}))
+			}
+		}
+	}
+
+	return derived
+}
+
+// allocationFailed builds an AllocationFailed event. Callers must hold t.mu.
+func (t *AllocationTracker) allocationFailed(st *allocState, previousStatus string) nomad.Event {
+	var failedTasks []string
+	taskStates, _ := st.payload["TaskStates"].(map[string]interface{})
+	for _, name := range sortedKeys(taskStates) {
+		if fields, ok := taskStates[name].(map[string]interface{}); ok && fields["Failed"] == true {
+			failedTasks = append(failedTasks, name)
+		}
+	}
+
+	message := fmt.Sprintf("Allocation %s failed", t.describe(st))
+	if len(failedTasks) > 0 {
+		message += ": task " + strings.Join(failedTasks, ", ") + " failed"
+	}
+
+	return t.derived(TypeAllocationFailed, st, message, map[string]interface{}{
+		"PreviousStatus": previousStatus,
+		"FailedTasks":    failedTasks,
+	})
+}
+
+// recovered builds an AllocationRecovered event. Callers must hold t.mu.
+func (t *AllocationTracker) recovered(st *allocState, reason string, fields map[string]interface{}) nomad.Event {
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	fields["Reason"] = reason
+	return t.derived(TypeAllocationRecovered, st, fmt.Sprintf("Allocation %s recovered: %s", t.describe(st), reason), fields)
+}
+
+// derived builds a derived event carrying the allocation's last known payload
+func (t *AllocationTracker) derived(eventType string, st *allocState, message string, fields map[string]interface{}) nomad.Event {
+	payload := map[string]interface{}{
+		"Message":    message,
+		"Allocation": st.payload,
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+
+	return nomad.Event{
+		Topic:     nomad.TopicDerived,
+		Type:      eventType,
+		Key:       st.id,
+		Namespace: st.namespace,
+		Index:     st.index,
+		Payload:   payload,
+	}
+}
+
+// describe returns "<short id> of job <job> (group <group>)" for messages
+func (t *AllocationTracker) describe(st *allocState) string {
+	return fmt.Sprintf("%s of job %s (group %s)", shortID(st.id), stringField(st.payload, "JobID"), stringField(st.payload, "TaskGroup"))
+}
+
+func isTerminal(clientStatus string) bool {
+	switch clientStatus {
+	case "complete", "failed", "lost":
+		return true
+	}
+	return false
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
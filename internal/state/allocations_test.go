@@ -0,0 +1,255 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a controllable clock for trackers
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestTracker(t *testing.T, cfg config.AllocationTrackingConfig) (*AllocationTracker, *testClock) {
+	tracker, err := NewAllocationTracker(cfg)
+	require.NoError(t, err)
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	tracker.now = clock.Now
+	return tracker, clock
+}
+
+// allocEvent builds an AllocationUpdated event; tasks maps task name to
+// {state, restarts, failed}
+func allocEvent(id, clientStatus string, tasks map[string]taskFixture) nomad.Event {
+	taskStates := map[string]interface{}{}
+	for name, task := range tasks {
+		taskStates[name] = map[string]interface{}{
+			"State":    task.state,
+			"Restarts": float64(task.restarts),
+			"Failed":   task.failed,
+		}
+	}
+
+	return nomad.Event{
+		Topic: "Allocation",
+		Type:  "AllocationUpdated",
+		Key:   id,
+		Index: 100,
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":           id,
+				"JobID":        "web",
+				"TaskGroup":    "api",
+				"Namespace":    "default",
+				"ClientStatus": clientStatus,
+				"TaskStates":   taskStates,
+			},
+		},
+	}
+}
+
+type taskFixture struct {
+	state    string
+	restarts int
+	failed   bool
+}
+
+func eventTypes(events []nomad.Event) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = event.Type
+	}
+	return result
+}
+
+func TestAllocationTrackerFailed(t *testing.T) {
+	tracker, _ := newTestTracker(t, config.AllocationTrackingConfig{})
+
+	derived := tracker.Observe(allocEvent("alloc-1234567890", "running", map[string]taskFixture{
+		"server": {state: "running"},
+	}))
+	assert.Empty(t, derived)
+
+	derived = tracker.Observe(allocEvent("alloc-1234567890", "failed", map[string]taskFixture{
+		"server":  {state: "dead", failed: true},
+		"sidecar": {state: "dead"},
+	}))
+	require.Len(t, derived, 1)
+
+	event := derived[0]
+	assert.Equal(t, nomad.TopicDerived, event.Topic)
+	assert.Equal(t, TypeAllocationFailed, event.Type)
+	assert.Equal(t, "alloc-1234567890", event.Key)
+	assert.Equal(t, "default", event.Namespace)
+	assert.Equal(t, uint64(100), event.Index)
+
+	payload := event.Payload.(map[string]interface{})
+	assert.Equal(t, "Allocation alloc-12 of job web (group api) failed: task server failed", payload["Message"])
+	assert.Equal(t, "running", payload["PreviousStatus"])
+	assert.Equal(t, []string{"server"}, payload["FailedTasks"])
+	assert.Equal(t, "web", payload["Allocation"].(map[string]interface{})["JobID"])
+
+	// Repeated updates for an already failed allocation emit nothing
+	derived = tracker.Observe(allocEvent("alloc-1234567890", "failed", nil))
+	assert.Empty(t, derived)
+}
+
+func TestAllocationTrackerRescheduleRecovers(t *testing.T) {
+	tracker, _ := newTestTracker(t, config.AllocationTrackingConfig{})
+
+	tracker.Observe(allocEvent("alloc-old", "failed", nil))
+
+	replacement := allocEvent("alloc-new", "pending", nil)
+	replacement.Payload.(map[string]interface{})["Allocation"].(map[string]interface{})["PreviousAllocation"] = "alloc-old"
+	assert.Empty(t, tracker.Observe(replacement))
+
+	replacement.Payload.(map[string]interface{})["Allocation"].(map[string]interface{})["ClientStatus"] = "running"
+	derived := tracker.Observe(replacement)
+	require.Len(t, derived, 1)
+	assert.Equal(t, TypeAllocationRecovered, derived[0].Type)
+	assert.Equal(t, "alloc-new", derived[0].Key)
+	assert.Equal(t, "alloc-old", derived[0].Payload.(map[string]interface{})["PreviousAllocation"])
+
+	// Recovery is only reported once
+	assert.Empty(t, tracker.Observe(replacement))
+}
+
+func TestAllocationTrackerRestartLoop(t *testing.T) {
+	tracker, clock := newTestTracker(t, config.AllocationTrackingConfig{
+		RestartThreshold: 3,
+		RestartWindow:    "10m",
+	})
+
+	observe := func(restarts int, state string) []nomad.Event {
+		return tracker.Observe(allocEvent("alloc-1", "running", map[string]taskFixture{
+			"server": {state: state, restarts: restarts},
+		}))
+	}
+
+	// Restarts from before the tracker started are not counted
+	assert.Empty(t, observe(7, "running"))
+
+	clock.Advance(time.Minute)
+	assert.Empty(t, observe(8, "running"))
+	clock.Advance(time.Minute)
+	assert.Empty(t, observe(9, "running"))
+	clock.Advance(time.Minute)
+
+	derived := observe(10, "running")
+	require.Equal(t, []string{TypeTaskRestartLoop}, eventTypes(derived))
+	payload := derived[0].Payload.(map[string]interface{})
+	assert.Equal(t, "server", payload["Task"])
+	assert.Equal(t, 3, payload["Restarts"])
+	assert.Equal(t, 10, payload["TotalRestarts"])
+
+	// Further restarts in the same loop are not reported again
+	clock.Advance(time.Minute)
+	assert.Empty(t, observe(11, "running"))
+
+	// Still restarting within the window
+	clock.Advance(5 * time.Minute)
+	assert.Empty(t, tracker.Sweep())
+
+	// Once the window has passed without restarts the allocation has recovered
+	clock.Advance(10 * time.Minute)
+	derived = tracker.Sweep()
+	require.Equal(t, []string{TypeAllocationRecovered}, eventTypes(derived))
+	assert.Equal(t, "server", derived[0].Payload.(map[string]interface{})["Task"])
+
+	assert.Empty(t, tracker.Sweep())
+}
+
+func TestAllocationTrackerRestartsOutsideWindow(t *testing.T) {
+	tracker, clock := newTestTracker(t, config.AllocationTrackingConfig{
+		RestartThreshold: 2,
+		RestartWindow:    "1m",
+	})
+
+	for restarts := 0; restarts < 5; restarts++ {
+		derived := tracker.Observe(allocEvent("alloc-1", "running", map[string]taskFixture{
+			"server": {state: "running", restarts: restarts},
+		}))
+		assert.Empty(t, derived)
+		clock.Advance(2 * time.Minute)
+	}
+}
+
+func TestAllocationTrackerStuckPending(t *testing.T) {
+	tracker, clock := newTestTracker(t, config.AllocationTrackingConfig{PendingTimeout: "5m"})
+
+	tracker.Observe(allocEvent("alloc-1", "pending", nil))
+
+	clock.Advance(4 * time.Minute)
+	assert.Empty(t, tracker.Sweep())
+
+	clock.Advance(time.Minute)
+	derived := tracker.Sweep()
+	require.Equal(t, []string{TypeAllocationStuckPending}, eventTypes(derived))
+	payload := derived[0].Payload.(map[string]interface{})
+	assert.Equal(t, "5m0s", payload["PendingFor"])
+	assert.Equal(t, 300, payload["PendingSeconds"])
+
+	// Reported once
+	clock.Advance(time.Minute)
+	assert.Empty(t, tracker.Sweep())
+
+	derived = tracker.Observe(allocEvent("alloc-1", "running", nil))
+	require.Equal(t, []string{TypeAllocationRecovered}, eventTypes(derived))
+}
+
+func TestAllocationTrackerRetention(t *testing.T) {
+	tracker, clock := newTestTracker(t, config.AllocationTrackingConfig{Retention: "10m"})
+
+	tracker.Observe(allocEvent("alloc-1", "complete", nil))
+	tracker.Observe(allocEvent("alloc-2", "running", nil))
+
+	clock.Advance(11 * time.Minute)
+	tracker.Sweep()
+
+	assert.NotContains(t, tracker.allocs, "alloc-1")
+	assert.Contains(t, tracker.allocs, "alloc-2")
+}
+
+func TestAllocationTrackerForgetsStaleAllocations(t *testing.T) {
+	tracker, clock := newTestTracker(t, config.AllocationTrackingConfig{})
+
+	// alloc-1 is garbage collected without a terminal event reaching us
+	tracker.Observe(allocEvent("alloc-1", "running", nil))
+	clock.Advance(23 * time.Hour)
+	tracker.Observe(allocEvent("alloc-2", "running", nil))
+
+	clock.Advance(2 * time.Hour)
+	tracker.Sweep()
+
+	assert.NotContains(t, tracker.allocs, "alloc-1")
+	assert.Contains(t, tracker.allocs, "alloc-2")
+}
+
+func TestAllocationTrackerIgnoresOtherEvents(t *testing.T) {
+	tracker, _ := newTestTracker(t, config.AllocationTrackingConfig{})
+
+	assert.Empty(t, tracker.Observe(nomad.Event{Topic: "Node", Type: "NodeRegistration"}))
+	assert.Empty(t, tracker.Observe(nomad.Event{Topic: "Allocation", Payload: map[string]interface{}{}}))
+	assert.Empty(t, tracker.allocs)
+}
+
+func TestNewAllocationTrackerInvalid(t *testing.T) {
+	_, err := NewAllocationTracker(config.AllocationTrackingConfig{RestartWindow: "soon"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid restart_window")
+}
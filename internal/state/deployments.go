@@ -0,0 +1,277 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+)
+
+// Derived deployment event types
+const (
+	TypeDeploymentStarted    = "DeploymentStarted"
+	TypeDeploymentProgress   = "DeploymentProgress"
+	TypeDeploymentSucceeded  = "DeploymentSucceeded"
+	TypeDeploymentFailed     = "DeploymentFailed"
+	TypeDeploymentRolledBack = "DeploymentRolledBack"
+	TypeDeploymentCancelled  = "DeploymentCancelled"
+)
+
+// staleDeploymentAge is how long a deployment without updates is remembered
+const staleDeploymentAge = 24 * time.Hour
+
+// DeploymentAggregator correlates Deployment events with the job version and
+// task group health, and emits one consolidated event per lifecycle step
+// instead of every raw Deployment event.
+type DeploymentAggregator struct {
+	progress bool
+	now      func() time.Time
+
+	mu          sync.Mutex
+	deployments map[string]*deploymentState
+	jobDiffs    map[string]jobDiff // namespace/job ID -> diff of the latest registered version
+}
+
+type deploymentState struct {
+	startedAt time.Time
+	updatedAt time.Time
+	health    deploymentHealth
+}
+
+type deploymentHealth struct {
+	desired, placed, healthy, unhealthy int
+}
+
+type jobDiff struct {
+	version    int
+	diff       interface{}
+	recordedAt time.Time
+}
+
+// NewDeploymentAggregator creates a deployment aggregator from its configuration
+func NewDeploymentAggregator(cfg config.DeploymentTrackingConfig) *DeploymentAggregator {
+	progress := true
+	if cfg.Progress != nil {
+		progress = *cfg.Progress
+	}
+
+	return &DeploymentAggregator{
+		progress:    progress,
+		now:         time.Now,
+		deployments: make(map[string]*deploymentState),
+		jobDiffs:    make(map[string]jobDiff),
+	}
+}
+
+// Observe records job diffs from Job events and returns consolidated events for Deployment events
+func (a *DeploymentAggregator) Observe(event nomad.Event) []nomad.Event {
+	switch event.Topic {
+	case "Job":
+		a.observeJob(event)
+		return nil
+	case "Deployment":
+		return a.observeDeployment(event)
+	}
+	return nil
+}
+
+func (a *DeploymentAggregator) observeJob(event nomad.Event) {
+	if event.Diff == nil {
+		return
+	}
+
+	job, ok := payloadObject(event, "Job")
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.jobDiffs[jobKey(stringField(job, "Namespace"), stringField(job, "ID"))] = jobDiff{
+		version:    intField(job, "Version"),
+		diff:       event.Diff,
+		recordedAt: a.now(),
+	}
+}
+
+func (a *DeploymentAggregator) observeDeployment(event nomad.Event) []nomad.Event {
+	deployment, ok := payloadObject(event, "Deployment")
+	if !ok {
+		return nil
+	}
+	id := stringField(deployment, "ID")
+	if id == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	status := stringField(deployment, "Status")
+	health := deploymentHealthOf(deployment)
+
+	st, exists := a.deployments[id]
+	if !exists {
+		st = &deploymentState{startedAt: now, health: health}
+		if created := intField(deployment, "CreateTime"); created > 0 {
+			st.startedAt = time.Unix(0, int64(created))
+		}
+		a.deployments[id] = st
+	}
+	st.updatedAt = now
+
+	var eventType string
+	switch status {
+	case "successful":
+		eventType = TypeDeploymentSucceeded
+	case "failed":
+		eventType = TypeDeploymentFailed
+		if strings.Contains(strings.ToLower(stringField(deployment, "StatusDescription")), "rolling back") {
+			eventType = TypeDeploymentRolledBack
+		}
+	case "cancelled":
+		eventType = TypeDeploymentCancelled
+	default:
+		if !exists {
+			eventType = TypeDeploymentStarted
+		} else if a.progress && health != st.health {
+			eventType = TypeDeploymentProgress
+		}
+	}
+	st.health = health
+
+	if eventType == "" {
+		return nil
+	}
+
+	finished := eventType != TypeDeploymentStarted && eventType != TypeDeploymentProgress
+	consolidated := a.consolidated(event, eventType, deployment, st, now, finished)
+	if finished {
+		delete(a.deployments, id)
+		a.forgetJobDiff(deployment)
+	}
+
+	return []nomad.Event{consolidated}
+}
+
+// consolidated builds a derived deployment event. Callers must hold a.mu.
+func (a *DeploymentAggregator) consolidated(event nomad.Event, eventType string, deployment map[string]interface{}, st *deploymentState, now time.Time, finished bool) nomad.Event {
+	namespace := stringField(deployment, "Namespace")
+	jobID := stringField(deployment, "JobID")
+	version := intField(deployment, "JobVersion")
+	duration := now.Sub(st.startedAt).Truncate(time.Second)
+	if duration < 0 {
+		duration = 0
+	}
+
+	payload := map[string]interface{}{
+		"Deployment":        deployment,
+		"JobID":             jobID,
+		"JobVersion":        version,
+		"Status":            stringField(deployment, "Status"),
+		"StatusDescription": stringField(deployment, "StatusDescription"),
+		"Desired":           st.health.desired,
+		"Placed":            st.health.placed,
+		"Healthy":           st.health.healthy,
+		"Unhealthy":         st.health.unhealthy,
+		"StartedAt":         st.startedAt.UTC().Format(time.RFC3339),
+		"Duration":          duration.String(),
+		"DurationSeconds":   int(duration.Seconds()),
+		"Finished":          finished,
+	}
+	payload["Message"] = deploymentMessage(eventType, jobID, version, st.health, duration)
+
+	var diff interface{}
+	if d, ok := a.jobDiffs[jobKey(namespace, jobID)]; ok && d.version == version {
+		diff = d.diff
+	}
+
+	return nomad.Event{
+		Topic:     nomad.TopicDerived,
+		Type:      eventType,
+		Key:       stringField(deployment, "ID"),
+		Namespace: namespace,
+		Index:     event.Index,
+		Payload:   payload,
+		Diff:      diff,
+	}
+}
+
+// forgetJobDiff drops the diff of a finished deployment's job version.
+// Callers must hold a.mu.
+func (a *DeploymentAggregator) forgetJobDiff(deployment map[string]interface{}) {
+	key := jobKey(stringField(deployment, "Namespace"), stringField(deployment, "JobID"))
+	if d, ok := a.jobDiffs[key]; ok && d.version == intField(deployment, "JobVersion") {
+		delete(a.jobDiffs, key)
+	}
+}
+
+// Sweep forgets deployments that have not been updated for a day, e.g.
+// because their final event was missed while disconnected, and job diffs
+// recorded a day ago, such as those of versions that never deployed
+func (a *DeploymentAggregator) Sweep() []nomad.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for id, st := range a.deployments {
+		if now.Sub(st.updatedAt) > staleDeploymentAge {
+			delete(a.deployments, id)
+		}
+	}
+	for key, d := range a.jobDiffs {
+		if now.Sub(d.recordedAt) > staleDeploymentAge {
+			delete(a.jobDiffs, key)
+		}
+	}
+	return nil
+}
+
+func deploymentMessage(eventType, jobID string, version int, health deploymentHealth, duration time.Duration) string {
+	subject := fmt.Sprintf("Deployment of job %s v%d", jobID, version)
+	healthy := fmt.Sprintf("%d/%d healthy", health.healthy, health.desired)
+
+	switch eventType {
+	case TypeDeploymentStarted:
+		return fmt.Sprintf("%s started (%s)", subject, healthy)
+	case TypeDeploymentProgress:
+		return fmt.Sprintf("%s in progress: %s after %s", subject, healthy, duration)
+	case TypeDeploymentSucceeded:
+		return fmt.Sprintf("%s succeeded in %s (%s)", subject, duration, healthy)
+	case TypeDeploymentRolledBack:
+		return fmt.Sprintf("%s failed and was rolled back after %s (%s)", subject, duration, healthy)
+	case TypeDeploymentCancelled:
+		return fmt.Sprintf("%s was cancelled after %s (%s)", subject, duration, healthy)
+	default:
+		return fmt.Sprintf("%s failed after %s (%s)", subject, duration, healthy)
+	}
+}
+
+// deploymentHealthOf sums the allocation counts of every task group
+func deploymentHealthOf(deployment map[string]interface{}) deploymentHealth {
+	var health deploymentHealth
+	groups, _ := deployment["TaskGroups"].(map[string]interface{})
+	for _, group := range groups {
+		fields, ok := group.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		health.desired += intField(fields, "DesiredTotal")
+		health.placed += intField(fields, "PlacedAllocs")
+		health.healthy += intField(fields, "HealthyAllocs")
+		health.unhealthy += intField(fields, "UnhealthyAllocs")
+	}
+	return health
+}
+
+func jobKey(namespace, jobID string) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace + "/" + jobID
+}
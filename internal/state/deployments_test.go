@@ -0,0 +1,206 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAggregator(cfg config.DeploymentTrackingConfig) (*DeploymentAggregator, *testClock) {
+	aggregator := NewDeploymentAggregator(cfg)
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	aggregator.now = clock.Now
+	return aggregator, clock
+}
+
+func deploymentEvent(status, description string, healthy int) nomad.Event {
+	return nomad.Event{
+		Topic: "Deployment",
+		Type:  "DeploymentStatusUpdate",
+		Key:   "deploy-1",
+		Index: 200,
+		Payload: map[string]interface{}{
+			"Deployment": map[string]interface{}{
+				"ID":                "deploy-1",
+				"Namespace":         "default",
+				"JobID":             "web",
+				"JobVersion":        float64(42),
+				"Status":            status,
+				"StatusDescription": description,
+				"TaskGroups": map[string]interface{}{
+					"api": map[string]interface{}{
+						"DesiredTotal":    float64(3),
+						"PlacedAllocs":    float64(3),
+						"HealthyAllocs":   float64(healthy),
+						"UnhealthyAllocs": float64(0),
+					},
+					"worker": map[string]interface{}{
+						"DesiredTotal":    float64(2),
+						"PlacedAllocs":    float64(2),
+						"HealthyAllocs":   float64(0),
+						"UnhealthyAllocs": float64(0),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDeploymentAggregatorLifecycle(t *testing.T) {
+	aggregator, clock := newTestAggregator(config.DeploymentTrackingConfig{})
+
+	diff := map[string]interface{}{"Type": "Edited"}
+	assert.Empty(t, aggregator.Observe(nomad.Event{
+		Topic: "Job",
+		Type:  "JobRegistered",
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web", "Namespace": "default", "Version": float64(42)},
+		},
+		Diff: diff,
+	}))
+
+	derived := aggregator.Observe(deploymentEvent("running", "Deployment is running", 0))
+	require.Equal(t, []string{TypeDeploymentStarted}, eventTypes(derived))
+	started := derived[0]
+	assert.Equal(t, nomad.TopicDerived, started.Topic)
+	assert.Equal(t, "deploy-1", started.Key)
+	assert.Equal(t, "default", started.Namespace)
+	assert.Equal(t, "Deployment of job web v42 started (0/5 healthy)", started.Payload.(map[string]interface{})["Message"])
+
+	// Unchanged health is not progress
+	assert.Empty(t, aggregator.Observe(deploymentEvent("running", "Deployment is running", 0)))
+
+	clock.Advance(time.Minute)
+	derived = aggregator.Observe(deploymentEvent("running", "Deployment is running", 3))
+	require.Equal(t, []string{TypeDeploymentProgress}, eventTypes(derived))
+	assert.Equal(t, 3, derived[0].Payload.(map[string]interface{})["Healthy"])
+
+	clock.Advance(3*time.Minute + 12*time.Second)
+	derived = aggregator.Observe(deploymentEvent("successful", "Deployment completed successfully", 3))
+	require.Equal(t, []string{TypeDeploymentSucceeded}, eventTypes(derived))
+
+	payload := derived[0].Payload.(map[string]interface{})
+	assert.Equal(t, "Deployment of job web v42 succeeded in 4m12s (3/5 healthy)", payload["Message"])
+	assert.Equal(t, "4m12s", payload["Duration"])
+	assert.Equal(t, 252, payload["DurationSeconds"])
+	assert.Equal(t, "web", payload["JobID"])
+	assert.Equal(t, 42, payload["JobVersion"])
+	assert.Equal(t, true, payload["Finished"])
+	assert.Equal(t, diff, derived[0].Diff)
+
+	assert.Empty(t, aggregator.deployments)
+	assert.Empty(t, aggregator.jobDiffs, "the diff is dropped once its deployment finishes")
+}
+
+func TestDeploymentAggregatorFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		description string
+		expected    string
+		message     string
+	}{
+		{
+			name:        "failed",
+			status:      "failed",
+			description: "Failed due to progress deadline",
+			expected:    TypeDeploymentFailed,
+			message:     "Deployment of job web v42 failed after 2m0s (1/5 healthy)",
+		},
+		{
+			name:        "rolled back",
+			status:      "failed",
+			description: "Failed due to unhealthy allocations - rolling back to job version 41",
+			expected:    TypeDeploymentRolledBack,
+			message:     "Deployment of job web v42 failed and was rolled back after 2m0s (1/5 healthy)",
+		},
+		{
+			name:        "cancelled",
+			status:      "cancelled",
+			description: "Cancelled because job is stopped",
+			expected:    TypeDeploymentCancelled,
+			message:     "Deployment of job web v42 was cancelled after 2m0s (1/5 healthy)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator, clock := newTestAggregator(config.DeploymentTrackingConfig{})
+
+			aggregator.Observe(deploymentEvent("running", "Deployment is running", 0))
+			clock.Advance(2 * time.Minute)
+
+			derived := aggregator.Observe(deploymentEvent(tt.status, tt.description, 1))
+			require.Equal(t, []string{tt.expected}, eventTypes(derived))
+
+			payload := derived[0].Payload.(map[string]interface{})
+			assert.Equal(t, tt.message, payload["Message"])
+			assert.Equal(t, tt.description, payload["StatusDescription"])
+			assert.Nil(t, derived[0].Diff)
+		})
+	}
+}
+
+func TestDeploymentAggregatorWithoutProgress(t *testing.T) {
+	progress := false
+	aggregator, _ := newTestAggregator(config.DeploymentTrackingConfig{Progress: &progress})
+
+	aggregator.Observe(deploymentEvent("running", "Deployment is running", 0))
+	assert.Empty(t, aggregator.Observe(deploymentEvent("running", "Deployment is running", 2)))
+}
+
+func TestDeploymentAggregatorUsesCreateTime(t *testing.T) {
+	aggregator, clock := newTestAggregator(config.DeploymentTrackingConfig{})
+
+	// Deployment started before the service did
+	event := deploymentEvent("successful", "Deployment completed successfully", 3)
+	event.Payload.(map[string]interface{})["Deployment"].(map[string]interface{})["CreateTime"] = float64(clock.now.Add(-10 * time.Minute).UnixNano())
+
+	derived := aggregator.Observe(event)
+	require.Equal(t, []string{TypeDeploymentSucceeded}, eventTypes(derived))
+	assert.Equal(t, "10m0s", derived[0].Payload.(map[string]interface{})["Duration"])
+}
+
+func TestDeploymentAggregatorIgnoresDiffForOtherVersion(t *testing.T) {
+	aggregator, _ := newTestAggregator(config.DeploymentTrackingConfig{})
+
+	aggregator.Observe(nomad.Event{
+		Topic: "Job",
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "web", "Namespace": "default", "Version": float64(43)},
+		},
+		Diff: map[string]interface{}{"Type": "Edited"},
+	})
+
+	derived := aggregator.Observe(deploymentEvent("running", "Deployment is running", 0))
+	require.Len(t, derived, 1)
+	assert.Nil(t, derived[0].Diff)
+}
+
+func TestDeploymentAggregatorSweep(t *testing.T) {
+	aggregator, clock := newTestAggregator(config.DeploymentTrackingConfig{})
+
+	aggregator.Observe(deploymentEvent("running", "Deployment is running", 0))
+	// A version that never deploys, such as a batch job's
+	aggregator.Observe(nomad.Event{
+		Topic: "Job",
+		Payload: map[string]interface{}{
+			"Job": map[string]interface{}{"ID": "report", "Namespace": "default", "Version": float64(3)},
+		},
+		Diff: map[string]interface{}{"Type": "Edited"},
+	})
+
+	clock.Advance(23 * time.Hour)
+	assert.Empty(t, aggregator.Sweep())
+	assert.Len(t, aggregator.jobDiffs, 1)
+
+	clock.Advance(2 * time.Hour)
+	assert.Empty(t, aggregator.Sweep())
+	assert.Empty(t, aggregator.deployments)
+	assert.Empty(t, aggregator.jobDiffs)
+}
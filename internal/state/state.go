@@ -0,0 +1,48 @@
+package state
+
+import (
+	"nomad-events/internal/nomad"
+)
+
+// Deriver turns the event stream into synthetic events. Observe is called for
+// every event from Nomad and Sweep periodically, for conditions that only
+// become true with the passage of time.
+type Deriver interface {
+	Observe(event nomad.Event) []nomad.Event
+	Sweep() []nomad.Event
+}
+
+// payloadObject returns a named object from the event payload, such as "Allocation"
+func payloadObject(event nomad.Event, name string) (map[string]interface{}, bool) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	object, ok := payload[name].(map[string]interface{})
+	return object, ok
+}
+
+func stringField(object map[string]interface{}, name string) string {
+	s, _ := object[name].(string)
+	return s
+}
+
+func intField(object map[string]interface{}, name string) int {
+	switch v := object[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case uint64:
+		return int(v)
+	}
+	return 0
+}
+
+// shortID returns the first 8 characters of a Nomad ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
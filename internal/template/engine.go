@@ -2,8 +2,13 @@ package template
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"sync"
 	"text/template"
+	"time"
 
+	"nomad-events/internal/cache"
 	"nomad-events/internal/nomad"
 
 	"github.com/Masterminds/sprig/v3"
@@ -13,13 +18,74 @@ import (
 type Engine struct {
 	funcMap     template.FuncMap
 	nomadClient *api.Client
+	nomad       NomadOptions
+	library     *Library
+	templates   sync.Map // template text -> *template.Template, parsed once
+	now         func() time.Time
+}
+
+// Error is a template that failed to parse or render
+type Error struct {
+	Template string // Template text
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NomadOptions configure the Nomad API functions of engines created for a client
+type NomadOptions struct {
+	Cache     *cache.Cache // Shared cache of lookups
+	Namespace string       // Namespace of lookups (default: the client's namespace)
+	UIURL     string       // Base URL of the Nomad UI (default: the client's address)
+}
+
+// Options are kept per client, so every engine, and so every output, shares
+// the same cache of Nomad API lookups
+var (
+	nomadOptionsMu sync.Mutex
+	nomadOptions   = make(map[*api.Client]NomadOptions)
+)
+
+// SetNomadOptions sets the options of engines created for a client. Engines
+// for a client without options share a cache with the default settings.
+func SetNomadOptions(client *api.Client, opts NomadOptions) {
+	nomadOptionsMu.Lock()
+	defer nomadOptionsMu.Unlock()
+
+	if opts.Cache == nil {
+		opts.Cache = cache.New(0, 0)
+	}
+	nomadOptions[client] = opts
+}
+
+func optionsFor(client *api.Client) NomadOptions {
+	nomadOptionsMu.Lock()
+	defer nomadOptionsMu.Unlock()
+
+	opts, ok := nomadOptions[client]
+	if !ok {
+		opts = NomadOptions{Cache: cache.New(0, 0)}
+		nomadOptions[client] = opts
+	}
+	return opts
 }
 
 // NewEngine creates a template engine without Nomad API functions
 func NewEngine() *Engine {
-	return &Engine{
+	e := &Engine{
 		funcMap: sprig.FuncMap(),
+		library: currentLibrary(),
+		now:     time.Now,
 	}
+	e.addHelperFunctions()
+
+	return e
 }
 
 // NewEngineWithNomad creates a template engine with Nomad API functions
@@ -27,7 +93,13 @@ func NewEngineWithNomad(nomadClient *api.Client) *Engine {
 	e := &Engine{
 		funcMap:     sprig.FuncMap(),
 		nomadClient: nomadClient,
+		library:     currentLibrary(),
+		now:         time.Now,
 	}
+	if nomadClient != nil {
+		e.nomad = optionsFor(nomadClient)
+	}
+	e.addHelperFunctions()
 
 	// Add our custom Nomad API functions
 	e.addNomadFunctions()
@@ -56,6 +128,30 @@ func (e *Engine) addNomadFunctions() {
 
 	// deploymentAllocs: get allocations for deployment
 	e.funcMap["deploymentAllocs"] = e.deploymentAllocsFunc
+
+	// deployment: retrieve a deployment by ID
+	e.funcMap["deployment"] = e.deploymentFunc
+
+	// jobVersions: get every version of a job, newest first
+	e.funcMap["jobVersions"] = e.jobVersionsFunc
+
+	// node: retrieve a node by ID
+	e.funcMap["node"] = e.nodeFunc
+
+	// allocation: retrieve an allocation by ID
+	e.funcMap["allocation"] = e.allocationFunc
+
+	// allocLogs: get the last lines of a task's stderr
+	e.funcMap["allocLogs"] = e.allocLogsFunc
+
+	// service: get the registrations of a Nomad service
+	e.funcMap["service"] = e.serviceFunc
+
+	// variable: get the items of a Nomad variable
+	e.funcMap["variable"] = e.variableFunc
+
+	// namespace: retrieve a namespace by name
+	e.funcMap["namespace"] = e.namespaceFunc
 }
 
 func (e *Engine) ProcessText(text string, event nomad.Event) (string, error) {
@@ -71,15 +167,70 @@ func (e *Engine) CreateTemplateData(event nomad.Event) map[string]interface{} {
 	return e.createTemplateData(event)
 }
 
+// CreateBatchTemplateData builds template data for a group of events, exposing
+// .Events (per-event template data) and .GroupLabels
+func (e *Engine) CreateBatchTemplateData(events []nomad.Event, groupLabels map[string]string) map[string]interface{} {
+	eventsData := make([]interface{}, len(events))
+	for i, event := range events {
+		eventsData[i] = e.createTemplateData(event)
+	}
+
+	if groupLabels == nil {
+		groupLabels = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"Events":      eventsData,
+		"GroupLabels": groupLabels,
+		"Count":       len(events),
+	}
+}
+
+// Compile parses a template so later renders of it do not have to. Outputs
+// compile their templates when they are created, so syntax errors fail
+// configuration loading rather than deliveries.
+func (e *Engine) Compile(text string) error {
+	_, err := e.parse(text)
+	return err
+}
+
+func (e *Engine) parse(text string) (*template.Template, error) {
+	if tmpl, ok := e.templates.Load(text); ok {
+		return tmpl.(*template.Template), nil
+	}
+
+	// Each template gets its own copy of the library, so templates that
+	// {{ define }} names of their own cannot affect each other
+	tmpl := template.New("template")
+	if e.library != nil {
+		base, err := e.library.tmpl.Clone()
+		if err != nil {
+			return nil, &Error{Template: text, Err: err}
+		}
+		tmpl = base.New("template")
+	}
+
+	tmpl, err := tmpl.Funcs(e.funcMap).Parse(text)
+	if err != nil {
+		return nil, &Error{Template: text, Err: err}
+	}
+	if err := checkReferences(tmpl); err != nil {
+		return nil, &Error{Template: text, Err: err}
+	}
+
+	e.templates.Store(text, tmpl)
+	return tmpl, nil
+}
+
 func (e *Engine) processText(text string, eventData map[string]interface{}) (string, error) {
-	tmpl, err := template.New("template").Funcs(e.funcMap).Parse(text)
+	tmpl, err := e.parse(text)
 	if err != nil {
-		return text, nil // Return original text on parse error
+		return "", err
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, eventData); err != nil {
-		return text, nil // Return original text on execution error
+		return "", &Error{Template: text, Err: err}
 	}
 
 	return buf.String(), nil
@@ -100,18 +251,57 @@ func (e *Engine) createTemplateData(event nomad.Event) map[string]interface{} {
 
 	if event.Diff != nil {
 		data["Diff"] = event.Diff
+		data["DiffSummary"] = nomad.DiffSummary(event.Diff)
+	}
+
+	if event.Status != "" {
+		data["Status"] = event.Status
+		data["Fingerprint"] = event.Fingerprint
+	}
+
+	if event.Enriched != nil {
+		data["Enriched"] = event.Enriched
+	}
+
+	if event.Logs != nil {
+		data["Logs"] = event.Logs
 	}
 
 	return data
 }
 
+// lookup runs a Nomad API call through the shared cache, with its timeout,
+// in the configured namespace
+func (e *Engine) lookup(key string, tags []string, fetch func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error)) (interface{}, error) {
+	return e.nomad.Cache.Get(context.Background(), key, tags, func(ctx context.Context) (interface{}, uint64, error) {
+		value, meta, err := fetch((&api.QueryOptions{Namespace: e.nomad.Namespace}).WithContext(ctx))
+		var index uint64
+		if meta != nil {
+			index = meta.LastIndex
+		}
+		return value, index, err
+	})
+}
+
+// jobKey is the cache key of a lookup about a job in the configured namespace
+func (e *Engine) jobKey(kind, jobID string) string {
+	namespace := e.nomad.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return kind + "/" + namespace + "/" + jobID
+}
+
 // jobFunc retrieves a Nomad job by job ID
 func (e *Engine) jobFunc(jobID string) (*api.Job, error) {
 	if e.nomadClient == nil {
 		return nil, nil
 	}
 
-	job, _, err := e.nomadClient.Jobs().Info(jobID, nil)
+	value, err := e.lookup(e.jobKey("job", jobID), []string{cache.JobTag(e.nomad.Namespace, jobID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Jobs().Info(jobID, q)
+	})
+	job, _ := value.(*api.Job)
 	return job, err
 }
 
@@ -121,7 +311,10 @@ func (e *Engine) jobAllocsFunc(jobID string) ([]*api.AllocationListStub, error)
 		return nil, nil
 	}
 
-	allocs, _, err := e.nomadClient.Jobs().Allocations(jobID, true, nil)
+	value, err := e.lookup(e.jobKey("jobAllocs", jobID), []string{cache.JobTag(e.nomad.Namespace, jobID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Jobs().Allocations(jobID, true, q)
+	})
+	allocs, _ := value.([]*api.AllocationListStub)
 	return allocs, err
 }
 
@@ -131,7 +324,10 @@ func (e *Engine) jobEvaluationsFunc(jobID string) ([]*api.Evaluation, error) {
 		return nil, nil
 	}
 
-	evals, _, err := e.nomadClient.Jobs().Evaluations(jobID, nil)
+	value, err := e.lookup(e.jobKey("jobEvaluations", jobID), []string{cache.JobTag(e.nomad.Namespace, jobID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Jobs().Evaluations(jobID, q)
+	})
+	evals, _ := value.([]*api.Evaluation)
 	return evals, err
 }
 
@@ -141,7 +337,10 @@ func (e *Engine) jobSummaryFunc(jobID string) (*api.JobSummary, error) {
 		return nil, nil
 	}
 
-	summary, _, err := e.nomadClient.Jobs().Summary(jobID, nil)
+	value, err := e.lookup(e.jobKey("jobSummary", jobID), []string{cache.JobTag(e.nomad.Namespace, jobID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Jobs().Summary(jobID, q)
+	})
+	summary, _ := value.(*api.JobSummary)
 	return summary, err
 }
 
@@ -151,7 +350,10 @@ func (e *Engine) evaluationFunc(evalID string) (*api.Evaluation, error) {
 		return nil, nil
 	}
 
-	eval, _, err := e.nomadClient.Evaluations().Info(evalID, nil)
+	value, err := e.lookup("evaluation/"+evalID, []string{cache.EvaluationTag(evalID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Evaluations().Info(evalID, q)
+	})
+	eval, _ := value.(*api.Evaluation)
 	return eval, err
 }
 
@@ -161,7 +363,10 @@ func (e *Engine) evaluationAllocsFunc(evalID string) ([]*api.AllocationListStub,
 		return nil, nil
 	}
 
-	allocs, _, err := e.nomadClient.Evaluations().Allocations(evalID, nil)
+	value, err := e.lookup("evaluationAllocs/"+evalID, []string{cache.EvaluationTag(evalID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Evaluations().Allocations(evalID, q)
+	})
+	allocs, _ := value.([]*api.AllocationListStub)
 	return allocs, err
 }
 
@@ -171,6 +376,128 @@ func (e *Engine) deploymentAllocsFunc(deploymentID string) ([]*api.AllocationLis
 		return nil, nil
 	}
 
-	allocs, _, err := e.nomadClient.Deployments().Allocations(deploymentID, nil)
+	value, err := e.lookup("deploymentAllocs/"+deploymentID, []string{cache.DeploymentTag(deploymentID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Deployments().Allocations(deploymentID, q)
+	})
+	allocs, _ := value.([]*api.AllocationListStub)
 	return allocs, err
 }
+
+// deploymentFunc retrieves a deployment by ID
+func (e *Engine) deploymentFunc(deploymentID string) (*api.Deployment, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("deployment/"+deploymentID, []string{cache.DeploymentTag(deploymentID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Deployments().Info(deploymentID, q)
+	})
+	deployment, _ := value.(*api.Deployment)
+	return deployment, err
+}
+
+// jobVersionsFunc gets every version of a job, newest first
+func (e *Engine) jobVersionsFunc(jobID string) ([]*api.Job, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup(e.jobKey("jobVersions", jobID), []string{cache.JobTag(e.nomad.Namespace, jobID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		versions, _, meta, err := e.nomadClient.Jobs().Versions(jobID, false, q)
+		return versions, meta, err
+	})
+	versions, _ := value.([]*api.Job)
+	return versions, err
+}
+
+// nodeFunc retrieves a node by ID
+func (e *Engine) nodeFunc(nodeID string) (*api.Node, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("node/"+nodeID, []string{cache.NodeTag(nodeID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Nodes().Info(nodeID, q)
+	})
+	node, _ := value.(*api.Node)
+	return node, err
+}
+
+// allocationFunc retrieves an allocation by ID
+func (e *Engine) allocationFunc(allocID string) (*api.Allocation, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("allocation/"+allocID, []string{cache.AllocationTag(allocID)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Allocations().Info(allocID, q)
+	})
+	alloc, _ := value.(*api.Allocation)
+	return alloc, err
+}
+
+// allocLogsMaxBytes bounds how much of a log allocLogs reads
+const allocLogsMaxBytes = 64 * 1024
+
+// allocLogsFunc gets the last lines of a task's stderr
+func (e *Engine) allocLogsFunc(allocID, task string, lines int) (string, error) {
+	if e.nomadClient == nil {
+		return "", nil
+	}
+
+	alloc, err := e.allocationFunc(allocID)
+	if err != nil || alloc == nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("allocLogs/%s/%s/%d", allocID, task, lines)
+	value, err := e.nomad.Cache.Get(context.Background(), key, []string{cache.AllocationTag(allocID)}, func(ctx context.Context) (interface{}, uint64, error) {
+		logs, err := nomad.TailLogs(ctx, e.nomadClient, alloc, task, "stderr", lines, allocLogsMaxBytes)
+		return logs, 0, err
+	})
+	logs, _ := value.(string)
+	return logs, err
+}
+
+// serviceFunc gets the registrations of a Nomad service
+func (e *Engine) serviceFunc(name string) ([]*api.ServiceRegistration, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("service/"+name, []string{cache.ServiceTag(name)}, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Services().Get(name, q)
+	})
+	registrations, _ := value.([]*api.ServiceRegistration)
+	return registrations, err
+}
+
+// variableFunc gets the items of a Nomad variable
+func (e *Engine) variableFunc(path string) (map[string]string, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("variable/"+path, nil, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		variable, meta, err := e.nomadClient.Variables().Read(path, q)
+		if err != nil {
+			return nil, meta, err
+		}
+		return map[string]string(variable.Items), meta, nil
+	})
+	items, _ := value.(map[string]string)
+	return items, err
+}
+
+// namespaceFunc retrieves a namespace by name
+func (e *Engine) namespaceFunc(name string) (*api.Namespace, error) {
+	if e.nomadClient == nil {
+		return nil, nil
+	}
+
+	value, err := e.lookup("namespace/"+name, nil, func(q *api.QueryOptions) (interface{}, *api.QueryMeta, error) {
+		return e.nomadClient.Namespaces().Info(name, q)
+	})
+	namespace, _ := value.(*api.Namespace)
+	return namespace, err
+}
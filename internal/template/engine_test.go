@@ -1,8 +1,13 @@
 package template
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"nomad-events/internal/cache"
 	"nomad-events/internal/nomad"
 
 	"github.com/hashicorp/nomad/api"
@@ -77,6 +82,63 @@ func TestEngineProcessText(t *testing.T) {
 	}
 }
 
+func TestEngineProcessTextErrors(t *testing.T) {
+	engine := NewEngine()
+	event := nomad.Event{Topic: "Job", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "web"}}}
+
+	tests := []struct {
+		name     string
+		template string
+		errorMsg string
+	}{
+		{
+			name:     "parse error",
+			template: "{{ .Payload.Job.ID }",
+			errorMsg: "unexpected \"}\"",
+		},
+		{
+			name:     "undefined function",
+			template: "{{ jobb .Key }}",
+			errorMsg: "function \"jobb\" not defined",
+		},
+		{
+			name:     "execution error",
+			template: "{{ index .Payload.Job.Tags 0 }}",
+			errorMsg: "error calling index",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.ProcessText(tt.template, event)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+			assert.Empty(t, result)
+
+			var tmplErr *Error
+			require.ErrorAs(t, err, &tmplErr)
+			assert.Equal(t, tt.template, tmplErr.Template)
+		})
+	}
+}
+
+func TestEngineCompile(t *testing.T) {
+	engine := NewEngine()
+
+	require.NoError(t, engine.Compile("{{ .Topic }}"))
+	_, ok := engine.templates.Load("{{ .Topic }}")
+	assert.True(t, ok)
+
+	// Failed parses are not cached
+	assert.Error(t, engine.Compile("{{ .Topic"))
+	_, ok = engine.templates.Load("{{ .Topic")
+	assert.False(t, ok)
+
+	result, err := engine.ProcessText("{{ .Topic }}", nomad.Event{Topic: "Node"})
+	require.NoError(t, err)
+	assert.Equal(t, "Node", result)
+}
+
 func TestEngineCreateTemplateData(t *testing.T) {
 	engine := NewEngine()
 
@@ -105,6 +167,37 @@ func TestEngineCreateTemplateData(t *testing.T) {
 	payload := data["Payload"].(map[string]interface{})
 	job := payload["Job"].(map[string]interface{})
 	assert.Equal(t, "example-job", job["ID"])
+	assert.NotContains(t, data, "Status")
+	assert.NotContains(t, data, "DiffSummary")
+
+	event.Diff = map[string]interface{}{"Type": "Edited", "TaskGroups": []interface{}{
+		map[string]interface{}{"Type": "Edited", "Name": "web", "Fields": []interface{}{
+			map[string]interface{}{"Type": "Edited", "Name": "Count", "Old": "3", "New": "5"},
+		}},
+	}}
+	data = engine.CreateTemplateData(event)
+	assert.Equal(t, "count 3→5", data["DiffSummary"])
+	event.Diff = nil
+
+	event.Status = "firing"
+	event.Fingerprint = "abc123"
+	data = engine.CreateTemplateData(event)
+	assert.Equal(t, "firing", data["Status"])
+	assert.Equal(t, "abc123", data["Fingerprint"])
+	assert.NotContains(t, data, "Enriched")
+
+	event.Enriched = map[string]interface{}{"Node": map[string]interface{}{"Name": "client-1"}}
+	data = engine.CreateTemplateData(event)
+	assert.Equal(t, event.Enriched, data["Enriched"])
+	assert.NotContains(t, data, "Logs")
+
+	event.Logs = map[string]nomad.TaskLogs{"server": {Stderr: "panic: boom"}}
+	data = engine.CreateTemplateData(event)
+	assert.Equal(t, event.Logs, data["Logs"])
+
+	result, err := engine.ProcessText(`{{ range $task, $logs := .Logs }}{{ $task }}: {{ $logs.Stderr }}{{ end }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, "server: panic: boom", result)
 }
 
 func TestEngineNomadAPIFunctions(t *testing.T) {
@@ -139,5 +232,151 @@ func TestEngineNomadAPIFunctions(t *testing.T) {
 		deployAllocs, err := engine.deploymentAllocsFunc("test-deployment")
 		assert.NoError(t, err)
 		assert.Nil(t, deployAllocs)
+
+		node, err := engine.nodeFunc("test-node")
+		assert.NoError(t, err)
+		assert.Nil(t, node)
+
+		logs, err := engine.allocLogsFunc("test-alloc", "server", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, logs)
+
+		items, err := engine.variableFunc("nomad/jobs/test")
+		assert.NoError(t, err)
+		assert.Nil(t, items)
 	})
 }
+
+func TestEngineNomadLookupFunctions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		switch r.URL.Path {
+		case "/v1/node/node-1":
+			w.Write([]byte(`{"ID": "node-1", "Name": "client-1", "Status": "ready"}`))
+		case "/v1/allocation/alloc-1":
+			assert.Equal(t, "prod", namespace)
+			w.Write([]byte(`{"ID": "alloc-1", "Namespace": "prod", "ClientStatus": "failed"}`))
+		case "/v1/client/fs/logs/alloc-1":
+			assert.Equal(t, "server", r.URL.Query().Get("task"))
+			assert.Equal(t, "stderr", r.URL.Query().Get("type"))
+			assert.Equal(t, "end", r.URL.Query().Get("origin"))
+			// "line 1\nline 2\npanic: boom\n"
+			w.Write([]byte(`{"Data": "bGluZSAxCmxpbmUgMgpwYW5pYzogYm9vbQo="}`))
+		case "/v1/deployment/deploy-1":
+			w.Write([]byte(`{"ID": "deploy-1", "Status": "running"}`))
+		case "/v1/job/web/versions":
+			assert.Equal(t, "prod", namespace)
+			w.Write([]byte(`{"Versions": [{"ID": "web", "Version": 2}, {"ID": "web", "Version": 1}]}`))
+		case "/v1/service/web":
+			assert.Equal(t, "prod", namespace)
+			w.Write([]byte(`[{"ServiceName": "web", "Address": "10.0.0.1", "Port": 8080}]`))
+		case "/v1/var/nomad/jobs/web":
+			assert.Equal(t, "prod", namespace)
+			w.Write([]byte(`{"Path": "nomad/jobs/web", "Items": {"owner": "payments"}}`))
+		case "/v1/namespace/prod":
+			w.Write([]byte(`{"Name": "prod", "Description": "Production"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.Address = server.URL
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+	SetNomadOptions(client, NomadOptions{Namespace: "prod"})
+
+	engine := NewEngineWithNomad(client)
+	event := nomad.Event{Topic: "Allocation", Type: "AllocationUpdated"}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "node", template: `{{ (node "node-1").Name }}`, expected: "client-1"},
+		{name: "allocation", template: `{{ (allocation "alloc-1").ClientStatus }}`, expected: "failed"},
+		{name: "allocLogs", template: `{{ allocLogs "alloc-1" "server" 2 }}`, expected: "line 2\npanic: boom"},
+		{name: "deployment", template: `{{ (deployment "deploy-1").Status }}`, expected: "running"},
+		{name: "jobVersions", template: `{{ len (jobVersions "web") }}`, expected: "2"},
+		{name: "service", template: `{{ range service "web" }}{{ .Address }}:{{ .Port }}{{ end }}`, expected: "10.0.0.1:8080"},
+		{name: "variable", template: `{{ (variable "nomad/jobs/web").owner }}`, expected: "payments"},
+		{name: "namespace", template: `{{ (namespace "prod").Description }}`, expected: "Production"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.ProcessText(tt.template, event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestEngineNomadAPIFunctionsCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Nomad-Index", "10")
+		w.Write([]byte(`{"ID": "web", "Name": "web", "Version": 3}`))
+	}))
+	defer server.Close()
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.Address = server.URL
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+
+	apiCache := cache.New(time.Minute, time.Second)
+	SetNomadOptions(client, NomadOptions{Cache: apiCache})
+
+	// Engines for the same client share lookups
+	first := NewEngineWithNomad(client)
+	second := NewEngineWithNomad(client)
+	event := nomad.Event{Topic: "Job", Type: "JobRegistered"}
+
+	for _, engine := range []*Engine{first, second} {
+		result, err := engine.ProcessText(`{{ (job "web").Version }}`, event)
+		require.NoError(t, err)
+		assert.Equal(t, "3", result)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// A job with the same ID in another namespace is a different job
+	apiCache.Observe(nomad.Event{Topic: "Job", Index: 11, Payload: map[string]interface{}{
+		"Job": map[string]interface{}{"ID": "web", "Namespace": "batch"},
+	}})
+	_, err = first.ProcessText(`{{ (job "web").Version }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// A newer change to the job drops the cached lookup
+	apiCache.Observe(nomad.Event{Topic: "Job", Index: 11, Payload: map[string]interface{}{
+		"Job": map[string]interface{}{"ID": "web"},
+	}})
+	_, err = first.ProcessText(`{{ (job "web").Version }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	stats := apiCache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+}
+
+func TestEngineCreateBatchTemplateData(t *testing.T) {
+	engine := NewEngine()
+
+	events := []nomad.Event{
+		{Topic: "Job", Type: "JobRegistered", Key: "web"},
+		{Topic: "Job", Type: "JobRegistered", Key: "api"},
+	}
+
+	data := engine.CreateBatchTemplateData(events, map[string]string{"Topic": "Job"})
+	assert.Equal(t, 2, data["Count"])
+	assert.Len(t, data["Events"], 2)
+
+	result, err := engine.ProcessTextWithData(`{{ .GroupLabels.Topic }}:{{ range .Events }} {{ .Key }}{{ end }}`, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Job: web api", result)
+}
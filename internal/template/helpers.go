@@ -0,0 +1,291 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// TaskState is a task of an allocation, flattened by taskStates
+type TaskState struct {
+	Task          string
+	State         string
+	Failed        bool
+	Restarts      uint64
+	LastEvent     string // Display message of the latest event
+	LastEventType string
+	LastEventTime int64 // Nanoseconds since the epoch
+	Events        []TaskEvent
+}
+
+// TaskEvent is an event of a task, oldest first
+type TaskEvent struct {
+	Type    string
+	Message string
+	Time    int64 // Nanoseconds since the epoch
+}
+
+// statusEmojis maps Nomad statuses, and alert statuses, to an emoji
+var statusEmojis = map[string]string{
+	"running":      "🟢",
+	"ready":        "🟢",
+	"healthy":      "🟢",
+	"complete":     "✅",
+	"successful":   "✅",
+	"resolved":     "✅",
+	"pending":      "⏳",
+	"initializing": "⏳",
+	"failed":       "❌",
+	"unhealthy":    "❌",
+	"down":         "🔴",
+	"firing":       "🔥",
+	"lost":         "⚠️",
+	"unknown":      "❔",
+	"draining":     "🚧",
+	"ineligible":   "🚧",
+	"blocked":      "⛔",
+	"paused":       "⏸️",
+	"cancelled":    "🚫",
+	"canceled":     "🚫",
+	"dead":         "⚫",
+	"stopped":      "⚫",
+}
+
+func (e *Engine) addHelperFunctions() {
+	// nomadUIURL: link to a job, allocation, node, evaluation or variable in the Nomad UI
+	e.funcMap["nomadUIURL"] = e.nomadUIURLFunc
+
+	// shortID: the 8 character prefix Nomad shows for IDs
+	e.funcMap["shortID"] = shortIDFunc
+
+	// humanizeNanos: how long ago a nanosecond timestamp was, such as "5m ago"
+	e.funcMap["humanizeNanos"] = e.humanizeNanosFunc
+
+	// taskStates: the tasks of an allocation with their latest event, sorted by name
+	e.funcMap["taskStates"] = taskStatesFunc
+
+	// failedTasks: the failed tasks of an allocation
+	e.funcMap["failedTasks"] = failedTasksFunc
+
+	// statusEmoji: an emoji for a Nomad status such as "running" or "failed"
+	e.funcMap["statusEmoji"] = statusEmojiFunc
+}
+
+// nomadUIURLFunc builds a link to an object in the Nomad UI. Jobs and
+// variables are namespaced; the namespace defaults to the one lookups use.
+func (e *Engine) nomadUIURLFunc(kind, id string, namespace ...string) (string, error) {
+	base := e.nomad.UIURL
+	if base == "" && e.nomadClient != nil {
+		base = e.nomadClient.Address()
+	}
+	if base == "" {
+		return "", fmt.Errorf("nomadUIURL: no Nomad UI URL is configured")
+	}
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "/"), "/ui")
+
+	ns := e.nomad.Namespace
+	if len(namespace) > 0 && namespace[0] != "" {
+		ns = namespace[0]
+	}
+	if ns == "" {
+		ns = "default"
+	}
+
+	switch kind {
+	case "job":
+		return fmt.Sprintf("%s/ui/jobs/%s@%s", base, url.PathEscape(id), url.PathEscape(ns)), nil
+	case "allocation", "alloc":
+		return fmt.Sprintf("%s/ui/allocations/%s", base, url.PathEscape(id)), nil
+	case "node", "client":
+		return fmt.Sprintf("%s/ui/clients/%s", base, url.PathEscape(id)), nil
+	case "evaluation", "eval":
+		return fmt.Sprintf("%s/ui/evaluations?currentEval=%s", base, url.QueryEscape(id)), nil
+	case "variable":
+		return fmt.Sprintf("%s/ui/variables/var/%s@%s", base, id, url.PathEscape(ns)), nil
+	default:
+		return "", fmt.Errorf("nomadUIURL: unknown kind %q - use job, allocation, node, evaluation or variable", kind)
+	}
+}
+
+// shortIDFunc returns the first 8 characters of an ID
+func shortIDFunc(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// humanizeNanosFunc describes a nanosecond timestamp relative to now, such as
+// "5m ago" or "in 2h". Zero timestamps, which Nomad uses for unset times, are empty.
+func (e *Engine) humanizeNanosFunc(value interface{}) (string, error) {
+	nanos, err := toNanos(value)
+	if err != nil {
+		return "", fmt.Errorf("humanizeNanos: %w", err)
+	}
+	if nanos == 0 {
+		return "", nil
+	}
+
+	d := e.now().Sub(time.Unix(0, nanos))
+	if d < 0 {
+		return "in " + humanizeDuration(-d), nil
+	}
+	if d < time.Second {
+		return "just now", nil
+	}
+	return humanizeDuration(d) + " ago", nil
+}
+
+// humanizeDuration formats a duration in its largest whole unit
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// toNanos converts the number types timestamps have in event payloads and API structs
+func toNanos(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid timestamp %v", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("unsupported timestamp type %T", value)
+	}
+}
+
+// taskStatesFunc flattens the task states of an allocation, given either the
+// allocation (an event payload's Allocation, or the allocation function's
+// result) or its TaskStates
+func taskStatesFunc(value interface{}) ([]TaskState, error) {
+	tasks, err := flattenTaskStates(value)
+	if err != nil {
+		return nil, fmt.Errorf("taskStates: %w", err)
+	}
+	return tasks, nil
+}
+
+// failedTasksFunc returns the failed tasks of an allocation, as taskStates does
+func failedTasksFunc(value interface{}) ([]TaskState, error) {
+	tasks, err := flattenTaskStates(value)
+	if err != nil {
+		return nil, fmt.Errorf("failedTasks: %w", err)
+	}
+
+	var failed []TaskState
+	for _, task := range tasks {
+		if task.Failed {
+			failed = append(failed, task)
+		}
+	}
+	return failed, nil
+}
+
+func flattenTaskStates(value interface{}) ([]TaskState, error) {
+	states, err := decodeTaskStates(value)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]TaskState, 0, len(states))
+	for name, state := range states {
+		if state == nil {
+			continue
+		}
+
+		task := TaskState{
+			Task:     name,
+			State:    state.State,
+			Failed:   state.Failed,
+			Restarts: state.Restarts,
+		}
+		for _, event := range state.Events {
+			if event == nil {
+				continue
+			}
+			message := event.DisplayMessage
+			if message == "" {
+				message = event.Message
+			}
+			task.Events = append(task.Events, TaskEvent{Type: event.Type, Message: message, Time: event.Time})
+		}
+		if n := len(task.Events); n > 0 {
+			last := task.Events[n-1]
+			task.LastEvent, task.LastEventType, task.LastEventTime = last.Message, last.Type, last.Time
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Task < tasks[j].Task })
+	return tasks, nil
+}
+
+// decodeTaskStates reads task states from payload maps and API structs alike
+// by way of their JSON encoding, which is the same for both
+func decodeTaskStates(value interface{}) (map[string]*api.TaskState, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *api.Allocation:
+		if v == nil {
+			return nil, nil
+		}
+		return v.TaskStates, nil
+	case map[string]*api.TaskState:
+		return v, nil
+	case map[string]interface{}:
+		// Allocations that have not started a task yet have no TaskStates
+		if states, ok := v["TaskStates"]; ok {
+			value = states
+		} else if _, ok := v["ID"]; ok {
+			return nil, nil
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var states map[string]*api.TaskState
+	if err := json.Unmarshal(encoded, &states); err != nil {
+		return nil, fmt.Errorf("expected an allocation or its task states: %w", err)
+	}
+	return states, nil
+}
+
+// statusEmojiFunc returns an emoji for a status, or ❔ for unknown statuses
+func statusEmojiFunc(status string) string {
+	if emoji, ok := statusEmojis[strings.ToLower(status)]; ok {
+		return emoji
+	}
+	return "❔"
+}
@@ -0,0 +1,197 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNomadUIURL(t *testing.T) {
+	client, err := api.NewClient(&api.Config{Address: "http://nomad.internal:4646"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		options  NomadOptions
+		client   *api.Client
+		template string
+		expected string
+		errorMsg string
+	}{
+		{
+			name:     "job from the client address",
+			client:   client,
+			template: `{{ nomadUIURL "job" "web api" }}`,
+			expected: "http://nomad.internal:4646/ui/jobs/web%20api@default",
+		},
+		{
+			name:     "job in the configured namespace",
+			options:  NomadOptions{UIURL: "https://nomad.example.com/ui/", Namespace: "platform"},
+			template: `{{ nomadUIURL "job" "web" }}`,
+			expected: "https://nomad.example.com/ui/jobs/web@platform",
+		},
+		{
+			name:     "job in the event namespace",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "job" "web" "batch" }}`,
+			expected: "https://nomad.example.com/ui/jobs/web@batch",
+		},
+		{
+			name:     "allocation",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "allocation" "0a1b2c3d-4e5f" }}`,
+			expected: "https://nomad.example.com/ui/allocations/0a1b2c3d-4e5f",
+		},
+		{
+			name:     "node",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "node" "node-1" }}`,
+			expected: "https://nomad.example.com/ui/clients/node-1",
+		},
+		{
+			name:     "evaluation",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "evaluation" "eval-1" }}`,
+			expected: "https://nomad.example.com/ui/evaluations?currentEval=eval-1",
+		},
+		{
+			name:     "variable",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "variable" "nomad/jobs/web" }}`,
+			expected: "https://nomad.example.com/ui/variables/var/nomad/jobs/web@default",
+		},
+		{
+			name:     "unknown kind",
+			options:  NomadOptions{UIURL: "https://nomad.example.com"},
+			template: `{{ nomadUIURL "volume" "data" }}`,
+			errorMsg: `unknown kind "volume"`,
+		},
+		{
+			name:     "no address",
+			template: `{{ nomadUIURL "job" "web" }}`,
+			errorMsg: "no Nomad UI URL is configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine()
+			if tt.client != nil {
+				engine = NewEngineWithNomad(tt.client)
+			}
+			engine.nomad.UIURL = tt.options.UIURL
+			engine.nomad.Namespace = tt.options.Namespace
+
+			text, err := engine.ProcessText(tt.template, nomad.Event{})
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+}
+
+func TestShortIDAndStatusEmoji(t *testing.T) {
+	engine := NewEngine()
+
+	text, err := engine.ProcessText(`{{ shortID "0a1b2c3d-4e5f-6789" }} {{ shortID "abc" }}`, nomad.Event{})
+	require.NoError(t, err)
+	assert.Equal(t, "0a1b2c3d abc", text)
+
+	text, err = engine.ProcessText(`{{ statusEmoji "running" }}{{ statusEmoji "Failed" }}{{ statusEmoji "firing" }}{{ statusEmoji "sideways" }}`, nomad.Event{})
+	require.NoError(t, err)
+	assert.Equal(t, "🟢❌🔥❔", text)
+}
+
+func TestHumanizeNanos(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	engine := NewEngine()
+	engine.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+		errorMsg string
+	}{
+		{name: "payload float", value: float64(now.Add(-90 * time.Second).UnixNano()), expected: "1m ago"},
+		{name: "api int64", value: now.Add(-3 * time.Hour).UnixNano(), expected: "3h ago"},
+		{name: "days", value: now.Add(-50 * time.Hour).UnixNano(), expected: "2d ago"},
+		{name: "seconds", value: now.Add(-5 * time.Second).UnixNano(), expected: "5s ago"},
+		{name: "just now", value: now.UnixNano(), expected: "just now"},
+		{name: "future", value: now.Add(10 * time.Minute).UnixNano(), expected: "in 10m"},
+		{name: "unset", value: 0, expected: ""},
+		{name: "invalid", value: true, errorMsg: "unsupported timestamp type bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := engine.ProcessTextWithData(`{{ humanizeNanos .Time }}`, map[string]interface{}{"Time": tt.value})
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+}
+
+func TestTaskStates(t *testing.T) {
+	engine := NewEngine()
+
+	// Event payloads decode task states as maps with float64 numbers
+	event := nomad.Event{
+		Topic: "Allocation",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID": "alloc-1",
+				"TaskStates": map[string]interface{}{
+					"web": map[string]interface{}{
+						"State":    "dead",
+						"Failed":   true,
+						"Restarts": float64(2),
+						"Events": []interface{}{
+							map[string]interface{}{"Type": "Started", "Time": float64(100), "DisplayMessage": "Task started by client"},
+							map[string]interface{}{"Type": "Terminated", "Time": float64(200), "DisplayMessage": "Exit Code: 1"},
+						},
+					},
+					"sidecar": map[string]interface{}{"State": "running"},
+				},
+			},
+		},
+	}
+
+	text, err := engine.ProcessText(`{{ range taskStates .Payload.Allocation }}{{ .Task }}={{ .State }}/{{ .Restarts }}/{{ .LastEvent }};{{ end }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, "sidecar=running/0/;web=dead/2/Exit Code: 1;", text)
+
+	text, err = engine.ProcessText(`{{ range failedTasks .Payload.Allocation }}{{ .Task }} {{ .LastEventType }} at {{ .LastEventTime }} after {{ len .Events }} events{{ end }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, "web Terminated at 200 after 2 events", text)
+
+	// Allocations from the API, and allocations without task states
+	tasks, err := taskStatesFunc(&api.Allocation{TaskStates: map[string]*api.TaskState{
+		"db": {State: "pending", Events: []*api.TaskEvent{{Type: "Received", Message: "received"}}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "received", tasks[0].LastEvent)
+
+	tasks, err = taskStatesFunc(map[string]interface{}{"ID": "alloc-2"})
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	_, err = taskStatesFunc("web")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected an allocation or its task states")
+}
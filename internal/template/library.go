@@ -0,0 +1,200 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateFileExt is the extension of files loaded from a template directory
+const TemplateFileExt = ".tmpl"
+
+// Library holds named templates that every engine's templates can call with
+// {{ template "name" . }}
+type Library struct {
+	tmpl *template.Template
+}
+
+// The library engines are created with. Engines keep the library they were
+// created with, so replacing it only affects outputs created afterwards.
+var (
+	libraryMu sync.Mutex
+	library   *Library
+)
+
+// SetLibrary sets the library of engines created from now on, returning the
+// previous one so a failed reload can restore it
+func SetLibrary(lib *Library) *Library {
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+
+	previous := library
+	library = lib
+	return previous
+}
+
+func currentLibrary() *Library {
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	return library
+}
+
+// NewLibrary parses named templates from a map of names to template text and
+// from the .tmpl files in dir, each named after its file without the
+// extension. Files may also {{ define }} further templates. Either may be
+// empty; a library without templates is nil.
+func NewLibrary(templates map[string]string, dir string) (*Library, error) {
+	files, err := templateFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 && len(files) == 0 {
+		return nil, nil
+	}
+
+	// Parse with every function an engine can have; engines bind their own
+	// Nomad functions when they clone the library
+	root := template.New("").Funcs(NewEngineWithNomad(nil).funcMap)
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := root.New(name).Parse(templates[name]); err != nil {
+			return nil, fmt.Errorf("templates.%s: %w", name, err)
+		}
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), TemplateFileExt)
+		if _, ok := templates[name]; ok {
+			return nil, fmt.Errorf("%s: template %q is also defined in the templates section", file, name)
+		}
+
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		if _, err := root.New(name).Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	// Templates may call each other, so references are checked once all are parsed
+	for _, tmpl := range root.Templates() {
+		if err := checkReferences(tmpl); err != nil {
+			return nil, fmt.Errorf("template %q: %w", tmpl.Name(), err)
+		}
+	}
+
+	return &Library{tmpl: root}, nil
+}
+
+// Names returns the names of the library's templates
+func (l *Library) Names() []string {
+	if l == nil {
+		return nil
+	}
+
+	var names []string
+	for _, tmpl := range l.tmpl.Templates() {
+		if tmpl.Name() != "" {
+			names = append(names, tmpl.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DirFingerprint summarizes the names, sizes and modification times of the
+// template files in dir, so a caller polling it can tell when they change.
+// A missing directory has an empty fingerprint.
+func DirFingerprint(dir string) string {
+	files, err := templateFiles(dir)
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(hash, "%s %d %d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// templateFiles lists the template files in dir, sorted by name
+func templateFiles(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("template directory %q does not exist", dir)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+TemplateFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template directory %q: %w", dir, err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// checkReferences reports calls to templates that are not defined, which
+// text/template only notices when the call is executed
+func checkReferences(tmpl *template.Template) error {
+	if tmpl.Tree == nil {
+		return nil
+	}
+
+	var missing string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		if missing != "" || node == nil {
+			return
+		}
+
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			if tmpl.Lookup(n.Name) == nil {
+				missing = n.Name
+			}
+		}
+	}
+	walk(tmpl.Tree.Root)
+
+	if missing != "" {
+		return fmt.Errorf("template %q is not defined", missing)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nomad-events/internal/nomad"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useLibrary sets the library for engines created by a test
+func useLibrary(t *testing.T, lib *Library) {
+	previous := SetLibrary(lib)
+	t.Cleanup(func() { SetLibrary(previous) })
+}
+
+func TestNewLibrary(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "alloc_summary.tmpl"),
+		[]byte(`{{ .Payload.Allocation.JobID }}/{{ template "short_id" .Payload.Allocation.ID }}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials.tmpl"),
+		[]byte(`{{ define "status" }}{{ .Payload.Allocation.ClientStatus | upper }}{{ end }}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(`{{ not a template`), 0o644))
+
+	lib, err := NewLibrary(map[string]string{
+		"short_id": `{{ printf "%.8s" . }}`,
+	}, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alloc_summary", "partials", "short_id", "status"}, lib.Names())
+
+	useLibrary(t, lib)
+	engine := NewEngine()
+
+	event := nomad.Event{
+		Topic: "Allocation",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":           "0a1b2c3d-4e5f-6789-abcd-ef0123456789",
+				"JobID":        "web",
+				"ClientStatus": "failed",
+			},
+		},
+	}
+
+	text, err := engine.ProcessText(`{{ template "alloc_summary" . }} is {{ template "status" . }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, "web/0a1b2c3d is FAILED", text)
+
+	// Templates may still define their own named templates
+	text, err = engine.ProcessText(`{{ define "local" }}{{ .Topic }}{{ end }}{{ template "local" . }}`, event)
+	require.NoError(t, err)
+	assert.Equal(t, "Allocation", text)
+}
+
+func TestNewLibraryEmpty(t *testing.T) {
+	lib, err := NewLibrary(nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, lib)
+	assert.Nil(t, lib.Names())
+
+	lib, err = NewLibrary(nil, t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, lib)
+}
+
+func TestNewLibraryErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summary.tmpl"), []byte(`{{ .Topic }}`), 0o644))
+
+	tests := []struct {
+		name      string
+		templates map[string]string
+		dir       string
+		errorMsg  string
+	}{
+		{
+			name:      "invalid template",
+			templates: map[string]string{"summary": `{{ .Topic`},
+			errorMsg:  "templates.summary:",
+		},
+		{
+			name:      "undefined reference",
+			templates: map[string]string{"summary": `{{ template "missing" . }}`},
+			errorMsg:  `template "missing" is not defined`,
+		},
+		{
+			name:     "missing directory",
+			dir:      filepath.Join(dir, "missing"),
+			errorMsg: "does not exist",
+		},
+		{
+			name:      "defined twice",
+			templates: map[string]string{"summary": `{{ .Key }}`},
+			dir:       dir,
+			errorMsg:  `template "summary" is also defined in the templates section`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLibrary(tt.templates, tt.dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestEngineCompileUndefinedTemplate(t *testing.T) {
+	lib, err := NewLibrary(map[string]string{"summary": `{{ .Topic }}`}, "")
+	require.NoError(t, err)
+
+	// Engines created before the library was set do not see it
+	before := NewEngine()
+	useLibrary(t, lib)
+	after := NewEngine()
+
+	assert.NoError(t, after.Compile(`{{ template "summary" . }}`))
+
+	err = before.Compile(`{{ template "summary" . }}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `template "summary" is not defined`)
+
+	err = after.Compile(`{{ if .Topic }}{{ template "sumary" . }}{{ end }}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `template "sumary" is not defined`)
+}
+
+func TestDirFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, "", DirFingerprint(dir))
+	assert.Equal(t, "", DirFingerprint(""))
+
+	file := filepath.Join(dir, "summary.tmpl")
+	require.NoError(t, os.WriteFile(file, []byte(`{{ .Topic }}`), 0o644))
+	first := DirFingerprint(dir)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, DirFingerprint(dir))
+
+	require.NoError(t, os.WriteFile(file, []byte(`{{ .Topic }} {{ .Type }}`), 0o644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	assert.NotEqual(t, first, DirFingerprint(dir))
+
+	// Other files are ignored
+	second := DirFingerprint(dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+	assert.Equal(t, second, DirFingerprint(dir))
+}
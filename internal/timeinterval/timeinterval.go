@@ -0,0 +1,285 @@
+package timeinterval
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"nomad-events/internal/config"
+)
+
+var weekdays = map[string]int{
+	"sunday":    int(time.Sunday),
+	"monday":    int(time.Monday),
+	"tuesday":   int(time.Tuesday),
+	"wednesday": int(time.Wednesday),
+	"thursday":  int(time.Thursday),
+	"friday":    int(time.Friday),
+	"saturday":  int(time.Saturday),
+}
+
+var months = map[string]int{
+	"january":   1,
+	"february":  2,
+	"march":     3,
+	"april":     4,
+	"may":       5,
+	"june":      6,
+	"july":      7,
+	"august":    8,
+	"september": 9,
+	"october":   10,
+	"november":  11,
+	"december":  12,
+}
+
+// Interval is a named time interval. It contains a time if any of its specs do.
+type Interval struct {
+	specs []spec
+}
+
+// spec is a parsed config.TimeInterval; empty fields match everything
+type spec struct {
+	weekdays    []intRange
+	times       []intRange // minutes since midnight, end exclusive
+	daysOfMonth []intRange
+	months      []intRange
+	location    *time.Location
+}
+
+// intRange is an inclusive range of integers. Weekday and month ranges wrap
+// around when the start is after the end, e.g. "friday:monday".
+type intRange struct {
+	start, end int
+}
+
+func (r intRange) contains(v int) bool {
+	if r.start > r.end {
+		return v >= r.start || v <= r.end
+	}
+	return v >= r.start && v <= r.end
+}
+
+// New parses the specs of a named time interval
+func New(cfgs []config.TimeInterval) (*Interval, error) {
+	interval := &Interval{specs: make([]spec, len(cfgs))}
+
+	for i, cfg := range cfgs {
+		s, err := parseSpec(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("interval %d: %w", i, err)
+		}
+		interval.specs[i] = s
+	}
+
+	return interval, nil
+}
+
+// NewSet parses every named time interval in a configuration
+func NewSet(cfgs map[string][]config.TimeInterval) (map[string]*Interval, error) {
+	set := make(map[string]*Interval, len(cfgs))
+	for name, cfg := range cfgs {
+		interval, err := New(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("time interval %q: %w", name, err)
+		}
+		set[name] = interval
+	}
+	return set, nil
+}
+
+// Contains reports whether t falls within the interval
+func (i *Interval) Contains(t time.Time) bool {
+	for _, s := range i.specs {
+		if s.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s spec) contains(t time.Time) bool {
+	t = t.In(s.location)
+
+	if len(s.months) > 0 && !anyContains(s.months, int(t.Month())) {
+		return false
+	}
+
+	if len(s.daysOfMonth) > 0 {
+		// Negative days count back from the end of the month (-1 is the last day)
+		daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, s.location).Day()
+		matched := false
+		for _, r := range s.daysOfMonth {
+			if resolveDay(r.start, daysInMonth) <= t.Day() && t.Day() <= resolveDay(r.end, daysInMonth) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(s.weekdays) > 0 && !anyContains(s.weekdays, int(t.Weekday())) {
+		return false
+	}
+
+	if len(s.times) > 0 {
+		minute := t.Hour()*60 + t.Minute()
+		matched := false
+		for _, r := range s.times {
+			if minute >= r.start && minute < r.end {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func anyContains(ranges []intRange, v int) bool {
+	for _, r := range ranges {
+		if r.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveDay(day, daysInMonth int) int {
+	if day < 0 {
+		return daysInMonth + day + 1
+	}
+	return day
+}
+
+func parseSpec(cfg config.TimeInterval) (spec, error) {
+	var s spec
+	var err error
+
+	s.location = time.UTC
+	if cfg.Location != "" {
+		s.location, err = time.LoadLocation(cfg.Location)
+		if err != nil {
+			return spec{}, fmt.Errorf("location: %w", err)
+		}
+	}
+
+	s.weekdays, err = parseRanges(cfg.Weekdays, func(v string) (int, error) {
+		return parseName(v, weekdays, 0, 0)
+	})
+	if err != nil {
+		return spec{}, fmt.Errorf("weekdays: %w", err)
+	}
+
+	s.months, err = parseRanges(cfg.Months, func(v string) (int, error) {
+		return parseName(v, months, 1, 12)
+	})
+	if err != nil {
+		return spec{}, fmt.Errorf("months: %w", err)
+	}
+
+	s.daysOfMonth, err = parseDaysOfMonth(cfg.DaysOfMonth)
+	if err != nil {
+		return spec{}, fmt.Errorf("days_of_month: %w", err)
+	}
+
+	for _, tr := range cfg.Times {
+		start, err := parseTimeOfDay(tr.StartTime)
+		if err != nil {
+			return spec{}, fmt.Errorf("times: start_time: %w", err)
+		}
+		end, err := parseTimeOfDay(tr.EndTime)
+		if err != nil {
+			return spec{}, fmt.Errorf("times: end_time: %w", err)
+		}
+		if start >= end {
+			return spec{}, fmt.Errorf("times: start_time %q must be before end_time %q", tr.StartTime, tr.EndTime)
+		}
+		s.times = append(s.times, intRange{start: start, end: end})
+	}
+
+	return s, nil
+}
+
+// parseRanges parses values written as "value" or "start:end"
+func parseRanges(values []string, parse func(string) (int, error)) ([]intRange, error) {
+	ranges := make([]intRange, 0, len(values))
+	for _, value := range values {
+		startValue, endValue, isRange := strings.Cut(value, ":")
+		if !isRange {
+			endValue = startValue
+		}
+
+		start, err := parse(startValue)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parse(endValue)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, intRange{start: start, end: end})
+	}
+	return ranges, nil
+}
+
+// parseName resolves a lower-case name, or a number between min and max when
+// max is non-zero
+func parseName(value string, names map[string]int, min, max int) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if v, ok := names[value]; ok {
+		return v, nil
+	}
+	if max > 0 {
+		if v, err := strconv.Atoi(value); err == nil && v >= min && v <= max {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid value %q", value)
+}
+
+// parseDaysOfMonth parses days and day ranges. Negative days count from the
+// end of the month, so ranges may mix both, such as "25:-1".
+func parseDaysOfMonth(values []string) ([]intRange, error) {
+	ranges := make([]intRange, 0, len(values))
+	for _, value := range values {
+		startValue, endValue, isRange := strings.Cut(value, ":")
+		if !isRange {
+			endValue = startValue
+		}
+
+		var days [2]int
+		for i, v := range []string{startValue, endValue} {
+			day, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || day == 0 || day < -31 || day > 31 {
+				return nil, fmt.Errorf("invalid day %q - use 1 to 31, or -1 to -31 to count from the end of the month", v)
+			}
+			days[i] = day
+		}
+
+		// Only reject ranges that are backwards in every month
+		if (days[0] > 0) == (days[1] > 0) && days[0] > days[1] {
+			return nil, fmt.Errorf("range %q: start must not be after end", value)
+		}
+		ranges = append(ranges, intRange{start: days[0], end: days[1]})
+	}
+	return ranges, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into minutes since midnight; "24:00" is allowed as an end
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q - use 24-hour HH:MM", value)
+}
@@ -0,0 +1,171 @@
+package timeinterval
+
+import (
+	"testing"
+	"time"
+
+	"nomad-events/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalContains(t *testing.T) {
+	businessHours := config.TimeInterval{
+		Weekdays: []string{"monday:friday"},
+		Times:    []config.TimeRange{{StartTime: "09:00", EndTime: "17:00"}},
+	}
+
+	tests := []struct {
+		name     string
+		specs    []config.TimeInterval
+		time     time.Time
+		expected bool
+	}{
+		{
+			name:     "business hours on a weekday",
+			specs:    []config.TimeInterval{businessHours},
+			time:     time.Date(2024, 3, 6, 10, 30, 0, 0, time.UTC), // Wednesday
+			expected: true,
+		},
+		{
+			name:     "end time is exclusive",
+			specs:    []config.TimeInterval{businessHours},
+			time:     time.Date(2024, 3, 6, 17, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "weekend",
+			specs:    []config.TimeInterval{businessHours},
+			time:     time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC), // Saturday
+			expected: false,
+		},
+		{
+			name: "location",
+			specs: []config.TimeInterval{{
+				Times:    []config.TimeRange{{StartTime: "09:00", EndTime: "17:00"}},
+				Location: "America/New_York",
+			}},
+			time:     time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC), // 10:00 in New York
+			expected: true,
+		},
+		{
+			name: "location outside range",
+			specs: []config.TimeInterval{{
+				Times:    []config.TimeRange{{StartTime: "09:00", EndTime: "17:00"}},
+				Location: "America/New_York",
+			}},
+			time:     time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC), // 05:00 in New York
+			expected: false,
+		},
+		{
+			name:     "weekday range wraps around the week",
+			specs:    []config.TimeInterval{{Weekdays: []string{"friday:monday"}}},
+			time:     time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), // Sunday
+			expected: true,
+		},
+		{
+			name:     "wrapped weekday range excludes midweek",
+			specs:    []config.TimeInterval{{Weekdays: []string{"friday:monday"}}},
+			time:     time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC), // Wednesday
+			expected: false,
+		},
+		{
+			name:     "months by name and number",
+			specs:    []config.TimeInterval{{Months: []string{"december", "1:2"}}},
+			time:     time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "month outside range",
+			specs:    []config.TimeInterval{{Months: []string{"december", "1:2"}}},
+			time:     time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "last day of month",
+			specs:    []config.TimeInterval{{DaysOfMonth: []string{"-1"}}},
+			time:     time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "mixed day range",
+			specs:    []config.TimeInterval{{DaysOfMonth: []string{"25:-1"}}},
+			time:     time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "day outside range",
+			specs:    []config.TimeInterval{{DaysOfMonth: []string{"1:7"}}},
+			time:     time.Date(2024, 4, 8, 12, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name: "any spec may match",
+			specs: []config.TimeInterval{
+				{Weekdays: []string{"saturday", "sunday"}},
+				businessHours,
+			},
+			time:     time.Date(2024, 3, 9, 3, 0, 0, 0, time.UTC), // Saturday
+			expected: true,
+		},
+		{
+			name:     "until midnight",
+			specs:    []config.TimeInterval{{Times: []config.TimeRange{{StartTime: "22:00", EndTime: "24:00"}}}},
+			time:     time.Date(2024, 3, 9, 23, 59, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "empty spec matches everything",
+			specs:    []config.TimeInterval{{}},
+			time:     time.Date(2024, 3, 9, 3, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, err := New(tt.specs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, interval.Contains(tt.time))
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   config.TimeInterval
+		errMsg string
+	}{
+		{"unknown weekday", config.TimeInterval{Weekdays: []string{"funday"}}, "weekdays: invalid value"},
+		{"unknown month", config.TimeInterval{Months: []string{"13"}}, "months: invalid value"},
+		{"invalid day", config.TimeInterval{DaysOfMonth: []string{"0"}}, "days_of_month: invalid day"},
+		{"backwards day range", config.TimeInterval{DaysOfMonth: []string{"10:5"}}, "start must not be after end"},
+		{"invalid time", config.TimeInterval{Times: []config.TimeRange{{StartTime: "9am", EndTime: "17:00"}}}, "start_time: invalid time"},
+		{"empty time range", config.TimeInterval{Times: []config.TimeRange{{StartTime: "17:00", EndTime: "09:00"}}}, "must be before end_time"},
+		{"unknown location", config.TimeInterval{Location: "Mars/Olympus_Mons"}, "location"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.TimeInterval{tt.spec})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	set, err := NewSet(map[string][]config.TimeInterval{
+		"weekends": {{Weekdays: []string{"saturday:sunday"}}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, set, "weekends")
+
+	_, err = NewSet(map[string][]config.TimeInterval{
+		"broken": {{Weekdays: []string{"funday"}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `time interval "broken"`)
+}